@@ -0,0 +1,136 @@
+package analyzers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/flanksource/duty/models"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const RegoAnalysisSource = "Rego"
+
+// RegoAnalyzer evaluates the deny and warn rules of compiled rego policies against config items.
+type RegoAnalyzer struct {
+	policy   v1.RegoPolicy
+	packages []string
+	query    rego.PreparedEvalQuery
+}
+
+// NewRegoAnalyzer compiles the rego modules, keyed by file name, into a single query
+// that collects the deny and warn messages of every package.
+func NewRegoAnalyzer(ctx context.Context, policy v1.RegoPolicy, modules map[string]string) (*RegoAnalyzer, error) {
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("no rego modules found")
+	}
+
+	version := ast.RegoV1
+	switch policy.RegoVersion {
+	case "", "v1":
+	case "v0":
+		version = ast.RegoV0
+	default:
+		return nil, fmt.Errorf("unsupported rego version %q, must be v0 or v1", policy.RegoVersion)
+	}
+
+	options := []func(*rego.Rego){rego.SetRegoVersion(version)}
+	var packages []string
+	for _, name := range lo.Keys(modules) {
+		module, err := ast.ParseModuleWithOpts(name, modules[name], ast.ParserOptions{RegoVersion: version})
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, module.Package.Path.String())
+		options = append(options, rego.Module(name, modules[name]))
+	}
+	packages = lo.Uniq(packages)
+	sort.Strings(packages)
+
+	var query []string
+	for i, pkg := range packages {
+		query = append(query,
+			fmt.Sprintf("deny_%d := [m | m := %s.deny[_]]", i, pkg),
+			fmt.Sprintf("warn_%d := [m | m := %s.warn[_]]", i, pkg),
+		)
+	}
+
+	prepared, err := rego.New(append(options, rego.Query(strings.Join(query, "; ")))...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegoAnalyzer{policy: policy, packages: packages, query: prepared}, nil
+}
+
+// Analyze returns an analysis for every policy package that denies or warns about the config item.
+func (r *RegoAnalyzer) Analyze(ctx context.Context, config v1.ScrapeResult) ([]v1.AnalysisResult, error) {
+	results, err := r.query.Eval(ctx, rego.EvalInput(config.Config))
+	if err != nil {
+		return nil, err
+	} else if len(results) == 0 {
+		return nil, nil
+	}
+
+	var analyses []v1.AnalysisResult
+	for i, pkg := range r.packages {
+		deny := regoMessages(results[0].Bindings[fmt.Sprintf("deny_%d", i)])
+		warn := regoMessages(results[0].Bindings[fmt.Sprintf("warn_%d", i)])
+		if len(deny) == 0 && len(warn) == 0 {
+			continue
+		}
+
+		analyzer := strings.TrimPrefix(pkg, "data.")
+		analysis := v1.AnalysisResult{
+			Analyzer:     analyzer,
+			ConfigType:   config.Type,
+			ExternalID:   config.ID,
+			AnalysisType: lo.CoalesceOrEmpty(r.policy.Category, models.AnalysisTypeSecurity),
+			Source:       RegoAnalysisSource,
+			Summary:      analyzer,
+			Analysis:     map[string]any{"deny": deny, "warn": warn},
+		}
+
+		if len(deny) > 0 {
+			analysis.Severity = lo.CoalesceOrEmpty(r.policy.Severity, models.SeverityHigh)
+		} else {
+			analysis.Severity = lo.CoalesceOrEmpty(r.policy.WarnSeverity, models.SeverityLow)
+		}
+
+		for _, message := range append(deny, warn...) {
+			analysis.Message(message)
+		}
+		analyses = append(analyses, analysis)
+	}
+
+	return analyses, nil
+}
+
+// regoMessages returns the messages of a deny or warn rule,
+// which are either strings or objects with a msg field.
+func regoMessages(value any) []string {
+	var messages []string
+	items, _ := value.([]any)
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			messages = append(messages, v)
+		case map[string]any:
+			if msg, ok := v["msg"].(string); ok {
+				messages = append(messages, msg)
+				continue
+			}
+			b, _ := json.Marshal(v)
+			messages = append(messages, string(b))
+		default:
+			messages = append(messages, fmt.Sprint(v))
+		}
+	}
+	return messages
+}
@@ -2,13 +2,17 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/flanksource/commons/har"
+	commonsHTTP "github.com/flanksource/commons/http"
 	"github.com/flanksource/commons/logger"
 	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/connection"
 	dutyCtx "github.com/flanksource/duty/context"
 	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
 	"github.com/google/uuid"
 	"github.com/samber/lo"
 )
@@ -22,6 +26,15 @@ type ScrapeContext struct {
 	debugRun      bool
 	harCollector  *har.Collector
 
+	// replay serves the HTTP requests of the scrapers from recorded responses instead of the network
+	replay http.RoundTripper
+
+	// dryRun holds the copies of the state kept across scrapes that a dry run uses instead of the state itself
+	dryRun *dryRunState
+
+	// execStubs are the outputs used in place of running the scripts of the exec scrapers
+	execStubs []string
+
 	namespace string
 
 	jobHistory        *models.JobHistory
@@ -110,6 +123,9 @@ func (ctx ScrapeContext) LastScrapeSummary() v1.ScrapeSummary {
 	for k, v := range ctx.lastScrapeSummary.ConfigTypes {
 		copied.ConfigTypes[k] = v
 	}
+	for k, v := range ctx.lastScrapeSummary.State {
+		copied.SetState(k, v)
+	}
 	return copied
 }
 
@@ -129,6 +145,9 @@ func (ctx ScrapeContext) WithValue(key, val any) ScrapeContext {
 		isIncremental:     ctx.isIncremental,
 		debugRun:          ctx.debugRun,
 		harCollector:      ctx.harCollector,
+		replay:            ctx.replay,
+		dryRun:            ctx.dryRun,
+		execStubs:         ctx.execStubs,
 		namespace:         ctx.namespace,
 		jobHistory:        ctx.jobHistory,
 		scrapeConfig:      ctx.scrapeConfig,
@@ -259,6 +278,91 @@ func (ctx ScrapeContext) HARCollector() *har.Collector {
 	return ctx.Context.EffectiveHARCollector("http", ctx.harCollector)
 }
 
+// WithReplay serves the HTTP requests of the scrapers from the transport instead of the network.
+func (ctx ScrapeContext) WithReplay(transport http.RoundTripper) ScrapeContext {
+	ctx.replay = transport
+	return ctx
+}
+
+// HTTPTransport wraps the transport of an HTTP client built by a scraper, to record its requests
+// into the HAR collector, or to serve them from the recorded responses when replaying.
+func (ctx ScrapeContext) HTTPTransport(transport http.RoundTripper) http.RoundTripper {
+	if ctx.replay != nil {
+		return ctx.replay
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if collector := ctx.HARCollector(); collector != nil {
+		return collector.Middleware()(transport)
+	}
+	return transport
+}
+
+// NewHTTPClient creates a client for a hydrated connection that records its requests into the
+// HAR collector, and serves them from the recorded responses when replaying.
+func (ctx ScrapeContext) NewHTTPClient(conn connection.HTTPConnection, opts ...types.ClientOption) (*commonsHTTP.Client, error) {
+	if ctx.harCollector != nil {
+		opts = append(opts, types.WithHARCollector(ctx.harCollector))
+	}
+	client, err := connection.CreateHTTPClient(ctx, conn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.replay != nil {
+		client.Use(func(http.RoundTripper) http.RoundTripper { return ctx.replay })
+	}
+	return client, nil
+}
+
+type dryRunState struct {
+	lock   sync.Mutex
+	copies map[string]any
+}
+
+// WithDryRun isolates the state kept across scrapes, e.g. caches and trackers, so that what a dry run
+// scrapes and saves does not change the next scrape. It has no effect on a context that is already a dry run.
+func (ctx ScrapeContext) WithDryRun() ScrapeContext {
+	if ctx.dryRun == nil {
+		ctx.dryRun = &dryRunState{copies: map[string]any{}}
+	}
+	return ctx
+}
+
+func (ctx ScrapeContext) IsDryRun() bool {
+	return ctx.dryRun != nil
+}
+
+// DryRunState returns the state kept across scrapes under the key or, in a dry run, its copy made with clone on first use.
+func DryRunState[T any](ctx ScrapeContext, key string, state T, clone func(T) T) T {
+	if ctx.dryRun == nil {
+		return state
+	}
+
+	ctx.dryRun.lock.Lock()
+	defer ctx.dryRun.lock.Unlock()
+	if copied, ok := ctx.dryRun.copies[key]; ok {
+		return copied.(T)
+	}
+	copied := clone(state)
+	ctx.dryRun.copies[key] = copied
+	return copied
+}
+
+// WithExecStubs replaces the output of the scripts of the exec scrapers, in the order of the exec scrapers in the spec.
+func (ctx ScrapeContext) WithExecStubs(stubs ...string) ScrapeContext {
+	ctx.execStubs = stubs
+	return ctx
+}
+
+// ExecStub returns the output that replaces the script of the exec scraper at the index.
+func (ctx ScrapeContext) ExecStub(index int) (string, bool) {
+	if index < 0 || index >= len(ctx.execStubs) {
+		return "", false
+	}
+	return ctx.execStubs[index], true
+}
+
 func (ctx ScrapeContext) WithEntities() ScrapeContext {
 	ctx.users = NewList[*models.ExternalUser, models.ExternalUser]()
 	ctx.groups = NewList[*models.ExternalGroup, models.ExternalGroup]()
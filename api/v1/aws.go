@@ -20,6 +20,10 @@ type AWS struct {
 	Exclude       []string      `json:"exclude,omitempty"`
 	Exclusions    AWSExclusions `json:"exclusions,omitempty"`
 	CostReporting CostReporting `json:"costReporting,omitempty"`
+
+	// MaxConcurrency bounds the number of AWS service collectors that run
+	// in parallel across all regions. Defaults to the `aws.concurrency` property.
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
 }
 
 // AWSExclusion matches a scraped item by type, name, and/or tags.
@@ -124,46 +128,69 @@ type CostReporting struct {
 }
 
 const (
-	AWSCloudFormationStack = "AWS::CloudFormation::Stack"
-	AWSECSCluster          = "AWS::ECS::Cluster"
-	AWSECSService          = "AWS::ECS::Service"
-	AWSECSTaskDefinition   = "AWS::ECS::TaskDefinition"
-	AWSECSTask             = "AWS::ECS::Task"
-	AWSEKSFargateProfile   = "AWS::EKS::FargateProfile"
-	AWSElastiCacheCluster  = "AWS::ElastiCache::CacheCluster"
-	AWSLambdaFunction      = "AWS::Lambda::Function"
-	AWSSNSTopic            = "AWS::SNS::Topic"
-	AWSSQS                 = "AWS::SQS::Queue"
-	AWSRegion              = "AWS::Region"
-	AWSZone                = "AWS::Route53::HostedZone"
-	AWSEC2Instance         = "AWS::EC2::Instance"
-	AWSEKSCluster          = "AWS::EKS::Cluster"
-	AWSS3Bucket            = "AWS::S3::Bucket"
-	AWSLoadBalancer        = "AWS::ElasticLoadBalancing::LoadBalancer"
-	AWSLoadBalancerV2      = "AWS::ElasticLoadBalancingV2::LoadBalancer"
-	AWSEBSVolume           = "AWS::EBS::Volume"
-	AWSRDSInstance         = "AWS::RDS::DBInstance"
-	AWSEC2VPC              = "AWS::EC2::VPC"
-	AWSEC2Subnet           = "AWS::EC2::Subnet"
-	AWSAccount             = "AWS::::Account"
-	AWSAvailabilityZone    = "AWS::AvailabilityZone"
-	AWSAvailabilityZoneID  = "AWS::AvailabilityZoneID"
-	AWSEC2SecurityGroup    = "AWS::EC2::SecurityGroup"
-	AWSIAMUser             = "AWS::IAM::User"
-	AWSIAMRole             = "AWS::IAM::Role"
-	AWSIAMGroup            = "AWS::IAM::Group"
-	AWSIAMInstanceProfile  = "AWS::IAM::InstanceProfile"
-	AWSIAMOIDCProvider     = "AWS::IAM::OIDCProvider"
-	AWSIAMSAMLProvider     = "AWS::IAM::SAMLProvider"
-	AWSEC2AMI              = "AWS::EC2::AMI"
-	AWSEC2DHCPOptions      = "AWS::EC2::DHCPOptions"
-	AWSBackupVault         = "AWS::Backup::BackupVault"
-	AWSBackupPlan          = "AWS::Backup::BackupPlan"
-	AWSEFSFileSystem       = "AWS::EFS::FileSystem"
-	AWSDynamoDBTable       = "AWS::DynamoDB::Table"
+	AWSCloudFormationStack    = "AWS::CloudFormation::Stack"
+	AWSECSCluster             = "AWS::ECS::Cluster"
+	AWSECSService             = "AWS::ECS::Service"
+	AWSECSTaskDefinition      = "AWS::ECS::TaskDefinition"
+	AWSECSTask                = "AWS::ECS::Task"
+	AWSEKSFargateProfile      = "AWS::EKS::FargateProfile"
+	AWSElastiCacheCluster     = "AWS::ElastiCache::CacheCluster"
+	AWSLambdaFunction         = "AWS::Lambda::Function"
+	AWSSNSTopic               = "AWS::SNS::Topic"
+	AWSSQS                    = "AWS::SQS::Queue"
+	AWSRegion                 = "AWS::Region"
+	AWSZone                   = "AWS::Route53::HostedZone"
+	AWSEC2Instance            = "AWS::EC2::Instance"
+	AWSEKSCluster             = "AWS::EKS::Cluster"
+	AWSS3Bucket               = "AWS::S3::Bucket"
+	AWSLoadBalancer           = "AWS::ElasticLoadBalancing::LoadBalancer"
+	AWSLoadBalancerV2         = "AWS::ElasticLoadBalancingV2::LoadBalancer"
+	AWSEBSVolume              = "AWS::EBS::Volume"
+	AWSRDSInstance            = "AWS::RDS::DBInstance"
+	AWSEC2VPC                 = "AWS::EC2::VPC"
+	AWSEC2Subnet              = "AWS::EC2::Subnet"
+	AWSAccount                = "AWS::::Account"
+	AWSAvailabilityZone       = "AWS::AvailabilityZone"
+	AWSAvailabilityZoneID     = "AWS::AvailabilityZoneID"
+	AWSEC2SecurityGroup       = "AWS::EC2::SecurityGroup"
+	AWSIAMUser                = "AWS::IAM::User"
+	AWSIAMRole                = "AWS::IAM::Role"
+	AWSIAMGroup               = "AWS::IAM::Group"
+	AWSIAMInstanceProfile     = "AWS::IAM::InstanceProfile"
+	AWSIAMOIDCProvider        = "AWS::IAM::OIDCProvider"
+	AWSIAMSAMLProvider        = "AWS::IAM::SAMLProvider"
+	AWSEC2AMI                 = "AWS::EC2::AMI"
+	AWSEC2DHCPOptions         = "AWS::EC2::DHCPOptions"
+	AWSBackupVault            = "AWS::Backup::BackupVault"
+	AWSBackupPlan             = "AWS::Backup::BackupPlan"
+	AWSEFSFileSystem          = "AWS::EFS::FileSystem"
+	AWSDynamoDBTable          = "AWS::DynamoDB::Table"
+	AWSKMSKey                 = "AWS::KMS::Key"
+	AWSSecretsManagerSecret   = "AWS::SecretsManager::Secret"
+	AWSCloudFrontDistribution = "AWS::CloudFront::Distribution"
+	AWSAPIGatewayRestAPI      = "AWS::ApiGateway::RestApi"
+	AWSAPIGatewayStage        = "AWS::ApiGateway::Stage"
+	AWSAPIGatewayV2API        = "AWS::ApiGatewayV2::Api"
+	AWSAPIGatewayV2Stage      = "AWS::ApiGatewayV2::Stage"
+	AWSWAFv2WebACL            = "AWS::WAFv2::WebACL"
+	AWSAutoScalingGroup       = "AWS::AutoScaling::AutoScalingGroup"
+	AWSEC2LaunchTemplate      = "AWS::EC2::LaunchTemplate"
 )
 
-var defaultAWSExclusions = []string{"ECSTaskDefinition"}
+// defaultAWSExclusions are only scraped when included explicitly. KMS, DynamoDB and
+// Secrets Manager need permissions, e.g. kms:ListKeys, that existing scrapers may not have.
+var defaultAWSExclusions = []string{"ECSTaskDefinition", "KMS", "DynamoDB", "SecretsManager"}
+
+// AllAWSIncludes are the resources the AWS scraper can include, matched case-insensitively.
+var AllAWSIncludes = []string{
+	"Account", "APIGateway", "AutoScalingActivities", "AutoScalingGroup", "Backups",
+	"CloudFormation", "CloudFront", "DHCP", "DNSZone", "DynamoDB",
+	"EBS", "EC2instance", "ECR", "ECS", "ECSService", "ECSTask", "ECSTaskDefinition",
+	"EFS", "EKS", "ElastiCache", "FargateProfile", "Groups", "IAMTrust", "Images",
+	"KMS", "Lambda", "LaunchTemplate", "LoadBalancer", "OIDCProviders", "Profiles",
+	"RDS", "RDSBackup", "RDSBackups", "RDSEvents", "Roles", "Route", "S3Bucket",
+	"SAMLProviders", "SecretsManager", "SecurityGroup", "SNS", "SQS", "Subnet", "User", "VPC", "WAF",
+}
 
 func (aws AWS) Includes(resource string) bool {
 	if len(aws.Include) == 0 {
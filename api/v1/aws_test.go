@@ -14,6 +14,8 @@ var _ = Describe("AWS", func() {
 			AWS{}, "ec2", true),
 		Entry("empty include list, in default exclusions",
 			AWS{}, "ECSTASKDEFINITION", false),
+		Entry("empty include list, resource needing new permissions",
+			AWS{}, "kms", false),
 		Entry("explicit inclusion of default exclusion",
 			AWS{Include: []string{"EcsTaskDefinition"}}, "ECSTASKDEFINITION", true),
 		Entry("non-empty include list, resource included",
@@ -1,7 +1,11 @@
 package v1
 
 import (
+	"time"
+
 	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/commons/duration"
+	"github.com/flanksource/commons/logger"
 	"github.com/flanksource/duty/types"
 )
 
@@ -77,6 +81,8 @@ type Azure struct {
 	Include        []string         `yaml:"include,omitempty" json:"include,omitempty"`
 	Exclusions     *AzureExclusions `yaml:"exclusions,omitempty" json:"exclusions,omitempty"`
 	Entra          *Entra           `yaml:"entra,omitempty" json:"entra,omitempty"`
+	// ResourceChanges configures fetching property level changes from the Resource Graph change history
+	ResourceChanges *AzureResourceChanges `yaml:"resourceChanges,omitempty" json:"resourceChanges,omitempty"`
 }
 
 func (azure Azure) Includes(resource string) bool {
@@ -86,6 +92,24 @@ func (azure Azure) Includes(resource string) bool {
 	return collections.MatchItems(resource, azure.Include...)
 }
 
+type AzureResourceChanges struct {
+	// MaxAge is how far back to look for changes when there is no cursor from a previous run (e.g. "24h", "7d").
+	// Resource Graph retains 14 days of change history. Defaults to 7d.
+	MaxAge string `yaml:"maxAge,omitempty" json:"maxAge,omitempty"`
+}
+
+func (c *AzureResourceChanges) GetMaxAge() time.Duration {
+	if c == nil || c.MaxAge == "" {
+		return 7 * 24 * time.Hour
+	}
+	d, err := duration.ParseDuration(c.MaxAge)
+	if err != nil {
+		logger.Warnf("Invalid azure resource changes max age %s: %v", c.MaxAge, err)
+		return 7 * 24 * time.Hour
+	}
+	return time.Duration(d)
+}
+
 type AzureExclusions struct {
 	// ActivityLogs is a list of operations to exclude from activity logs.
 	// Example:
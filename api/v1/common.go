@@ -145,7 +145,7 @@ type ChangeMapping struct {
 	Type string `json:"type,omitempty"`
 	// Action allows performing actions on the corresponding config item
 	// based on this change.
-	// Allowed actions: "delete", "ignore", "move-up", "copy-up", "copy", "move"
+	// Allowed actions: "delete", "ignore", "move-up", "copy-up", "copy", "move", "aggregate"
 	Action ChangeAction `json:"action,omitempty"`
 	// Summary replaces the existing change summary.
 	Summary string `json:"summary,omitempty"`
@@ -165,6 +165,16 @@ type ChangeMapping struct {
 	// The selector is evaluated to find target config items to redirect or
 	// duplicate changes to. Mutually exclusive with move-up/copy-up/ancestor_type.
 	Target *duty.RelationshipSelectorTemplate `json:"target,omitempty"`
+	// GroupBy is a CEL expression that returns the key "aggregate" actions group changes by.
+	// Changes of the same config and key are rolled up into a single change.
+	// Defaults to the change type.
+	GroupBy string `json:"group_by,omitempty"`
+	// Window is the duration after the first change of a group in which
+	// further changes are rolled up into it, for "aggregate" actions. Defaults to 1h.
+	Window string `json:"window,omitempty"`
+	// Samples is the number of aggregated changes whose details are kept
+	// on the rolled up change. Defaults to 5.
+	Samples int `json:"samples,omitempty"`
 }
 
 type TransformChange struct {
@@ -205,11 +215,21 @@ type Transform struct {
 
 	Locations []LocationOrAlias `json:"locations,omitempty"`
 	Aliases   []LocationOrAlias `json:"aliases,omitempty"`
+
+	// Health is an ordered list of rules that set the health and status of config items.
+	// The first matching rule of a config type is applied.
+	Health []HealthRule `json:"health,omitempty"`
+
+	// Analysis is a list of rules that report an analysis on the config items they match.
+	Analysis []AnalysisRule `json:"analysis,omitempty"`
+
+	// Rego is a list of OPA policies whose deny and warn rules report an analysis on the config items.
+	Rego []RegoPolicy `json:"rego,omitempty"`
 }
 
 func (t Transform) IsEmpty() bool {
 	return t.Script.IsEmpty() && t.Change.IsEmpty() && len(t.Exclude) == 0 && t.Masks.IsEmpty() && len(t.Relationship) == 0 &&
-		len(t.Locations) == 0 && len(t.Aliases) == 0
+		len(t.Locations) == 0 && len(t.Aliases) == 0 && len(t.Health) == 0 && len(t.Analysis) == 0 && len(t.Rego) == 0
 }
 
 func (t Transform) String() string {
@@ -232,6 +252,18 @@ func (t Transform) String() string {
 
 	s += fmt.Sprintf(" relationships=%d", len(t.Relationship))
 
+	if len(t.Health) > 0 {
+		s += fmt.Sprintf(" health=%d", len(t.Health))
+	}
+
+	if len(t.Analysis) > 0 {
+		s += fmt.Sprintf(" analysis=%d", len(t.Analysis))
+	}
+
+	if len(t.Rego) > 0 {
+		s += fmt.Sprintf(" rego=%d", len(t.Rego))
+	}
+
 	if len(t.Locations) > 0 {
 		s += fmt.Sprintf(" locations=%d", len(t.Locations))
 	}
@@ -269,7 +301,7 @@ type CustomScraperBase struct {
 	// A static value or JSONPath expression to use as the class for the resource.
 	Class string `json:"class,omitempty"`
 
-	// Format of config item, defaults to JSON, available options are JSON, properties
+	// Format of config item, defaults to JSON, available options are JSON, YAML, properties, TOML, INI, HCL, XML, CSV and env
 	Format string `json:"format,omitempty"`
 
 	// A static value or JSONPath expression to use as the status of the config item
@@ -318,6 +350,9 @@ func (base BaseScraper) ApplyPlugins(plugins ...ScrapePluginSpec) BaseScraper {
 		base.Transform.Aliases = append(base.Transform.Aliases, p.Aliases...)
 
 		base.Transform.Relationship = append(base.Transform.Relationship, p.Relationship...)
+		base.Transform.Health = append(base.Transform.Health, p.Health...)
+		base.Transform.Analysis = append(base.Transform.Analysis, p.Analysis...)
+		base.Transform.Rego = append(base.Transform.Rego, p.Rego...)
 		base.Properties = append(base.Properties, p.Properties...)
 	}
 
@@ -512,6 +547,120 @@ type ChangeExtractionRule struct {
 	Config []types.EnvVarResourceSelector `yaml:"config" json:"config"`
 }
 
+type HealthRuleMode string
+
+const (
+	// HealthRuleOverride replaces the health and status set by the scraper
+	HealthRuleOverride HealthRuleMode = "override"
+
+	// HealthRuleAugment only applies the rule if its health is worse than the health set by the scraper
+	HealthRuleAugment HealthRuleMode = "augment"
+)
+
+type HealthRule struct {
+	// Types on which this rule should run.
+	// Supports match expression
+	// Example: AWS::*, Kubernetes::Deployment
+	Type types.MatchExpression `json:"type,omitempty"`
+
+	// Expr is a CEL expression that must return true for this rule to apply.
+	//
+	// Receives the config item as the cel env variable.
+	// Example: config.spec.replicas != config.status.readyReplicas
+	Expr types.CelExpression `json:"expr"`
+
+	// Health to set when the rule applies.
+	// +kubebuilder:validation:Enum=healthy;unhealthy;warning;unknown
+	Health models.Health `json:"health,omitempty"`
+
+	// Status to set when the rule applies.
+	Status string `json:"status,omitempty"`
+
+	// Message replaces the description of the config item when the rule applies.
+	Message string `json:"message,omitempty"`
+
+	// Mode is either override (default) or augment.
+	// +kubebuilder:validation:Enum=override;augment
+	Mode HealthRuleMode `json:"mode,omitempty"`
+}
+
+type AnalysisRule struct {
+	// Types on which this rule should run.
+	// Supports match expression
+	// Example: AWS::*, Kubernetes::Deployment
+	Type types.MatchExpression `json:"type,omitempty"`
+
+	// Expr is a CEL expression that must return true for the analysis to be reported.
+	// The analysis is resolved once the expression stops matching and
+	// the analysis becomes stale.
+	//
+	// Receives the config item as the cel env variable.
+	Expr types.CelExpression `json:"expr"`
+
+	// Analyzer is the name of the analysis.
+	Analyzer string `json:"analyzer"`
+
+	// Severity of the analysis, defaults to the severity of the analyzer in the built-in rules.
+	// +kubebuilder:validation:Enum=critical;high;medium;low;info
+	Severity models.Severity `json:"severity,omitempty"`
+
+	// Category of the analysis, defaults to the category of the analyzer in the built-in rules.
+	// +kubebuilder:validation:Enum=availability;compliance;cost;integration;other;performance;recommendation;reliability;security;technical_debt
+	Category models.AnalysisType `json:"category,omitempty"`
+
+	// Message is a go template that receives the config item.
+	Message string `json:"message,omitempty"`
+}
+
+// RegoPolicy evaluates OPA policies against the config of the items it matches.
+// The `deny` and `warn` rules of every policy package are evaluated with the config as `input`,
+// the messages they return are reported as an analysis named after the package.
+//
+// Policy modules are read from any combination of the inline modules, a ConfigMap and a
+// directory, which is relative to the git checkout when one is provided.
+type RegoPolicy struct {
+	// Types on which the policies should run.
+	// Supports match expression
+	// Example: AWS::*, Kubernetes::Deployment
+	Type types.MatchExpression `json:"type,omitempty"`
+
+	// Modules are inline rego modules keyed by file name.
+	Modules map[string]string `json:"modules,omitempty"`
+
+	// Path to a .rego file or a directory of .rego files.
+	Path string `json:"path,omitempty"`
+
+	// ConfigMap whose keys ending in .rego are policy modules.
+	ConfigMap *RegoConfigMap `json:"configMap,omitempty"`
+
+	// Checkout is a git repository that contains the policy modules under Path.
+	Checkout *connection.GitConnection `json:"checkout,omitempty"`
+
+	// RegoVersion is the syntax of the policy modules, defaults to v1.
+	// Use v0 for policies written before OPA 1.0, e.g. conftest style `deny[msg] { ... }` rules.
+	// +kubebuilder:validation:Enum=v0;v1
+	RegoVersion string `json:"regoVersion,omitempty"`
+
+	// Severity of deny messages, defaults to high.
+	// +kubebuilder:validation:Enum=critical;high;medium;low;info
+	Severity models.Severity `json:"severity,omitempty"`
+
+	// WarnSeverity is the severity of warn messages, defaults to low.
+	// +kubebuilder:validation:Enum=critical;high;medium;low;info
+	WarnSeverity models.Severity `json:"warnSeverity,omitempty"`
+
+	// Category of the analysis, defaults to security.
+	// +kubebuilder:validation:Enum=availability;compliance;cost;integration;other;performance;recommendation;reliability;security;technical_debt
+	Category models.AnalysisType `json:"category,omitempty"`
+}
+
+type RegoConfigMap struct {
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap, defaults to the namespace of the scraper.
+	Namespace string `json:"namespace,omitempty"`
+}
+
 type LocationOrAlias struct {
 	// Types on which this plugin should run.
 	// Supports match expression
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strings"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
 )
 
@@ -66,3 +67,42 @@ func parseConfig(configfile string) ([]ScrapeConfig, error) {
 
 	return scrapers, nil
 }
+
+// ParseConfigsAndPlugins reads the ScrapeConfigs and ScrapePlugins of a config file.
+func ParseConfigsAndPlugins(configfile string) ([]ScrapeConfig, []ScrapePlugin, error) {
+	configs, err := readFile(configfile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading config file=%s: %w", configfile, err)
+	}
+
+	var scrapers []ScrapeConfig
+	var plugins []ScrapePlugin
+	for _, chunk := range yamlDividerRegexp.Split(configs, -1) {
+		if strings.TrimSpace(chunk) == "" {
+			continue
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(chunk), 1024).Decode(&typeMeta); err != nil {
+			return nil, nil, fmt.Errorf("error decoding yaml. file=%s: %w", configfile, err)
+		}
+
+		decoder := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(chunk), 1024)
+		if typeMeta.Kind == "ScrapePlugin" {
+			var plugin ScrapePlugin
+			if err := decoder.Decode(&plugin); err != nil {
+				return nil, nil, fmt.Errorf("error decoding yaml. file=%s: %w", configfile, err)
+			}
+			plugins = append(plugins, plugin)
+			continue
+		}
+
+		var config ScrapeConfig
+		if err := decoder.Decode(&config); err != nil {
+			return nil, nil, fmt.Errorf("error decoding yaml. file=%s: %w", configfile, err)
+		}
+		scrapers = append(scrapers, config)
+	}
+
+	return scrapers, plugins, nil
+}
@@ -9,10 +9,14 @@ var (
 	CopyUp ChangeAction = "copy-up"
 	Copy   ChangeAction = "copy"
 	Move   ChangeAction = "move"
+
+	// Aggregate rolls up the changes of a group into a single change
+	Aggregate ChangeAction = "aggregate"
 )
 
 const (
 	ChangeTypeDiff              = "diff"
 	ChangeTypePermissionAdded   = "PermissionAdded"
 	ChangeTypePermissionRemoved = "PermissionRemoved"
+	ChangeTypeRateLimited       = "RateLimited"
 )
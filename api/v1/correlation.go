@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/flanksource/commons/duration"
+	"github.com/flanksource/duty/types"
+)
+
+// ChangeCorrelation links a change (the effect) to the change that preceded it (the cause)
+// on a related config item, e.g. a ConfigMap change followed by a Deployment rollout.
+//
+// The effect records the cause as its parent, and the cause records the effect as one of its children,
+// under the "correlation" key of the change details.
+type ChangeCorrelation struct {
+	// Name of the rule, recorded on the links.
+	Name string `json:"name"`
+
+	// Cause selects the changes that trigger the effect.
+	Cause ChangeCorrelationSelector `json:"cause"`
+
+	// Effect selects the changes that follow the cause.
+	Effect ChangeCorrelationSelector `json:"effect"`
+
+	// Relationship is the path from the config item of the effect to the config item of the cause.
+	Relationship ChangeCorrelationPath `json:"relationship,omitempty"`
+
+	// Window is the maximum duration between the cause and the effect. Defaults to 15m.
+	Window string `json:"window,omitempty"`
+}
+
+func (c ChangeCorrelation) GetWindow() (time.Duration, error) {
+	if c.Window == "" {
+		return 15 * time.Minute, nil
+	}
+
+	window, err := duration.ParseDuration(c.Window)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %q for correlation %s: %w", c.Window, c.Name, err)
+	}
+	return time.Duration(window), nil
+}
+
+type ChangeCorrelationSelector struct {
+	// ConfigType of the config item of the change.
+	// Supports match expression
+	// Example: Kubernetes::Deployment, AWS::*
+	ConfigType types.MatchExpression `json:"configType,omitempty"`
+
+	// ChangeType of the change.
+	// Supports match expression
+	// Example: ModifyDBInstance, Scaling*
+	ChangeType types.MatchExpression `json:"changeType"`
+}
+
+func (s ChangeCorrelationSelector) Matches(changeType, configType string) bool {
+	if s.ChangeType != "" && !s.ChangeType.Match(changeType) {
+		return false
+	}
+	return s.ConfigType == "" || s.ConfigType.Match(configType)
+}
+
+type ChangeCorrelationPath struct {
+	// Direction of the relationships from the config item of the effect to the config item of the cause.
+	// incoming (default) follows relationships upstream, e.g. from a Pod to its Deployment,
+	// outgoing follows them downstream and all in both directions.
+	// +kubebuilder:validation:Enum=incoming;outgoing;all
+	Direction string `json:"direction,omitempty"`
+
+	// MaxDepth of the relationships to follow. Defaults to 3,
+	// 0 only correlates changes of the same config item.
+	MaxDepth *int `json:"maxDepth,omitempty"`
+}
+
+func (p ChangeCorrelationPath) GetDirection() string {
+	if p.Direction == "" {
+		return "incoming"
+	}
+	return p.Direction
+}
+
+func (p ChangeCorrelationPath) GetMaxDepth() int {
+	if p.MaxDepth == nil {
+		return 3
+	}
+	return *p.MaxDepth
+}
+
+// ChangeLink refers to a correlated change
+// +kubebuilder:object:generate=false
+type ChangeLink struct {
+	ID         string    `json:"id"`
+	ConfigID   string    `json:"config_id"`
+	ChangeType string    `json:"change_type"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Rule is the name of the correlation rule that linked the changes
+	Rule string `json:"rule,omitempty"`
+}
+
+// ChangeCorrelationDetails are stored under the "correlation" key of the change details.
+// +kubebuilder:object:generate=false
+type ChangeCorrelationDetails struct {
+	// Parent is the change that caused this change
+	Parent *ChangeLink `json:"parent,omitempty"`
+	// Children are the changes caused by this change
+	Children []ChangeLink `json:"children,omitempty"`
+}
+
+// Correlation returns the changes linked to this change by the correlation rules.
+func (r ChangeResult) Correlation() ChangeCorrelationDetails {
+	return CorrelationFromDetails(r.Details)
+}
+
+func CorrelationFromDetails(details map[string]any) ChangeCorrelationDetails {
+	var correlation ChangeCorrelationDetails
+	if raw, ok := details["correlation"]; ok {
+		if b, err := json.Marshal(raw); err == nil {
+			_ = json.Unmarshal(b, &correlation)
+		}
+	}
+	return correlation
+}
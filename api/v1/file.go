@@ -17,6 +17,22 @@ type File struct {
 
 	// ConnectionName is used to populate the URL
 	ConnectionName string `json:"connection,omitempty" yaml:"connection,omitempty"`
+
+	// History emits a change for every commit that touched a matched file
+	// since the last scrape, when the URL points to a git repository.
+	History *FileHistory `json:"history,omitempty" yaml:"history,omitempty"`
+}
+
+type FileHistory struct {
+	// MaxCommits is the maximum number of commits per file that are read in a single scrape, defaults to 100
+	MaxCommits int `json:"maxCommits,omitempty" yaml:"maxCommits,omitempty"`
+}
+
+func (h FileHistory) GetMaxCommits() int {
+	if h.MaxCommits <= 0 {
+		return 100
+	}
+	return h.MaxCommits
 }
 
 func (f File) RedactedString() string {
@@ -24,6 +24,14 @@ const (
 	GCPDisk       = "GCP::Disk"
 	GCPGKECluster = "GCP::GKECluster"
 
+	GCPCloudRunService    = "GCP::CloudRun::Service"
+	GCPCloudFunction      = "GCP::CloudFunction"
+	GCPFunction           = "GCP::Function"
+	GCPPubSubTopic        = "GCP::PubSub::Topic"
+	GCPPubSubSubscription = "GCP::Subscription"
+	GCPVPCAccessConnector = "GCP::Connector"
+	GCPSecret             = "GCP::Secret"
+
 	GCPManagedZone       = "GCP::ManagedZone"
 	GCPResourceRecordSet = "GCP::ResourceRecordSet"
 
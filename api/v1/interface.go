@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -132,6 +133,10 @@ type ChangeResult struct {
 	// selector for resolving target config items.
 	Target *duty.RelationshipSelectorTemplate `json:"-"`
 
+	// Aggregate is set by aggregate change mappings to roll up the changes
+	// of the same group into a single change.
+	Aggregate *ChangeAggregate `json:"-"`
+
 	// Resolved is the final state of the change after transformation by the
 	// change mapping pipeline. The original ChangeResult fields represent the
 	// scraper input; Resolved represents the pipeline output.
@@ -170,6 +175,106 @@ func (r *ChangeResult) FlushMap() {
 	r._map = nil
 }
 
+// ChangeAggregate is a group of changes rolled up into a single change.
+// +kubebuilder:object:generate=false
+type ChangeAggregate struct {
+	// Key of the group, returned by the group_by expression of the change mapping
+	Key     string
+	Window  time.Duration
+	Samples int
+
+	Count       int
+	First, Last time.Time
+	// SampleDetails of the most recent changes in the group
+	SampleDetails []map[string]any
+	// ExternalChangeIDs of the aggregated changes, to not count them twice
+	ExternalChangeIDs []string
+}
+
+// InWindow returns true if a change at the given time belongs to the group.
+func (a ChangeAggregate) InWindow(t time.Time) bool {
+	return !t.Before(a.First) && t.Sub(a.First) <= a.Window
+}
+
+// Add rolls up the change into the group.
+// Changes already aggregated, identified by their external change id, are skipped.
+func (a *ChangeAggregate) Add(change ChangeResult) bool {
+	if change.ExternalChangeID != "" {
+		if lo.Contains(a.ExternalChangeIDs, change.ExternalChangeID) {
+			return false
+		}
+		a.ExternalChangeIDs = append(a.ExternalChangeIDs, change.ExternalChangeID)
+	}
+
+	createdAt := lo.FromPtrOr(change.CreatedAt, time.Now())
+	if a.Count == 0 || createdAt.Before(a.First) {
+		a.First = createdAt
+	}
+	if createdAt.After(a.Last) {
+		a.Last = createdAt
+	}
+	a.Count++
+
+	sample := map[string]any{
+		"summary":    change.Summary,
+		"created_at": createdAt,
+		"details":    change.Details,
+	}
+	if change.ExternalChangeID != "" {
+		sample["external_change_id"] = change.ExternalChangeID
+	}
+	a.SampleDetails = append(a.SampleDetails, sample)
+	if a.Samples > 0 && len(a.SampleDetails) > a.Samples {
+		a.SampleDetails = a.SampleDetails[len(a.SampleDetails)-a.Samples:]
+	}
+	return true
+}
+
+// Merge rolls up the changes of another group of the same key into this one.
+// Changes that were already rolled up into this group aren't counted again.
+func (a *ChangeAggregate) Merge(other ChangeAggregate) {
+	count := other.Count
+	for _, id := range other.ExternalChangeIDs {
+		if lo.Contains(a.ExternalChangeIDs, id) {
+			count--
+		} else {
+			a.ExternalChangeIDs = append(a.ExternalChangeIDs, id)
+		}
+	}
+	if count <= 0 {
+		return
+	}
+
+	if a.Count == 0 || other.First.Before(a.First) {
+		a.First = other.First
+	}
+	if other.Last.After(a.Last) {
+		a.Last = other.Last
+	}
+	a.Count += count
+
+	for _, sample := range other.SampleDetails {
+		if id, ok := sample["external_change_id"]; ok && slices.ContainsFunc(a.SampleDetails, func(s map[string]any) bool { return s["external_change_id"] == id }) {
+			continue
+		}
+		a.SampleDetails = append(a.SampleDetails, sample)
+	}
+	if a.Samples > 0 && len(a.SampleDetails) > a.Samples {
+		a.SampleDetails = a.SampleDetails[len(a.SampleDetails)-a.Samples:]
+	}
+}
+
+// AsDetails returns the group as the details of the rolled up change.
+func (a ChangeAggregate) AsDetails() map[string]any {
+	return map[string]any{
+		"key":     a.Key,
+		"count":   a.Count,
+		"first":   a.First,
+		"last":    a.Last,
+		"samples": a.SampleDetails,
+	}
+}
+
 func (r ChangeResult) PatchesMap() map[string]any {
 	output := make(map[string]any)
 	if r.Patches != "" {
@@ -366,6 +471,23 @@ func warningCount(w Warning) int {
 	return 1
 }
 
+// CollectorSummary captures the outcome of a single collector within a scrape,
+// e.g. one AWS service in one region.
+// +kubebuilder:object:generate=false
+type CollectorSummary struct {
+	Results     int      `json:"results,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+	DurationSec float64  `json:"duration_secs,omitempty"`
+}
+
+func (a CollectorSummary) Merge(b CollectorSummary) CollectorSummary {
+	return CollectorSummary{
+		Results:     a.Results + b.Results,
+		Errors:      append(slices.Clone(a.Errors), b.Errors...),
+		DurationSec: a.DurationSec + b.DurationSec,
+	}
+}
+
 // +kubebuilder:object:generate=false
 type ScrapeSummary struct {
 	ConfigTypes map[string]ConfigTypeScrapeSummary `json:"config_types,omitempty"`
@@ -382,6 +504,10 @@ type ScrapeSummary struct {
 	ConfigAccess   EntitySummary[struct{}]             `json:"config_access,omitempty"`
 	AccessLogs     EntitySummary[struct{}]             `json:"access_logs,omitempty"`
 
+	// Collectors records per-collector timing and errors, keyed by
+	// collector name (e.g. "us-east-1/rds" for the AWS scraper).
+	Collectors map[string]CollectorSummary `json:"collectors,omitempty"`
+
 	OrphanedChanges []ChangeResult `json:"orphaned_changes,omitempty"`
 	FKErrorChanges  []ChangeResult `json:"fk_error_changes,omitempty"`
 	Warnings        []Warning      `json:"warnings,omitempty"`
@@ -428,7 +554,7 @@ func (s *ScrapeSummary) UnmarshalJSON(data []byte) error {
 	}
 
 	// Detect new-format payloads by checking for any known top-level key
-	newFormatKeys := []string{"config_types", "external_users", "external_groups", "external_roles", "config_access", "access_logs"}
+	newFormatKeys := []string{"config_types", "external_users", "external_groups", "external_roles", "config_access", "access_logs", "collectors"}
 	isNewFormat := false
 	for _, key := range newFormatKeys {
 		if _, ok := raw[key]; ok {
@@ -488,6 +614,9 @@ func (summary ConfigTypeScrapeSummary) String() string {
 		}
 		s = append(s, fmt.Sprintf("orphaned=%d", total))
 	}
+	if summary.Change != nil && len(summary.Change.RateLimited) > 0 {
+		s = append(s, fmt.Sprintf("rate_limited=%d", lo.Sum(lo.Values(summary.Change.RateLimited))))
+	}
 
 	return strings.Join(s, ", ")
 }
@@ -550,11 +679,23 @@ func (t *ScrapeSummary) AddChangeSummary(configType string, cs ChangeSummary) {
 		Ignored:          cs.Ignored,
 		IgnoredByAction:  cs.IgnoredByAction,
 		Orphaned:         cs.Orphaned,
+		RateLimited:      cs.RateLimited,
 		ForeignKeyErrors: cs.ForeignKeyErrors,
 	}
 	t.ConfigTypes[configType] = v
 }
 
+// AddRateLimited counts the changes dropped by the change rate limits
+func (t *ScrapeSummary) AddRateLimited(configType, changeType string, count int) {
+	t.initConfigTypes()
+	v := t.ConfigTypes[configType]
+	if v.Change == nil {
+		v.Change = &ChangeSummary{}
+	}
+	v.Change.AddRateLimited(changeType, count)
+	t.ConfigTypes[configType] = v
+}
+
 func (t *ScrapeSummary) AddInserted(configType string) {
 	t.initConfigTypes()
 	v := t.ConfigTypes[configType]
@@ -595,6 +736,20 @@ func (t *ScrapeSummary) AddScrapeWarning(w Warning) {
 	t.Warnings = append(t.Warnings, w)
 }
 
+func (t *ScrapeSummary) AddCollector(name string, c CollectorSummary) {
+	if t.Collectors == nil {
+		t.Collectors = make(map[string]CollectorSummary)
+	}
+	t.Collectors[name] = t.Collectors[name].Merge(c)
+}
+
+func (t *ScrapeSummary) SetState(key string, value any) {
+	if t.State == nil {
+		t.State = make(map[string]any)
+	}
+	t.State[key] = value
+}
+
 func (t *ScrapeSummary) AddChanges(configType string, count int) {
 	t.initConfigTypes()
 	v := t.ConfigTypes[configType]
@@ -623,6 +778,12 @@ func (s *ScrapeSummary) Merge(other ScrapeSummary) {
 	s.ExternalRoles = s.ExternalRoles.Merge(other.ExternalRoles)
 	s.ConfigAccess = s.ConfigAccess.Merge(other.ConfigAccess)
 	s.AccessLogs = s.AccessLogs.Merge(other.AccessLogs)
+	for name, c := range other.Collectors {
+		s.AddCollector(name, c)
+	}
+	for key, value := range other.State {
+		s.SetState(key, value)
+	}
 	for _, w := range other.Warnings {
 		s.AddScrapeWarning(w)
 	}
@@ -649,6 +810,7 @@ type ChangeSummary struct {
 	Orphaned         map[string]OrphanedChanges `json:"orphaned,omitempty"`
 	Ignored          map[string]int             `json:"ignored,omitempty"`
 	IgnoredByAction  map[string]map[string]int  `json:"ignored_by_action,omitempty"` // action -> change_type -> count
+	RateLimited      map[string]int             `json:"rate_limited,omitempty"`      // change_type -> count
 	ForeignKeyErrors int                        `json:"foreign_key_errors,omitempty"`
 }
 
@@ -659,7 +821,7 @@ type OrphanedChanges struct {
 }
 
 func (t ChangeSummary) IsEmpty() bool {
-	return len(t.Orphaned) == 0 && len(t.Ignored) == 0
+	return len(t.Orphaned) == 0 && len(t.Ignored) == 0 && len(t.RateLimited) == 0
 }
 
 func (t *ChangeSummary) AddOrphaned(typ, id string) {
@@ -694,6 +856,13 @@ func (t *ChangeSummary) AddIgnoredByAction(action, changeType string) {
 	t.IgnoredByAction[action][changeType] += 1
 }
 
+func (t *ChangeSummary) AddRateLimited(changeType string, count int) {
+	if t.RateLimited == nil {
+		t.RateLimited = make(map[string]int)
+	}
+	t.RateLimited[changeType] += count
+}
+
 func (t *ChangeSummary) Merge(b ChangeSummary) {
 	if b.Orphaned != nil {
 		if t.Orphaned == nil {
@@ -736,6 +905,10 @@ func (t *ChangeSummary) Merge(b ChangeSummary) {
 			}
 		}
 	}
+
+	for changeType, count := range b.RateLimited {
+		t.AddRateLimited(changeType, count)
+	}
 }
 
 func (t *ChangeSummary) Totals() (ignored, orphaned, errors int) {
@@ -1292,6 +1465,13 @@ type ScrapeResult struct {
 	ConfigAccessLogs []ExternalConfigAccessLog `json:"-"`
 	Warnings         []Warning                 `json:"-"`
 
+	// Collectors carries per-collector timing and errors into the ScrapeSummary.
+	Collectors map[string]CollectorSummary `json:"-"`
+
+	// State carries scraper state, such as resume cursors, into the ScrapeSummary
+	// so that the next run can read it back from the last scrape summary.
+	State map[string]any `json:"-"`
+
 	// Transform context captured for diagnostics.
 	TransformInput  any    `json:"-"`
 	TransformOutput any    `json:"-"`
@@ -1963,6 +2143,11 @@ func (s *ScrapeResult) AsMap() map[string]any {
 	return s._map
 }
 
+// FlushMap clears the map cached by AsMap, it must be called after modifying a result that was already converted.
+func (s *ScrapeResult) FlushMap() {
+	s._map = nil
+}
+
 func NewScrapeResult(base BaseScraper) *ScrapeResult {
 	return &ScrapeResult{
 		BaseScraper: base,
@@ -233,6 +233,23 @@ func AddEventResourceToWatch(watches []KubernetesResourceToWatch) []KubernetesRe
 	return watches
 }
 
+// AddRolloutResourcesToWatch watches ControllerRevisions when StatefulSets or DaemonSets are watched,
+// to follow their rollouts. ControllerRevisions from the watch are not saved as config items.
+func AddRolloutResourcesToWatch(watches []KubernetesResourceToWatch) []KubernetesResourceToWatch {
+	controllerRevision := KubernetesResourceToWatch{ApiVersion: "apps/v1", Kind: "ControllerRevision"}
+	if KubernetesResourcesToWatch(watches).Contains(controllerRevision) {
+		return watches
+	}
+
+	for _, w := range watches {
+		if w.ApiVersion == "apps/v1" && (w.Kind == "StatefulSet" || w.Kind == "DaemonSet") {
+			return append(watches, controllerRevision)
+		}
+	}
+
+	return watches
+}
+
 type Kubernetes struct {
 	BaseScraper                     `json:",inline"`
 	connection.KubernetesConnection `json:",inline"`
@@ -260,6 +277,10 @@ type Kubernetes struct {
 
 	// Relationships specify the fields to use to relate Kubernetes objects.
 	Relationships []KubernetesRelationshipSelectorTemplate `json:"relationships,omitempty"`
+
+	// HelmValues includes the values of Helm releases in their config and in the diffs of their revisions.
+	// Values often hold credentials, so they are left out by default.
+	HelmValues bool `json:"helmValues,omitempty"`
 }
 
 // Hash returns an identifier to uniquely identify this kubernetes config
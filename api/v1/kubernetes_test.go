@@ -37,3 +37,22 @@ var _ = Describe("KubernetesExclusionConfig", func() {
 			"test-foo", "default", "", nil, false),
 	)
 })
+
+var _ = Describe("AddRolloutResourcesToWatch", func() {
+	controllerRevision := KubernetesResourceToWatch{ApiVersion: "apps/v1", Kind: "ControllerRevision"}
+
+	It("watches ControllerRevisions with StatefulSets or DaemonSets", func() {
+		Expect(AddRolloutResourcesToWatch(DefaultWatchKinds)).To(ContainElement(controllerRevision))
+		Expect(DefaultWatchKinds).NotTo(ContainElement(controllerRevision))
+	})
+
+	It("does not watch ControllerRevisions without StatefulSets or DaemonSets", func() {
+		watches := []KubernetesResourceToWatch{{ApiVersion: "apps/v1", Kind: "Deployment"}}
+		Expect(AddRolloutResourcesToWatch(watches)).To(Equal(watches))
+	})
+
+	It("does not watch ControllerRevisions twice", func() {
+		watches := []KubernetesResourceToWatch{{ApiVersion: "apps/v1", Kind: "StatefulSet"}, controllerRevision}
+		Expect(AddRolloutResourcesToWatch(watches)).To(HaveLen(2))
+	})
+})
@@ -40,6 +40,9 @@ type ScrapePluginSpec struct {
 	// Retention config for changes, types, and stale items.
 	Retention *RetentionSpec `json:"retention,omitempty"`
 
+	// Correlation rules link saved changes to the changes on related config items that caused them.
+	Correlation []ChangeCorrelation `json:"correlation,omitempty"`
+
 	// Relationship allows you to form relationships between config items using selectors.
 	Relationship []RelationshipConfig `json:"relationship,omitempty"`
 
@@ -50,6 +53,15 @@ type ScrapePluginSpec struct {
 	Locations []LocationOrAlias `json:"locations,omitempty"`
 
 	Aliases []LocationOrAlias `json:"aliases,omitempty"`
+
+	// Health is an ordered list of rules that set the health and status of config items.
+	Health []HealthRule `json:"health,omitempty"`
+
+	// Analysis is a list of rules that report an analysis on the config items they match.
+	Analysis []AnalysisRule `json:"analysis,omitempty"`
+
+	// Rego is a list of OPA policies whose deny and warn rules report an analysis on the config items.
+	Rego []RegoPolicy `json:"rego,omitempty"`
 }
 
 func (t ScrapePlugin) ToModel() (*models.ScrapePlugin, error) {
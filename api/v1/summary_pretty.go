@@ -99,5 +99,15 @@ func (s *ScrapeSummary) Pretty() api.Text {
 	appendEntity("Config Access:", s.ConfigAccess)
 	appendEntity("Access Logs:", s.AccessLogs)
 
+	collectors := lo.Keys(s.Collectors)
+	sort.Strings(collectors)
+	for _, name := range collectors {
+		c := s.Collectors[name]
+		if len(c.Errors) == 0 {
+			continue
+		}
+		t = t.Append(name, "font-bold").Append(fmt.Sprintf(" errors=%d duration=%.1fs", len(c.Errors), c.DurationSec), "text-warning").NewLine()
+	}
+
 	return t
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/flanksource/clicky/api"
 	"github.com/flanksource/commons/duration"
 	"github.com/flanksource/config-db/utils"
+	"github.com/flanksource/duty/types"
 
 	"github.com/google/uuid"
 
@@ -50,10 +51,34 @@ type TypeRetentionSpec struct {
 	DeletedAge string `json:"deletedAge,omitempty"`
 }
 
+// AnalysisRetentionSpec deletes the analyses that match the analyzer, severity and status
+// and haven't been observed within the age.
+type AnalysisRetentionSpec struct {
+	// Analyzer of the analyses, empty matches all analyzers
+	Analyzer string `json:"analyzer,omitempty"`
+	// Severity of the analyses, empty matches all severities
+	Severity string `json:"severity,omitempty"`
+	// Status of the analyses, e.g. resolved. Empty matches all statuses
+	Status string `json:"status,omitempty"`
+	// Age since the analysis was last observed
+	Age string `json:"age"`
+}
+
+// RelationshipRetentionSpec deletes the relationships that were deleted,
+// or whose config items on either end were deleted, before the age.
+type RelationshipRetentionSpec struct {
+	// Relation of the relationships, empty matches all relations
+	Relation string `json:"relation,omitempty"`
+	// DeletedAge since the relationship or one of its config items was deleted
+	DeletedAge string `json:"deletedAge"`
+}
+
 type RetentionSpec struct {
-	Changes      []ChangeRetentionSpec `json:"changes,omitempty"`
-	Types        []TypeRetentionSpec   `json:"types,omitempty"`
-	StaleItemAge string                `json:"staleItemAge,omitempty"`
+	Changes       []ChangeRetentionSpec       `json:"changes,omitempty"`
+	Types         []TypeRetentionSpec         `json:"types,omitempty"`
+	Analysis      []AnalysisRetentionSpec     `json:"analysis,omitempty"`
+	Relationships []RelationshipRetentionSpec `json:"relationships,omitempty"`
+	StaleItemAge  string                      `json:"staleItemAge,omitempty"`
 	// StaleAnalysisAge is the duration after which an analysis that is no longer observed by the scraper
 	// is marked as resolved. Defaults to 48h. Use "keep" to disable auto-resolution.
 	StaleAnalysisAge string `json:"staleAnalysisAge,omitempty"`
@@ -62,6 +87,8 @@ type RetentionSpec struct {
 func (r RetentionSpec) Merge(other RetentionSpec) RetentionSpec {
 	r.Changes = append(r.Changes, other.Changes...)
 	r.Types = append(r.Types, other.Types...)
+	r.Analysis = append(r.Analysis, other.Analysis...)
+	r.Relationships = append(r.Relationships, other.Relationships...)
 	if r.StaleItemAge == "" && other.StaleItemAge != "" {
 		r.StaleItemAge = other.StaleItemAge
 	}
@@ -71,6 +98,91 @@ func (r RetentionSpec) Merge(other RetentionSpec) RetentionSpec {
 	return r
 }
 
+// ChangeRateLimit caps the number of changes of a type that are saved per config item in a window.
+// The changes over the limit are dropped and counted on a RateLimited change of the config item.
+type ChangeRateLimit struct {
+	// ChangeType of the changes to limit, empty matches all change types.
+	// Supports match expression
+	// Example: SuccessfulRescale, Lease*
+	ChangeType types.MatchExpression `json:"changeType,omitempty"`
+
+	// ConfigType of the config items, empty matches all config types.
+	// Supports match expression
+	// Example: Kubernetes::HorizontalPodAutoscaler
+	ConfigType types.MatchExpression `json:"configType,omitempty"`
+
+	// Max number of changes of a type that are saved per config item in the window
+	Max int `json:"max"`
+
+	// Window of the limit. Defaults to 1h.
+	Window string `json:"window,omitempty"`
+}
+
+func (l ChangeRateLimit) Matches(changeType, configType string) bool {
+	if l.ChangeType != "" && !l.ChangeType.Match(changeType) {
+		return false
+	}
+	return l.ConfigType == "" || l.ConfigType.Match(configType)
+}
+
+func (l ChangeRateLimit) GetWindow() (time.Duration, error) {
+	if l.Window == "" {
+		return time.Hour, nil
+	}
+
+	window, err := duration.ParseDuration(l.Window)
+	if err != nil {
+		return 0, fmt.Errorf("invalid change rate limit window %q: %w", l.Window, err)
+	}
+	return time.Duration(window), nil
+}
+
+// ChangeAttribution attributes the diff changes without an author to the nearest audit event,
+// e.g. a CloudTrail event, recorded on the same config item.
+// Unless the sources are set, only the AWS scrapers with CloudTrail and the Azure scrapers attribute changes.
+// GCP audit logs are recorded as config access logs rather than changes, so they are not used for attribution.
+type ChangeAttribution struct {
+	// Disabled turns off the attribution of diff changes.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Sources of the changes that are audit events. Supports wildcards.
+	// Defaults to AWS::CloudTrail*, Azure::ActivityLog and Azure::ResourceChanges
+	Sources []string `json:"sources,omitempty"`
+
+	// Window is the maximum duration between a diff change and its audit event. Defaults to 5m.
+	Window string `json:"window,omitempty"`
+}
+
+// IsEnabled returns whether the diff changes of the scraper are attributed.
+func (a ChangeAttribution) IsEnabled(spec ScraperSpec) bool {
+	if a.Disabled {
+		return false
+	}
+	if len(a.Sources) > 0 || len(spec.Azure) > 0 {
+		return true
+	}
+	return slices.ContainsFunc(spec.AWS, func(aws AWS) bool { return !aws.Excludes("cloudtrail") })
+}
+
+func (a ChangeAttribution) GetSources() []string {
+	if len(a.Sources) == 0 {
+		return []string{"AWS::CloudTrail*", "Azure::ActivityLog", "Azure::ResourceChanges"}
+	}
+	return a.Sources
+}
+
+func (a ChangeAttribution) GetWindow() (time.Duration, error) {
+	if a.Window == "" {
+		return 5 * time.Minute, nil
+	}
+
+	window, err := duration.ParseDuration(a.Window)
+	if err != nil {
+		return 0, fmt.Errorf("invalid change attribution window %q: %w", a.Window, err)
+	}
+	return time.Duration(window), nil
+}
+
 // ScraperSpec defines the desired state of Config scraper
 type ScraperSpec struct {
 	// LogLevel sets the log level for the scraper. Supported values are "trace", "debug", "info" Default is "info".
@@ -113,6 +225,15 @@ type ScraperSpec struct {
 
 	// Full flag when set will try to extract out changes from the scraped config.
 	Full bool `json:"full,omitempty"`
+
+	// Correlation rules link saved changes to the changes on related config items that caused them.
+	Correlation []ChangeCorrelation `json:"correlation,omitempty"`
+
+	// ChangeRateLimits throttle the changes saved per config item, the first matching limit applies.
+	ChangeRateLimits []ChangeRateLimit `json:"changeRateLimits,omitempty"`
+
+	// Attribution of diff changes to the audit events that caused them.
+	Attribution ChangeAttribution `json:"attribution,omitempty"`
 }
 
 // TimeoutDuration returns the configured scrape timeout, falling back to defaultTimeout.
@@ -135,6 +256,7 @@ func (c ScraperSpec) ApplyPlugin(plugins []ScrapePluginSpec) ScraperSpec {
 		if p.Retention != nil {
 			spec.Retention = spec.Retention.Merge(*p.Retention)
 		}
+		spec.Correlation = append(spec.Correlation, p.Correlation...)
 	}
 
 	for i := range spec.GCP {
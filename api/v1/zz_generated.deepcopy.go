@@ -151,6 +151,36 @@ func (in *AWSS3) DeepCopy() *AWSS3 {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnalysisRetentionSpec) DeepCopyInto(out *AnalysisRetentionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnalysisRetentionSpec.
+func (in *AnalysisRetentionSpec) DeepCopy() *AnalysisRetentionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AnalysisRetentionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnalysisRule) DeepCopyInto(out *AnalysisRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnalysisRule.
+func (in *AnalysisRule) DeepCopy() *AnalysisRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AnalysisRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Authentication) DeepCopyInto(out *Authentication) {
 	*out = *in
@@ -189,6 +219,11 @@ func (in *Azure) DeepCopyInto(out *Azure) {
 		*out = new(Entra)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ResourceChanges != nil {
+		in, out := &in.ResourceChanges, &out.ResourceChanges
+		*out = new(AzureResourceChanges)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Azure.
@@ -356,6 +391,21 @@ func (in *AzureLogAnalyticsConfig) DeepCopy() *AzureLogAnalyticsConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureResourceChanges) DeepCopyInto(out *AzureResourceChanges) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureResourceChanges.
+func (in *AzureResourceChanges) DeepCopy() *AzureResourceChanges {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureResourceChanges)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BaseScraper) DeepCopyInto(out *BaseScraper) {
 	*out = *in
@@ -409,6 +459,79 @@ func (in *BigQueryConfig) DeepCopy() *BigQueryConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeAttribution) DeepCopyInto(out *ChangeAttribution) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeAttribution.
+func (in *ChangeAttribution) DeepCopy() *ChangeAttribution {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeAttribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeCorrelation) DeepCopyInto(out *ChangeCorrelation) {
+	*out = *in
+	out.Cause = in.Cause
+	out.Effect = in.Effect
+	in.Relationship.DeepCopyInto(&out.Relationship)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeCorrelation.
+func (in *ChangeCorrelation) DeepCopy() *ChangeCorrelation {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeCorrelation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeCorrelationPath) DeepCopyInto(out *ChangeCorrelationPath) {
+	*out = *in
+	if in.MaxDepth != nil {
+		in, out := &in.MaxDepth, &out.MaxDepth
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeCorrelationPath.
+func (in *ChangeCorrelationPath) DeepCopy() *ChangeCorrelationPath {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeCorrelationPath)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeCorrelationSelector) DeepCopyInto(out *ChangeCorrelationSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeCorrelationSelector.
+func (in *ChangeCorrelationSelector) DeepCopy() *ChangeCorrelationSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeCorrelationSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChangeExtractionMapping) DeepCopyInto(out *ChangeExtractionMapping) {
 	*out = *in
@@ -476,6 +599,21 @@ func (in *ChangeMapping) DeepCopy() *ChangeMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeRateLimit) DeepCopyInto(out *ChangeRateLimit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeRateLimit.
+func (in *ChangeRateLimit) DeepCopy() *ChangeRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChangeRetentionSpec) DeepCopyInto(out *ChangeRetentionSpec) {
 	*out = *in
@@ -881,6 +1019,11 @@ func (in *File) DeepCopyInto(out *File) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = new(FileHistory)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new File.
@@ -893,6 +1036,21 @@ func (in *File) DeepCopy() *File {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileHistory) DeepCopyInto(out *FileHistory) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileHistory.
+func (in *FileHistory) DeepCopy() *FileHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(FileHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FileLocation) DeepCopyInto(out *FileLocation) {
 	*out = *in
@@ -1184,6 +1342,21 @@ func (in *HTTP) DeepCopy() *HTTP {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthRule) DeepCopyInto(out *HealthRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthRule.
+func (in *HealthRule) DeepCopy() *HealthRule {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IncrementalStatus) DeepCopyInto(out *IncrementalStatus) {
 	*out = *in
@@ -1934,6 +2107,53 @@ func (in *QueryRequest) DeepCopy() *QueryRequest {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegoConfigMap) DeepCopyInto(out *RegoConfigMap) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegoConfigMap.
+func (in *RegoConfigMap) DeepCopy() *RegoConfigMap {
+	if in == nil {
+		return nil
+	}
+	out := new(RegoConfigMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegoPolicy) DeepCopyInto(out *RegoPolicy) {
+	*out = *in
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(RegoConfigMap)
+		**out = **in
+	}
+	if in.Checkout != nil {
+		in, out := &in.Checkout, &out.Checkout
+		*out = new(connection.GitConnection)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegoPolicy.
+func (in *RegoPolicy) DeepCopy() *RegoPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RegoPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RelationshipConfig) DeepCopyInto(out *RelationshipConfig) {
 	*out = *in
@@ -1988,6 +2208,21 @@ func (in RelationshipResults) DeepCopy() RelationshipResults {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelationshipRetentionSpec) DeepCopyInto(out *RelationshipRetentionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelationshipRetentionSpec.
+func (in *RelationshipRetentionSpec) DeepCopy() *RelationshipRetentionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RelationshipRetentionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceSelector) DeepCopyInto(out *ResourceSelector) {
 	*out = *in
@@ -2016,6 +2251,16 @@ func (in *RetentionSpec) DeepCopyInto(out *RetentionSpec) {
 		*out = make([]TypeRetentionSpec, len(*in))
 		copy(*out, *in)
 	}
+	if in.Analysis != nil {
+		in, out := &in.Analysis, &out.Analysis
+		*out = make([]AnalysisRetentionSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Relationships != nil {
+		in, out := &in.Relationships, &out.Relationships
+		*out = make([]RelationshipRetentionSpec, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionSpec.
@@ -2213,6 +2458,13 @@ func (in *ScrapePluginSpec) DeepCopyInto(out *ScrapePluginSpec) {
 		*out = new(RetentionSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Correlation != nil {
+		in, out := &in.Correlation, &out.Correlation
+		*out = make([]ChangeCorrelation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Relationship != nil {
 		in, out := &in.Relationship, &out.Relationship
 		*out = make([]RelationshipConfig, len(*in))
@@ -2241,6 +2493,23 @@ func (in *ScrapePluginSpec) DeepCopyInto(out *ScrapePluginSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = make([]HealthRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Analysis != nil {
+		in, out := &in.Analysis, &out.Analysis
+		*out = make([]AnalysisRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rego != nil {
+		in, out := &in.Rego, &out.Rego
+		*out = make([]RegoPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScrapePluginSpec.
@@ -2510,6 +2779,19 @@ func (in *ScraperSpec) DeepCopyInto(out *ScraperSpec) {
 		}
 	}
 	in.Retention.DeepCopyInto(&out.Retention)
+	if in.Correlation != nil {
+		in, out := &in.Correlation, &out.Correlation
+		*out = make([]ChangeCorrelation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ChangeRateLimits != nil {
+		in, out := &in.ChangeRateLimits, &out.ChangeRateLimits
+		*out = make([]ChangeRateLimit, len(*in))
+		copy(*out, *in)
+	}
+	in.Attribution.DeepCopyInto(&out.Attribution)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScraperSpec.
@@ -2772,6 +3054,23 @@ func (in *Transform) DeepCopyInto(out *Transform) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = make([]HealthRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Analysis != nil {
+		in, out := &in.Analysis, &out.Analysis
+		*out = make([]AnalysisRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rego != nil {
+		in, out := &in.Rego, &out.Rego
+		*out = make([]RegoPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Transform.
@@ -0,0 +1,512 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flanksource/clicky"
+	clickyapi "github.com/flanksource/clicky/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/cmd/scrapeui"
+	"github.com/flanksource/config-db/db"
+	"github.com/flanksource/duty"
+	dutyapi "github.com/flanksource/duty/api"
+	dutycontext "github.com/flanksource/duty/context"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+// diffSourceDB is the source name that compares against the live database
+const diffSourceDB = "db"
+
+type diffStatus string
+
+const (
+	diffAdded    diffStatus = "added"
+	diffRemoved  diffStatus = "removed"
+	diffModified diffStatus = "modified"
+)
+
+type DiffOptions struct {
+	Sources   []string `args:"true" required:"true" help:"two result directories or JSON files, or one of them and 'db'"`
+	Scraper   string   `flag:"scraper" help:"id of the scraper whose config items are compared when a source is 'db'"`
+	Threshold int      `flag:"threshold" default:"-1" help:"exit with an error when the number of differences exceeds the threshold, -1 to disable"`
+}
+
+func (DiffOptions) GetName() string {
+	return "diff <before> <after>"
+}
+
+// diffSource is one side of a diff.
+type diffSource struct {
+	name          string
+	results       v1.FullScrapeResults
+	relationships []scrapeui.UIRelationship
+
+	// Exported result directories carry neither relationships nor config access,
+	// so they are only compared when both sides have them.
+	hasRelationships bool
+	hasAccess        bool
+}
+
+type configDiff struct {
+	Status     diffStatus `json:"status"`
+	ConfigType string     `json:"config_type"`
+	ID         string     `json:"id"`
+	Name       string     `json:"name,omitempty"`
+	Diff       string     `json:"diff,omitempty"`
+}
+
+func (d configDiff) Columns() []clickyapi.ColumnDef {
+	return []clickyapi.ColumnDef{
+		clicky.Column("Status").Build(),
+		clicky.Column("ConfigType").Build(),
+		clicky.Column("ID").Build(),
+		clicky.Column("Name").Build(),
+	}
+}
+
+func (d configDiff) Row() map[string]any {
+	return map[string]any{
+		"Status":     d.Status.text(),
+		"ConfigType": d.ConfigType,
+		"ID":         d.ID,
+		"Name":       d.Name,
+	}
+}
+
+func (d configDiff) RowDetail() clickyapi.Textable {
+	return clicky.Text(d.Diff, "font-mono")
+}
+
+type relationshipDiff struct {
+	Status      diffStatus `json:"status"`
+	ConfigID    string     `json:"config_id"`
+	RelatedID   string     `json:"related_id"`
+	Relation    string     `json:"relation,omitempty"`
+	ConfigName  string     `json:"config_name,omitempty"`
+	RelatedName string     `json:"related_name,omitempty"`
+}
+
+func (d relationshipDiff) Columns() []clickyapi.ColumnDef {
+	return []clickyapi.ColumnDef{
+		clicky.Column("Status").Build(),
+		clicky.Column("Config").Build(),
+		clicky.Column("Relation").Build(),
+		clicky.Column("Related").Build(),
+	}
+}
+
+func (d relationshipDiff) Row() map[string]any {
+	return map[string]any{
+		"Status":   d.Status.text(),
+		"Config":   lo.CoalesceOrEmpty(d.ConfigName, d.ConfigID),
+		"Relation": d.Relation,
+		"Related":  lo.CoalesceOrEmpty(d.RelatedName, d.RelatedID),
+	}
+}
+
+type accessDiff struct {
+	Status     diffStatus `json:"status"`
+	ConfigType string     `json:"config_type"`
+	ConfigID   string     `json:"config_id"`
+	User       string     `json:"user,omitempty"`
+	Group      string     `json:"group,omitempty"`
+	Role       string     `json:"role,omitempty"`
+}
+
+func (d accessDiff) Columns() []clickyapi.ColumnDef {
+	return []clickyapi.ColumnDef{
+		clicky.Column("Status").Build(),
+		clicky.Column("ConfigType").Build(),
+		clicky.Column("ConfigID").Build(),
+		clicky.Column("User").Build(),
+		clicky.Column("Group").Build(),
+		clicky.Column("Role").Build(),
+	}
+}
+
+func (d accessDiff) Row() map[string]any {
+	return map[string]any{
+		"Status":     d.Status.text(),
+		"ConfigType": d.ConfigType,
+		"ConfigID":   d.ConfigID,
+		"User":       d.User,
+		"Group":      d.Group,
+		"Role":       d.Role,
+	}
+}
+
+func (s diffStatus) text() clickyapi.Textable {
+	switch s {
+	case diffAdded:
+		return clicky.Text("+ "+string(s), "text-green-600")
+	case diffRemoved:
+		return clicky.Text("- "+string(s), "text-red-600")
+	default:
+		return clicky.Text("~ "+string(s), "text-yellow-600")
+	}
+}
+
+// ResultsDiff is the difference between two result sets.
+type ResultsDiff struct {
+	Before        string             `json:"before"`
+	After         string             `json:"after"`
+	Configs       []configDiff       `json:"configs,omitempty"`
+	Relationships []relationshipDiff `json:"relationships,omitempty"`
+	Access        []accessDiff       `json:"access,omitempty"`
+}
+
+func (d ResultsDiff) Total() int {
+	return len(d.Configs) + len(d.Relationships) + len(d.Access)
+}
+
+func (d ResultsDiff) Pretty() clickyapi.Text {
+	t := clicky.Text(fmt.Sprintf("%s → %s: %d differences", d.Before, d.After, d.Total()), "font-bold")
+	if len(d.Configs) > 0 {
+		t = t.NewLine().Append("Config Items", "font-bold").NewLine().Add(clickyapi.NewTableFrom(d.Configs))
+	}
+	if len(d.Relationships) > 0 {
+		t = t.NewLine().Append("Relationships", "font-bold").NewLine().Add(clickyapi.NewTableFrom(d.Relationships))
+	}
+	if len(d.Access) > 0 {
+		t = t.NewLine().Append("Config Access", "font-bold").NewLine().Add(clickyapi.NewTableFrom(d.Access))
+	}
+	return t
+}
+
+type diffFailure struct {
+	diff ResultsDiff
+	err  error
+}
+
+func (failure diffFailure) Error() string {
+	return failure.err.Error()
+}
+
+func (failure diffFailure) Unwrap() error {
+	return failure.err
+}
+
+func (failure diffFailure) Pretty() clickyapi.Text {
+	return failure.diff.Pretty()
+}
+
+func (failure diffFailure) MarshalJSON() ([]byte, error) {
+	return json.Marshal(failure.diff)
+}
+
+var Diff *cobra.Command
+
+func runDiff(options DiffOptions) (ResultsDiff, error) {
+	clicky.Flags.UseFlags()
+
+	if len(options.Sources) != 2 {
+		return ResultsDiff{}, fmt.Errorf("diff requires exactly two result sets")
+	}
+	if options.Sources[0] == diffSourceDB && options.Sources[1] == diffSourceDB {
+		return ResultsDiff{}, fmt.Errorf("at most one side of a diff can be the database")
+	}
+
+	ctx := dutycontext.New()
+	if lo.Contains(options.Sources, diffSourceDB) {
+		if _, err := uuid.Parse(options.Scraper); err != nil {
+			return ResultsDiff{}, fmt.Errorf("comparing against the database requires a --scraper id: %w", err)
+		}
+		if dutyapi.DefaultConfig.ReadEnv().ConnectionString == "" {
+			return ResultsDiff{}, fmt.Errorf("comparing against the database requires a configured database")
+		}
+
+		c, _, err := duty.Start(app, duty.ClientOnly)
+		if err != nil {
+			return ResultsDiff{}, fmt.Errorf("initialize database: %w", err)
+		}
+		ctx = c
+	}
+
+	before, err := loadDiffSource(ctx, options.Sources[0], options.Scraper)
+	if err != nil {
+		return ResultsDiff{}, err
+	}
+	after, err := loadDiffSource(ctx, options.Sources[1], options.Scraper)
+	if err != nil {
+		return ResultsDiff{}, err
+	}
+
+	diff, err := diffSources(ctx, before, after)
+	if err != nil {
+		return ResultsDiff{}, err
+	}
+
+	if options.Threshold >= 0 && diff.Total() > options.Threshold {
+		return ResultsDiff{}, diffFailure{
+			diff: diff,
+			err:  fmt.Errorf("%d differences exceed the threshold of %d", diff.Total(), options.Threshold),
+		}
+	}
+	return diff, nil
+}
+
+// loadDiffSource loads the results of the database, an exported result directory (run --output-dir)
+// or a results JSON file (run --json).
+func loadDiffSource(ctx dutycontext.Context, source, scraperID string) (diffSource, error) {
+	if source == diffSourceDB {
+		results, err := db.GetScraperResults(ctx, scraperID)
+		if err != nil {
+			return diffSource{}, err
+		}
+		return diffSource{
+			name:             diffSourceDB,
+			results:          results,
+			relationships:    scrapeui.BuildUIRelationshipsFromDB(results.Relationships, results.Configs),
+			hasRelationships: true,
+			hasAccess:        true,
+		}, nil
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return diffSource{}, fmt.Errorf("inspect diff source %q: %w", source, err)
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return diffSource{}, fmt.Errorf("read diff source %q: %w", source, err)
+		}
+		snap, err := parseUISnapshot(data)
+		if err != nil {
+			return diffSource{}, fmt.Errorf("parse diff source %q: %w", source, err)
+		}
+		return diffSource{
+			name:             source,
+			results:          snap.Results,
+			relationships:    snap.Relationships,
+			hasRelationships: true,
+			hasAccess:        true,
+		}, nil
+	}
+
+	var configs []v1.ScrapeResult
+	err = filepath.WalkDir(source, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// changes are exported next to the config items, and are not compared
+		if entry.IsDir() && path != source && entry.Name() == "changes" {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var result v1.ScrapeResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		configs = append(configs, result)
+		return nil
+	})
+	if err != nil {
+		return diffSource{}, fmt.Errorf("read diff source %q: %w", source, err)
+	}
+
+	return diffSource{name: source, results: v1.FullScrapeResults{Configs: configs}}, nil
+}
+
+func diffSources(ctx dutycontext.Context, before, after diffSource) (ResultsDiff, error) {
+	diff := ResultsDiff{Before: before.name, After: after.name}
+
+	configs, err := diffConfigs(ctx, before.results.Configs, after.results.Configs)
+	if err != nil {
+		return diff, err
+	}
+	diff.Configs = configs
+
+	if before.hasRelationships && after.hasRelationships {
+		diff.Relationships = diffRelationships(before.relationships, after.relationships)
+	}
+	if before.hasAccess && after.hasAccess {
+		diff.Access = diffAccess(before.results.ConfigAccess, after.results.ConfigAccess)
+	}
+	return diff, nil
+}
+
+func diffConfigs(ctx dutycontext.Context, before, after []v1.ScrapeResult) ([]configDiff, error) {
+	key := func(r v1.ScrapeResult) string {
+		return strings.ToLower(r.Type + "/" + r.ID)
+	}
+	index := func(results []v1.ScrapeResult) map[string]v1.ScrapeResult {
+		m := make(map[string]v1.ScrapeResult, len(results))
+		for _, r := range results {
+			if r.ID != "" && r.Config != nil {
+				m[key(r)] = r
+			}
+		}
+		return m
+	}
+	beforeByKey, afterByKey := index(before), index(after)
+
+	var diffs []configDiff
+	for k, b := range beforeByKey {
+		if _, ok := afterByKey[k]; !ok {
+			diffs = append(diffs, configDiff{Status: diffRemoved, ConfigType: b.Type, ID: b.ID, Name: b.Name})
+		}
+	}
+
+	for k, a := range afterByKey {
+		b, ok := beforeByKey[k]
+		if !ok {
+			diffs = append(diffs, configDiff{Status: diffAdded, ConfigType: a.Type, ID: a.ID, Name: a.Name})
+			continue
+		}
+
+		prev, err := configJSON(b.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal config %s/%s: %w", b.Type, b.ID, err)
+		}
+		curr, err := configJSON(a.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal config %s/%s: %w", a.Type, a.ID, err)
+		}
+
+		changes, err := db.GenerateDiff(ctx, curr, prev)
+		if err != nil {
+			// configs that are not JSON objects are compared as text
+			changes = db.TextDiff(prev, curr)
+		}
+		if changes != "" {
+			diffs = append(diffs, configDiff{Status: diffModified, ConfigType: a.Type, ID: a.ID, Name: a.Name, Diff: changes})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].ConfigType != diffs[j].ConfigType {
+			return diffs[i].ConfigType < diffs[j].ConfigType
+		}
+		return diffs[i].ID < diffs[j].ID
+	})
+	return diffs, nil
+}
+
+func configJSON(config any) (string, error) {
+	if s, ok := config.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(config)
+	return string(data), err
+}
+
+func diffRelationships(before, after []scrapeui.UIRelationship) []relationshipDiff {
+	key := func(r scrapeui.UIRelationship) string {
+		return strings.ToLower(r.ConfigExternalID + "|" + r.RelatedExternalID + "|" + r.Relation)
+	}
+	toDiff := func(status diffStatus, r scrapeui.UIRelationship) relationshipDiff {
+		return relationshipDiff{
+			Status:      status,
+			ConfigID:    r.ConfigExternalID,
+			RelatedID:   r.RelatedExternalID,
+			Relation:    r.Relation,
+			ConfigName:  r.ConfigName,
+			RelatedName: r.RelatedName,
+		}
+	}
+
+	beforeByKey := lo.SliceToMap(before, func(r scrapeui.UIRelationship) (string, scrapeui.UIRelationship) { return key(r), r })
+	afterByKey := lo.SliceToMap(after, func(r scrapeui.UIRelationship) (string, scrapeui.UIRelationship) { return key(r), r })
+
+	var diffs []relationshipDiff
+	for k, r := range beforeByKey {
+		if _, ok := afterByKey[k]; !ok {
+			diffs = append(diffs, toDiff(diffRemoved, r))
+		}
+	}
+	for k, r := range afterByKey {
+		if _, ok := beforeByKey[k]; !ok {
+			diffs = append(diffs, toDiff(diffAdded, r))
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].ConfigID != diffs[j].ConfigID {
+			return diffs[i].ConfigID < diffs[j].ConfigID
+		}
+		if diffs[i].RelatedID != diffs[j].RelatedID {
+			return diffs[i].RelatedID < diffs[j].RelatedID
+		}
+		return diffs[i].Relation < diffs[j].Relation
+	})
+	return diffs
+}
+
+// diffAccess compares config access entries by their config item and the lowest alias of their user, group and role,
+// as the aliases saved in the database are normalized and sorted.
+func diffAccess(before, after []v1.ExternalConfigAccess) []accessDiff {
+	toDiff := func(a v1.ExternalConfigAccess) accessDiff {
+		return accessDiff{
+			ConfigType: a.ConfigExternalID.ConfigType,
+			ConfigID:   a.ConfigExternalID.ExternalID,
+			User:       lowestAlias(a.ExternalUserAliases),
+			Group:      lowestAlias(a.ExternalGroupAliases),
+			Role:       lowestAlias(a.ExternalRoleAliases),
+		}
+	}
+	key := func(d accessDiff) string {
+		return strings.ToLower(strings.Join([]string{d.ConfigType, d.ConfigID, d.User, d.Group, d.Role}, "|"))
+	}
+	index := func(access []v1.ExternalConfigAccess) map[string]accessDiff {
+		m := make(map[string]accessDiff, len(access))
+		for _, a := range access {
+			d := toDiff(a)
+			m[key(d)] = d
+		}
+		return m
+	}
+	beforeByKey, afterByKey := index(before), index(after)
+
+	var diffs []accessDiff
+	for k, d := range beforeByKey {
+		if _, ok := afterByKey[k]; !ok {
+			d.Status = diffRemoved
+			diffs = append(diffs, d)
+		}
+	}
+	for k, d := range afterByKey {
+		if _, ok := beforeByKey[k]; !ok {
+			d.Status = diffAdded
+			diffs = append(diffs, d)
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return key(diffs[i]) < key(diffs[j])
+	})
+	return diffs
+}
+
+func lowestAlias(aliases []string) string {
+	normalized := lo.FilterMap(aliases, func(alias string, _ int) (string, bool) {
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		return alias, alias != ""
+	})
+	if len(normalized) == 0 {
+		return ""
+	}
+	return lo.Min(normalized)
+}
+
+func init() {
+	Diff = clicky.AddCommand(Root, DiffOptions{}, runDiff)
+	Diff.Short = "Compare two scrape results, or a scrape result against the database"
+	duty.BindPFlags(Diff.Flags(), duty.SkipMigrationByDefaultMode)
+	clicky.BindAllFlags(Diff.Flags(), "format")
+}
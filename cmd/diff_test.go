@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/cmd/scrapeui"
+	dutycontext "github.com/flanksource/duty/context"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("diff", func() {
+	pod := func(id string, image string) v1.ScrapeResult {
+		return v1.ScrapeResult{
+			ID:     id,
+			Name:   id,
+			Type:   "Kubernetes::Pod",
+			Config: map[string]any{"kind": "Pod", "spec": map[string]any{"image": image}},
+		}
+	}
+
+	It("reports added, removed and modified config items", func() {
+		before := diffSource{name: "before", results: v1.FullScrapeResults{Configs: []v1.ScrapeResult{
+			pod("pod-removed", "nginx:1"),
+			pod("pod-modified", "nginx:1"),
+			pod("pod-unchanged", "nginx:1"),
+		}}}
+		after := diffSource{name: "after", results: v1.FullScrapeResults{Configs: []v1.ScrapeResult{
+			pod("pod-added", "nginx:1"),
+			pod("pod-modified", "nginx:2"),
+			pod("pod-unchanged", "nginx:1"),
+		}}}
+
+		diff, err := diffSources(dutycontext.New(), before, after)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(diff.Configs).To(HaveLen(3))
+		Expect(diff.Configs[0]).To(MatchFields(IgnoreExtras, Fields{"Status": Equal(diffAdded), "ID": Equal("pod-added")}))
+		Expect(diff.Configs[1]).To(MatchFields(IgnoreExtras, Fields{"Status": Equal(diffModified), "ID": Equal("pod-modified")}))
+		Expect(diff.Configs[1].Diff).To(ContainSubstring("nginx:2"))
+		Expect(diff.Configs[2]).To(MatchFields(IgnoreExtras, Fields{"Status": Equal(diffRemoved), "ID": Equal("pod-removed")}))
+	})
+
+	It("compares relationships and access only when both sides have them", func() {
+		access := v1.ExternalConfigAccess{
+			ConfigExternalID:    v1.ExternalID{ConfigType: "Kubernetes::Pod", ExternalID: "pod-1"},
+			ExternalUserAliases: []string{"Bob@example.com", "bob"},
+		}
+		before := diffSource{
+			name:             "before",
+			results:          v1.FullScrapeResults{ConfigAccess: []v1.ExternalConfigAccess{access}},
+			relationships:    []scrapeui.UIRelationship{{ConfigExternalID: "deploy-1", RelatedExternalID: "pod-1", Relation: "DeploymentPod"}},
+			hasRelationships: true,
+			hasAccess:        true,
+		}
+		after := diffSource{name: "after"}
+
+		diff, err := diffSources(dutycontext.New(), before, after)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Total()).To(BeZero())
+
+		after.hasRelationships, after.hasAccess = true, true
+		diff, err = diffSources(dutycontext.New(), before, after)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Relationships).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Status":    Equal(diffRemoved),
+			"ConfigID":  Equal("deploy-1"),
+			"RelatedID": Equal("pod-1"),
+		})))
+		Expect(diff.Access).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Status": Equal(diffRemoved),
+			"User":   Equal("bob"),
+		})))
+	})
+
+	It("loads exported result directories and results JSON files", func() {
+		dir := GinkgoT().TempDir()
+		Expect(exportResource(pod("pod-1", "nginx:1"), dir)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, "changes"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "changes", "change-1.json"), []byte(`{"change_type":"diff"}`), 0644)).To(Succeed())
+
+		exported, err := loadDiffSource(dutycontext.New(), dir, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exported.results.Configs).To(HaveLen(1))
+		Expect(exported.hasRelationships).To(BeFalse())
+
+		data, err := json.Marshal(scrapeui.Snapshot{
+			Results: v1.FullScrapeResults{Configs: []v1.ScrapeResult{pod("pod-1", "nginx:2")}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		file := filepath.Join(GinkgoT().TempDir(), "results.json")
+		Expect(os.WriteFile(file, data, 0644)).To(Succeed())
+
+		snapshot, err := loadDiffSource(dutycontext.New(), file, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.hasRelationships).To(BeTrue())
+
+		diff, err := diffSources(dutycontext.New(), exported, snapshot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Configs).To(ConsistOf(MatchFields(IgnoreExtras, Fields{"Status": Equal(diffModified), "ID": Equal("pod-1")})))
+	})
+
+	It("requires exactly two result sets", func() {
+		_, err := runDiff(DiffOptions{Sources: []string{"results.json"}})
+
+		Expect(err).To(MatchError("diff requires exactly two result sets"))
+	})
+})
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/flanksource/clicky"
+	clickyapi "github.com/flanksource/clicky/api"
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db"
+	"github.com/samber/lo"
+)
+
+// dryRunReport is what saving the results of a scraper would have written to the database.
+type dryRunReport struct {
+	Scraper  string            `json:"scraper"`
+	Inserted []string          `json:"inserted,omitempty"`
+	Updated  []string          `json:"updated,omitempty"`
+	Deleted  []string          `json:"deleted,omitempty"`
+	Changes  map[string]int    `json:"changes,omitempty"`
+	Dropped  map[string]int    `json:"dropped,omitempty"`
+	Summary  *v1.ScrapeSummary `json:"summary,omitempty"`
+}
+
+func newDryRunReport(scraper string, results v1.ScrapeResults, summary *v1.ScrapeSummary, changes map[string]int) dryRunReport {
+	report := dryRunReport{Scraper: scraper, Changes: changes, Dropped: map[string]int{}, Summary: summary}
+
+	for _, result := range results {
+		if result.Resolved == nil {
+			continue
+		}
+		name := fmt.Sprintf("%s/%s", result.Type, lo.CoalesceOrEmpty(result.Name, result.ID))
+		switch {
+		case result.DeletedAt != nil && result.Resolved.Action != "unchanged":
+			report.Deleted = append(report.Deleted, name)
+		case result.Resolved.Action == "inserted":
+			report.Inserted = append(report.Inserted, name)
+		case result.Resolved.Action == "updated":
+			report.Updated = append(report.Updated, name)
+		}
+	}
+
+	for _, change := range v1.MergeScrapeResults(results).Changes {
+		if change.Resolved == nil {
+			continue
+		}
+		switch v1.ChangeAction(change.Resolved.Action) {
+		case v1.Ignore:
+			report.Dropped["ignored"]++
+		case v1.Delete:
+			report.Deleted = append(report.Deleted, fmt.Sprintf("%s/%s", change.ConfigType, change.ExternalID))
+		}
+	}
+
+	if summary != nil {
+		for _, configType := range summary.ConfigTypes {
+			if configType.Change == nil {
+				continue
+			}
+			for _, count := range configType.Change.RateLimited {
+				report.Dropped["rate limited"] += count
+			}
+			for _, orphaned := range configType.Change.Orphaned {
+				report.Dropped["orphaned"] += orphaned.Count
+			}
+		}
+		report.Dropped["foreign key errors"] += len(summary.FKErrorChanges)
+	}
+	report.Dropped = lo.PickBy(report.Dropped, func(_ string, count int) bool { return count > 0 })
+
+	return report
+}
+
+func (report dryRunReport) Pretty() clickyapi.Text {
+	t := clicky.Text("Dry run of ", "font-bold").Append(report.Scraper, "font-bold").Append(", nothing was saved", "text-muted").NewLine()
+
+	appendNames := func(label, style string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		sort.Strings(names)
+		t = t.Append(fmt.Sprintf("%s (%d):", label, len(names)), style)
+		for _, name := range names {
+			t = t.NewLine().Append("  " + name)
+		}
+		t = t.NewLine()
+	}
+	appendCounts := func(label, style string, counts map[string]int) {
+		if len(counts) == 0 {
+			return
+		}
+		t = t.Append(label, style)
+		keys := lo.Keys(counts)
+		sort.Strings(keys)
+		for _, key := range keys {
+			t = t.Append(fmt.Sprintf(" %s=%d", key, counts[key]))
+		}
+		t = t.NewLine()
+	}
+
+	appendNames("Insert", "text-green-600", report.Inserted)
+	appendNames("Update", "text-yellow-600", report.Updated)
+	appendNames("Delete", "text-red-600", report.Deleted)
+	appendCounts("Changes:", "font-bold", report.Changes)
+	appendCounts("Dropped changes:", "text-warning", report.Dropped)
+	if report.Summary != nil && !report.Summary.ConfigAccess.IsEmpty() {
+		t = t.Append("Permissions:", "font-bold").Append(fmt.Sprintf(" saved=%d deleted=%d", report.Summary.ConfigAccess.Saved, report.Summary.ConfigAccess.Deleted)).NewLine()
+	}
+	return t.Add(report.Summary.Pretty())
+}
+
+// dryRunSave saves the results in a transaction that is rolled back, and logs what the save would have written.
+// The returned snapshot is the state of the database before the rollback.
+func dryRunSave(ctx api.ScrapeContext, results v1.ScrapeResults, runStart time.Time) (v1.ScrapeSummary, *v1.ScrapeSnapshot, error) {
+	var summary v1.ScrapeSummary
+	var snapshot *v1.ScrapeSnapshot
+	var changes map[string]int
+
+	scraper := ctx.ScrapeConfig()
+	meta := scraper.ObjectMeta
+	defer func() { scraper.ObjectMeta = meta }()
+
+	err := db.DryRun(ctx, func(ctx api.ScrapeContext) error {
+		// The scraper only exists in the transaction when it has not been saved before
+		if err := ensureScraper(ctx.DutyContext(), scraper); err != nil {
+			return err
+		}
+
+		before, err := db.CountChangesByType(ctx, ctx.ScraperID())
+		if err != nil {
+			return fmt.Errorf("failed to count changes: %w", err)
+		}
+
+		if summary, err = db.SaveResults(ctx, results); err != nil {
+			return err
+		}
+
+		if snapshot, err = db.CaptureScrapeSnapshot(ctx, runStart); err != nil {
+			logger.Warnf("failed to capture post-scrape snapshot: %v", err)
+		}
+
+		after, err := db.CountChangesByType(ctx, ctx.ScraperID())
+		if err != nil {
+			return fmt.Errorf("failed to count changes: %w", err)
+		}
+		changes = lo.PickBy(lo.MapValues(after, func(count int, changeType string) int {
+			return count - before[changeType]
+		}), func(_ string, count int) bool { return count != 0 })
+		return nil
+	})
+	if err != nil {
+		return summary, nil, err
+	}
+
+	report := newDryRunReport(scraper.Name, results, &summary, changes)
+	logger.Infof("%s", report.Pretty().ANSI())
+	return summary, snapshot, nil
+}
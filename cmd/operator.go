@@ -28,6 +28,7 @@ import (
 
 var (
 	webhookPort          int
+	webhookCertDir       string
 	enableLeaderElection bool
 	operatorExecutor     bool
 	k8sLogLevel          int
@@ -42,6 +43,7 @@ func init() {
 	ServerFlags(Operator.Flags())
 	Operator.Flags().BoolVar(&operatorExecutor, "executor", true, "If false, only serve the UI and sync the configs")
 	Operator.Flags().IntVar(&webhookPort, "webhookPort", 8082, "Port for webhooks ")
+	Operator.Flags().StringVar(&webhookCertDir, "webhook-cert-dir", "", "Directory with the tls.crt and tls.key of the validating webhooks, the webhooks are disabled when empty")
 	Operator.Flags().IntVar(&k8sLogLevel, "k8s-log-level", -1, "Kubernetes controller log level")
 	Operator.Flags().BoolVar(&enableLeaderElection, "enable-leader-election", false, "Enabling this will ensure there is only one active controller manager")
 }
@@ -104,6 +106,12 @@ func launchKopper(ctx dutyContext.Context) error {
 		return err
 	}
 
+	if webhookCertDir != "" {
+		if err := mgr.Add(newWebhookServer(mgr.GetScheme(), webhookPort, webhookCertDir)); err != nil {
+			return fmt.Errorf("unable to setup validating webhooks: %w", err)
+		}
+	}
+
 	if _, err := kopper.SetupReconciler(ctx, mgr,
 		db.PersistScrapePluginFromCRD,
 		db.DeleteScrapePlugin,
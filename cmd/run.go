@@ -24,12 +24,14 @@ import (
 	"github.com/flanksource/commons/har"
 	"github.com/flanksource/commons/hash"
 	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/commons/properties"
 	"github.com/flanksource/commons/timer"
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
 	"github.com/flanksource/config-db/cmd/scrapeui"
 	"github.com/flanksource/config-db/db"
 	"github.com/flanksource/config-db/scrapers"
+	"github.com/flanksource/config-db/utils/replay"
 	"github.com/flanksource/duty"
 	dutyapi "github.com/flanksource/duty/api"
 	"github.com/flanksource/duty/context"
@@ -50,8 +52,11 @@ var outputDir string
 var debugPort int
 var export bool
 var save bool
+var dryRunSaveEnabled bool
 var uiEnabled bool
 var uiPort int
+var recordFile string
+var replayFile string
 
 // Run ...
 var Run = &cobra.Command{
@@ -60,6 +65,14 @@ var Run = &cobra.Command{
 	Run: func(cmd *cobra.Command, configFiles []string) {
 		var logBuf bytes.Buffer
 		harCollector := har.NewCollector(har.DefaultConfig())
+		if recordFile != "" {
+			if replayFile != "" {
+				logger.Fatalf("--record and --replay cannot be used together")
+			}
+			// Capture the full exchanges of every HTTP client, not only when HTTP tracing is enabled
+			properties.Set("log.level.http.har", "trace")
+			harCollector = har.NewCollector(replay.RecordingConfig())
+		}
 
 		if logger.IsTraceEnabled() {
 			logger.Tracef("Enabling HAR collection")
@@ -87,6 +100,24 @@ var Run = &cobra.Command{
 			}
 		}
 
+		var cassette *replay.Cassette
+		if replayFile != "" {
+			if cassette, err = replay.Load(replayFile); err != nil {
+				logger.Fatalf(err.Error())
+			}
+			restore := cassette.Install()
+			defer restore()
+		}
+
+		if dryRunSaveEnabled {
+			if save {
+				logger.Fatalf("--save and --dry-run-save cannot be used together")
+			}
+			if dutyapi.DefaultConfig.ConnectionString == "" {
+				logger.Fatalf("--dry-run-save requires a database connection")
+			}
+		}
+
 		dutyCtx := context.New()
 		if dutyapi.DefaultConfig.ConnectionString != "" {
 			c, _, err := duty.Start(app, duty.ClientOnly)
@@ -178,6 +209,13 @@ var Run = &cobra.Command{
 				}
 			}
 			scrapeCtx = scrapeCtx.WithHARCollector(harCollector)
+			if cassette != nil {
+				scrapeCtx = scrapeCtx.WithReplay(cassette)
+			}
+			if dryRunSaveEnabled {
+				// the trackers of the scrapers are isolated as well as the caches of the save
+				scrapeCtx = scrapeCtx.WithDryRun()
+			}
 
 			results, summary, snapshotPair, err := scrapeAndStore(scrapeCtx)
 			progress[i].DurationSec = time.Since(startedAt).Seconds()
@@ -234,6 +272,25 @@ var Run = &cobra.Command{
 			}
 		}
 
+		if recordFile != "" {
+			if err := har.WriteFile(harCollector, recordFile); err != nil {
+				hasErrors = true
+				logger.Errorf("failed to write HAR file %s: %v", recordFile, err)
+			} else {
+				logger.Infof("Recorded %d HTTP requests to %s", len(harCollector.Entries()), recordFile)
+			}
+		}
+		if cassette != nil {
+			if misses := cassette.Misses(); len(misses) > 0 {
+				hasErrors = true
+				logger.Errorf("No recorded response in %s for %d requests:\n%s", replayFile, len(misses), strings.Join(misses, "\n"))
+			}
+			if requests := replay.Unreplayed(harCollector.Entries()); len(requests) > 0 {
+				hasErrors = true
+				logger.Errorf("%d requests reached the network while replaying %s:\n%s", len(requests), replayFile, strings.Join(requests, "\n"))
+			}
+		}
+
 		// Restore stderr-only logging before rendering
 		logger.Use(os.Stderr)
 
@@ -291,7 +348,7 @@ func scrapeAndStore(ctx api.ScrapeContext) ([]v1.ScrapeResult, *v1.ScrapeSummary
 	// otherwise we skip both captures entirely. Snapshot capture is
 	// observability, so we log-and-continue on failure rather than aborting
 	// the scrape itself.
-	dbConnected := save && dutyapi.DefaultConfig.ConnectionString != ""
+	dbConnected := (save || dryRunSaveEnabled) && dutyapi.DefaultConfig.ConnectionString != ""
 	runStart := time.Now()
 	var beforeSnapshot *v1.ScrapeSnapshot
 	if dbConnected {
@@ -346,15 +403,24 @@ func scrapeAndStore(ctx api.ScrapeContext) ([]v1.ScrapeResult, *v1.ScrapeSummary
 	}
 
 	if dbConnected {
-		summary, saveErr := db.SaveResults(ctx, results)
-		if saveErr != nil {
-			return results, nil, beforeOnlyPair(), errors.Join(scrapeErr, fmt.Errorf("failed to save results to db: %w", saveErr))
-		}
-		logger.Infof("Exported %d resources to DB: %s (%s)", len(results), summary.PrettyShort(), timer.End())
+		var summary v1.ScrapeSummary
+		var afterSnapshot *v1.ScrapeSnapshot
+		var saveErr error
+		if dryRunSaveEnabled {
+			if summary, afterSnapshot, saveErr = dryRunSave(ctx, results, runStart); saveErr != nil {
+				return results, nil, beforeOnlyPair(), errors.Join(scrapeErr, fmt.Errorf("failed to dry run saving results to db: %w", saveErr))
+			}
+			logger.Infof("Dry run of saving %d resources to DB: %s (%s)", len(results), summary.PrettyShort(), timer.End())
+		} else {
+			if summary, saveErr = db.SaveResults(ctx, results); saveErr != nil {
+				return results, nil, beforeOnlyPair(), errors.Join(scrapeErr, fmt.Errorf("failed to save results to db: %w", saveErr))
+			}
+			logger.Infof("Exported %d resources to DB: %s (%s)", len(results), summary.PrettyShort(), timer.End())
 
-		afterSnapshot, captureErr := db.CaptureScrapeSnapshot(ctx, runStart)
-		if captureErr != nil {
-			logger.Warnf("failed to capture post-scrape snapshot: %v", captureErr)
+			var captureErr error
+			if afterSnapshot, captureErr = db.CaptureScrapeSnapshot(ctx, runStart); captureErr != nil {
+				logger.Warnf("failed to capture post-scrape snapshot: %v", captureErr)
+			}
 		}
 		snapshotPair := &v1.ScrapeSnapshotPair{
 			Before: beforeSnapshot,
@@ -783,10 +849,13 @@ func openBrowser(url string) {
 
 func init() {
 	Run.Flags().BoolVar(&save, "save", false, "Save scraped configurations to the database")
+	Run.Flags().BoolVar(&dryRunSaveEnabled, "dry-run-save", false, "Save scraped configurations in a database transaction that is rolled back, and report what would have been written")
 	Run.Flags().BoolVar(&export, "export", true, "Export scraped configurations to files in the output directory and/or pretty print them")
 	Run.Flags().StringVarP(&outputDir, "output-dir", "o", "", "The output folder for configurations")
 	Run.Flags().IntVar(&debugPort, "debug-port", -1, "Start an HTTP server to use the /debug routes, Use -1 to disable and 0 to pick a free port")
 	Run.Flags().BoolVar(&uiEnabled, "ui", false, "Open a browser dashboard showing real-time scrape progress")
 	Run.Flags().IntVar(&uiPort, "ui-port", 9001, "Port for the UI server (0 to pick a free port)")
+	Run.Flags().StringVar(&recordFile, "record", "", "Record every HTTP request and response made by the scrapers into a HAR file")
+	Run.Flags().StringVar(&replayFile, "replay", "", "Serve HTTP requests from a HAR file recorded with --record instead of the network")
 	clicky.BindAllFlags(Run.Flags())
 }
@@ -0,0 +1,327 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flanksource/clicky"
+	clickyapi "github.com/flanksource/clicky/api"
+	"github.com/flanksource/clicky/api/icons"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/cmd/scrapeui"
+	"github.com/flanksource/config-db/db"
+	"github.com/flanksource/config-db/scrapers"
+	"github.com/flanksource/config-db/utils/replay"
+	dutycontext "github.com/flanksource/duty/context"
+	"github.com/flanksource/duty/models"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+type TestOptions struct {
+	Configs   []string `args:"true" required:"true" help:"scraper.yaml files to test"`
+	Golden    string   `flag:"golden" help:"golden file of a single scraper file, defaults to <scraper>.golden.json next to it"`
+	HAR       string   `flag:"har" help:"HAR file whose recorded responses are served in place of the network"`
+	ExecStubs []string `flag:"exec-stub" help:"file whose contents replace the output of an exec scraper, in the order of the exec scrapers"`
+	Ignore    []string `flag:"ignore" help:"keys removed from the results before comparing, e.g. created_at"`
+	Update    bool     `flag:"update" help:"write the results to the golden files instead of comparing them"`
+}
+
+func (TestOptions) GetName() string {
+	return "test <scraper.yaml>..."
+}
+
+type testStatus string
+
+const (
+	testPass    testStatus = "pass"
+	testFail    testStatus = "fail"
+	testUpdated testStatus = "updated"
+)
+
+type TestResult struct {
+	Scraper string     `json:"scraper"`
+	Golden  string     `json:"golden"`
+	Status  testStatus `json:"status"`
+	Message string     `json:"message,omitempty"`
+	Diff    string     `json:"diff,omitempty"`
+}
+
+type TestResults []TestResult
+
+func (results TestResults) FailureCount() int {
+	return len(lo.Filter(results, func(r TestResult, _ int) bool { return r.Status == testFail }))
+}
+
+func (result TestResult) Columns() []clickyapi.ColumnDef {
+	return []clickyapi.ColumnDef{
+		clicky.Column("Status").Build(),
+		clicky.Column("Scraper").Build(),
+		clicky.Column("Golden").Build(),
+		clicky.Column("Message").Build(),
+	}
+}
+
+func (result TestResult) Row() map[string]any {
+	return map[string]any{
+		"Status":  result.statusText(),
+		"Scraper": result.Scraper,
+		"Golden":  result.Golden,
+		"Message": result.Message,
+	}
+}
+
+func (result TestResult) RowDetail() clickyapi.Textable {
+	return clicky.Text(result.Diff, "font-mono")
+}
+
+func (result TestResult) statusText() clickyapi.Textable {
+	switch result.Status {
+	case testPass:
+		return clicky.Text(fmt.Sprintf("%s %s", icons.Success, result.Status), "text-green-600")
+	case testFail:
+		return clicky.Text(fmt.Sprintf("%s %s", icons.Fail, result.Status), "text-red-600")
+	default:
+		return clicky.Text(string(result.Status), "text-yellow-600")
+	}
+}
+
+type testFailure struct {
+	results TestResults
+	err     error
+}
+
+func (failure testFailure) Error() string {
+	return failure.err.Error()
+}
+
+func (failure testFailure) Unwrap() error {
+	return failure.err
+}
+
+func (failure testFailure) Pretty() clickyapi.Text {
+	text := clicky.Text("").Add(clickyapi.NewTableFrom(failure.results))
+	for _, result := range failure.results {
+		if result.Diff != "" {
+			text = text.NewLine().Append(result.Scraper, "font-bold").NewLine().Append(result.Diff, "font-mono")
+		}
+	}
+	return text
+}
+
+func (failure testFailure) MarshalJSON() ([]byte, error) {
+	return json.Marshal(failure.results)
+}
+
+// goldenResults are the results of a scraper that are compared to its golden file.
+type goldenResults struct {
+	Configs       []v1.ScrapeResult         `json:"configs,omitempty"`
+	Changes       []v1.ChangeResult         `json:"changes,omitempty"`
+	Relationships []scrapeui.UIRelationship `json:"relationships,omitempty"`
+	Analysis      []models.ConfigAnalysis   `json:"analysis,omitempty"`
+	ConfigAccess  []v1.ExternalConfigAccess `json:"config_access,omitempty"`
+}
+
+var Test *cobra.Command
+
+func runTest(options TestOptions) (TestResults, error) {
+	clicky.Flags.UseFlags()
+
+	if options.Golden != "" && len(options.Configs) != 1 {
+		return nil, fmt.Errorf("--golden requires exactly one scraper file")
+	}
+
+	var stubs []string
+	for _, path := range options.ExecStubs {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read exec stub %q: %w", path, err)
+		}
+		stubs = append(stubs, string(data))
+	}
+
+	var cassette *replay.Cassette
+	if options.HAR != "" {
+		c, err := replay.Load(options.HAR)
+		if err != nil {
+			return nil, err
+		}
+		cassette = c
+		defer cassette.Install()()
+	}
+
+	// Tests are hermetic: the scrapers run without a database
+	ctx := dutycontext.New()
+
+	var results TestResults
+	for _, path := range options.Configs {
+		golden := lo.CoalesceOrEmpty(options.Golden, goldenPath(path))
+		result := testScraper(ctx, path, golden, stubs, cassette, options)
+		if cassette != nil && result.Status == testFail {
+			if misses := cassette.Misses(); len(misses) > 0 {
+				result.Message += fmt.Sprintf(" (no recorded response for %s)", strings.Join(misses, ", "))
+			}
+		}
+		results = append(results, result)
+	}
+
+	if failures := results.FailureCount(); failures > 0 {
+		return nil, testFailure{results: results, err: fmt.Errorf("%d scraper tests failed", failures)}
+	}
+	return results, nil
+}
+
+func testScraper(ctx dutycontext.Context, path, golden string, stubs []string, cassette *replay.Cassette, options TestOptions) TestResult {
+	result := TestResult{Scraper: path, Golden: golden}
+	fail := func(format string, args ...any) TestResult {
+		result.Status = testFail
+		result.Message = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	configs, err := v1.ParseConfigs(path)
+	if err != nil {
+		return fail("failed to parse scraper: %v", err)
+	}
+
+	var scraped v1.ScrapeResults
+	for i := range configs {
+		scrapeCtx := api.NewScrapeContext(ctx).WithScrapeConfig(&configs[i]).WithExecStubs(stubs...)
+		if cassette != nil {
+			scrapeCtx = scrapeCtx.WithReplay(cassette)
+		}
+		results, err := scrapers.Run(scrapeCtx)
+		if err != nil {
+			return fail("failed to run scraper %s: %v", configs[i].Name, err)
+		}
+		// Change mappings are otherwise only applied when the results are saved
+		if err := db.ApplyChangeMappings(scrapeCtx, results); err != nil {
+			return fail("failed to map the changes of scraper %s: %v", configs[i].Name, err)
+		}
+		scraped = append(scraped, results...)
+	}
+	if scraped.HasErr() {
+		return fail("scrape errors: %s", strings.Join(scraped.Errors(), "; "))
+	}
+
+	actual, err := normalizeGolden(goldenFromResults(scraped), options.Ignore)
+	if err != nil {
+		return fail("failed to normalize results: %v", err)
+	}
+
+	if options.Update {
+		if err := os.WriteFile(golden, []byte(actual+"\n"), 0644); err != nil {
+			return fail("failed to write golden file: %v", err)
+		}
+		result.Status = testUpdated
+		return result
+	}
+
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		return fail("failed to read golden file, run with --update to create it: %v", err)
+	}
+	var expected any
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return fail("failed to parse golden file: %v", err)
+	}
+	want, err := normalizeGolden(expected, options.Ignore)
+	if err != nil {
+		return fail("failed to normalize golden file: %v", err)
+	}
+
+	if diff := db.TextDiff(want, actual); diff != "" {
+		result.Diff = diff
+		return fail("results differ from the golden file")
+	}
+	result.Status = testPass
+	return result
+}
+
+func goldenPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".golden.json"
+}
+
+func goldenFromResults(results v1.ScrapeResults) goldenResults {
+	all := v1.MergeScrapeResults(results)
+	return goldenResults{
+		Configs:       all.Configs,
+		Changes:       all.Changes,
+		Relationships: scrapeui.BuildUIRelationships(results),
+		Analysis:      all.Analysis,
+		ConfigAccess:  all.ConfigAccess,
+	}
+}
+
+// normalizeGolden returns the results as indented JSON with sorted keys, without the ignored keys,
+// and with the entries of each list sorted, so that the order the scraper returns them in does not matter.
+func normalizeGolden(results any, ignore []string) (string, error) {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return "", err
+	}
+
+	for key, value := range normalized {
+		value = removeKeys(value, ignore)
+		if list, ok := value.([]any); ok {
+			if err := sortByJSON(list); err != nil {
+				return "", err
+			}
+		}
+		normalized[key] = value
+	}
+
+	out, err := json.MarshalIndent(normalized, "", "  ")
+	return string(out), err
+}
+
+func removeKeys(value any, keys []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if lo.Contains(keys, key) {
+				delete(v, key)
+				continue
+			}
+			v[key] = removeKeys(child, keys)
+		}
+	case []any:
+		for i := range v {
+			v[i] = removeKeys(v[i], keys)
+		}
+	}
+	return value
+}
+
+func sortByJSON(list []any) error {
+	keys := make(map[int]string, len(list))
+	for i, item := range list {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		keys[i] = string(data)
+	}
+
+	indexes := lo.Range(len(list))
+	sort.SliceStable(indexes, func(i, j int) bool { return keys[indexes[i]] < keys[indexes[j]] })
+
+	sorted := lo.Map(indexes, func(i int, _ int) any { return list[i] })
+	copy(list, sorted)
+	return nil
+}
+
+func init() {
+	Test = clicky.AddCommand(Root, TestOptions{}, runTest)
+	Test.Short = "Run scrapers against recorded inputs and compare the results to golden files"
+	clicky.BindAllFlags(Test.Flags(), "format")
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("test", func() {
+	const scraper = `apiVersion: configs.flanksource.com/v1
+kind: ScrapeConfig
+metadata:
+  name: exec-stubbed
+spec:
+  exec:
+    - type: AWS::EC2::Instance
+      id: $.id
+      name: $.name
+      script: exit 1
+`
+
+	var dir, config, stub string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		config = filepath.Join(dir, "exec.yaml")
+		stub = filepath.Join(dir, "stdout.json")
+		Expect(os.WriteFile(config, []byte(scraper), 0644)).To(Succeed())
+		Expect(os.WriteFile(stub, []byte(`[{"id": "i-1", "name": "web-1", "created_at": "2024-01-01"}, {"id": "i-2", "name": "db-1"}]`), 0644)).To(Succeed())
+	})
+
+	It("writes golden files and compares the results with them", func() {
+		results, err := runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}, Update: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Status": Equal(testUpdated),
+			"Golden": Equal(filepath.Join(dir, "exec.golden.json")),
+		})))
+		Expect(filepath.Join(dir, "exec.golden.json")).To(BeAnExistingFile())
+
+		results, err = runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(ConsistOf(MatchFields(IgnoreExtras, Fields{"Status": Equal(testPass)})))
+
+		// the order of the results does not matter
+		Expect(os.WriteFile(stub, []byte(`[{"id": "i-2", "name": "db-1"}, {"id": "i-1", "name": "web-1", "created_at": "2024-01-01"}]`), 0644)).To(Succeed())
+		_, err = runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("fails with a diff when the results differ from the golden file", func() {
+		_, err := runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}, Update: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(stub, []byte(`[{"id": "i-1", "name": "web-2", "created_at": "2024-01-01"}, {"id": "i-2", "name": "db-1"}]`), 0644)).To(Succeed())
+		_, err = runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}})
+		Expect(err).To(HaveOccurred())
+
+		var failure testFailure
+		Expect(err).To(BeAssignableToTypeOf(failure))
+		failure = err.(testFailure)
+		Expect(failure.results).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Status": Equal(testFail),
+			"Diff":   ContainSubstring("web-2"),
+		})))
+	})
+
+	It("ignores keys when comparing", func() {
+		_, err := runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}, Update: true, Ignore: []string{"created_at"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(stub, []byte(`[{"id": "i-1", "name": "web-1", "created_at": "2025-01-01"}, {"id": "i-2", "name": "db-1"}]`), 0644)).To(Succeed())
+		_, err = runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}, Ignore: []string{"created_at"}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("fails when the golden file is missing", func() {
+		_, err := runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}})
+
+		Expect(err).To(MatchError("1 scraper tests failed"))
+	})
+
+	It("requires a single scraper file with --golden", func() {
+		_, err := runTest(TestOptions{Configs: []string{config, config}, Golden: "golden.json"})
+
+		Expect(err).To(MatchError("--golden requires exactly one scraper file"))
+	})
+
+	It("applies the change mappings before comparing", func() {
+		Expect(os.WriteFile(config, []byte(`apiVersion: configs.flanksource.com/v1
+kind: ScrapeConfig
+metadata:
+  name: exec-changes
+spec:
+  full: true
+  exec:
+    - type: TestApp::Environment
+      id: $.id
+      name: $.name
+      script: exit 1
+      transform:
+        changes:
+          mapping:
+            - filter: change_type == 'CycleRun'
+              type: HealthCheckPassed
+            - filter: change_type == 'Heartbeat'
+              action: ignore
+`), 0644)).To(Succeed())
+		Expect(os.WriteFile(stub, []byte(`[{"id": "env-1", "name": "env-1", "config": {"region": "eu"}, "changes": [
+			{"external_change_id": "cycle-1", "change_type": "CycleRun", "summary": "healthy"},
+			{"external_change_id": "ping-1", "change_type": "Heartbeat", "summary": "ping"}
+		]}]`), 0644)).To(Succeed())
+
+		_, err := runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}, Update: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		golden, err := os.ReadFile(filepath.Join(dir, "exec.golden.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(golden)).To(ContainSubstring(`"change_type": "HealthCheckPassed"`))
+		Expect(string(golden)).NotTo(ContainSubstring("ping-1"))
+
+		_, err = runTest(TestOptions{Configs: []string{config}, ExecStubs: []string{stub}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/flanksource/clicky"
+	clickyapi "github.com/flanksource/clicky/api"
+	"github.com/flanksource/clicky/api/icons"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/scrapers"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type ValidateOptions struct {
+	Files []string `args:"true" required:"true" help:"ScrapeConfig and ScrapePlugin files to validate"`
+}
+
+func (ValidateOptions) GetName() string {
+	return "validate <scraper.yaml>..."
+}
+
+type ValidateResult struct {
+	File   string   `json:"file"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+type ValidateResults []ValidateResult
+
+func (results ValidateResults) InvalidCount() int {
+	return len(lo.Filter(results, func(r ValidateResult, _ int) bool { return len(r.Errors) > 0 }))
+}
+
+func (result ValidateResult) Columns() []clickyapi.ColumnDef {
+	return []clickyapi.ColumnDef{
+		clicky.Column("Status").Build(),
+		clicky.Column("File").Build(),
+		clicky.Column("Kind").Build(),
+		clicky.Column("Name").Build(),
+		clicky.Column("Errors").Build(),
+	}
+}
+
+func (result ValidateResult) Row() map[string]any {
+	status := clicky.Text(fmt.Sprintf("%s valid", icons.Success), "text-green-600")
+	if len(result.Errors) > 0 {
+		status = clicky.Text(fmt.Sprintf("%s invalid", icons.Fail), "text-red-600")
+	}
+	return map[string]any{
+		"Status": status,
+		"File":   result.File,
+		"Kind":   result.Kind,
+		"Name":   result.Name,
+		"Errors": len(result.Errors),
+	}
+}
+
+func (result ValidateResult) RowDetail() clickyapi.Textable {
+	return clicky.Text(strings.Join(result.Errors, "\n"), "font-mono")
+}
+
+type validateFailure struct {
+	results ValidateResults
+	err     error
+}
+
+func (failure validateFailure) Error() string {
+	return failure.err.Error()
+}
+
+func (failure validateFailure) Unwrap() error {
+	return failure.err
+}
+
+func (failure validateFailure) Pretty() clickyapi.Text {
+	text := clicky.Text("").Add(clickyapi.NewTableFrom(failure.results))
+	for _, result := range failure.results {
+		if len(result.Errors) > 0 {
+			text = text.NewLine().Append(fmt.Sprintf("%s %s/%s", result.File, result.Kind, result.Name), "font-bold").
+				NewLine().Append(strings.Join(result.Errors, "\n"), "font-mono")
+		}
+	}
+	return text
+}
+
+func (failure validateFailure) MarshalJSON() ([]byte, error) {
+	return json.Marshal(failure.results)
+}
+
+var Validate *cobra.Command
+
+// runValidate applies the checks of the validating webhooks to ScrapeConfig and ScrapePlugin files.
+func runValidate(options ValidateOptions) (ValidateResults, error) {
+	clicky.Flags.UseFlags()
+
+	var results ValidateResults
+	for _, path := range options.Files {
+		configs, plugins, err := v1.ParseConfigsAndPlugins(path)
+		if err != nil {
+			results = append(results, ValidateResult{File: path, Errors: []string{err.Error()}})
+			continue
+		}
+
+		for _, config := range configs {
+			results = append(results, newValidateResult(path, "ScrapeConfig", config.Name, scrapers.ValidateScrapeConfig(config)))
+		}
+		for _, plugin := range plugins {
+			results = append(results, newValidateResult(path, "ScrapePlugin", plugin.Name, scrapers.ValidateScrapePlugin(plugin)))
+		}
+	}
+
+	if invalid := results.InvalidCount(); invalid > 0 {
+		return nil, validateFailure{results: results, err: fmt.Errorf("%d invalid resources", invalid)}
+	}
+	return results, nil
+}
+
+func newValidateResult(path, kind, name string, errs field.ErrorList) ValidateResult {
+	return ValidateResult{
+		File:   path,
+		Kind:   kind,
+		Name:   name,
+		Errors: lo.Map(errs, func(err *field.Error, _ int) string { return err.Error() }),
+	}
+}
+
+func init() {
+	Validate = clicky.AddCommand(Root, ValidateOptions{}, runValidate)
+	Validate.Short = "Validate ScrapeConfig and ScrapePlugin files without running them"
+	clicky.BindAllFlags(Validate.Flags(), "format")
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("validate", func() {
+	write := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "scraper.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	It("validates scrape configs and plugins", func() {
+		path := write(`apiVersion: configs.flanksource.com/v1
+kind: ScrapeConfig
+metadata:
+  name: valid
+spec:
+  schedule: "@every 1h"
+  file:
+    - type: $.kind
+      id: $.id
+      paths: [fixtures/data/*.json]
+---
+apiVersion: configs.flanksource.com/v1
+kind: ScrapePlugin
+metadata:
+  name: plugin
+spec:
+  changes:
+    exclude:
+      - change_type == "diff"
+`)
+
+		results, err := runValidate(ValidateOptions{Files: []string{path}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(ConsistOf(
+			MatchFields(IgnoreExtras, Fields{"Kind": Equal("ScrapeConfig"), "Name": Equal("valid"), "Errors": BeEmpty()}),
+			MatchFields(IgnoreExtras, Fields{"Kind": Equal("ScrapePlugin"), "Name": Equal("plugin"), "Errors": BeEmpty()}),
+		))
+	})
+
+	It("reports the field path of invalid values", func() {
+		path := write(`apiVersion: configs.flanksource.com/v1
+kind: ScrapeConfig
+metadata:
+  name: invalid
+spec:
+  timeout: soon
+  file:
+    - type: File
+      id: $.id
+      items: $.items[
+      paths: [fixtures/data/*.json]
+`)
+
+		_, err := runValidate(ValidateOptions{Files: []string{path}})
+		Expect(err).To(MatchError("1 invalid resources"))
+
+		var failure validateFailure
+		Expect(err).To(BeAssignableToTypeOf(failure))
+		failure = err.(validateFailure)
+		Expect(failure.results).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Errors": ConsistOf(
+				ContainSubstring("spec.timeout"),
+				ContainSubstring("spec.file[0].items"),
+			),
+		})))
+	})
+})
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/scrapers"
+)
+
+const (
+	validateScrapeConfigPath = "/validate-scrape-config"
+	validateScrapePluginPath = "/validate-scrape-plugin"
+)
+
+// newWebhookServer serves the validating admission webhooks of ScrapeConfigs and ScrapePlugins.
+func newWebhookServer(scheme *runtime.Scheme, port int, certDir string) webhook.Server {
+	server := webhook.NewServer(webhook.Options{Port: port, CertDir: certDir})
+	server.Register(validateScrapeConfigPath, admission.WithValidator[*v1.ScrapeConfig](scheme, scrapeConfigValidator{}))
+	server.Register(validateScrapePluginPath, admission.WithValidator[*v1.ScrapePlugin](scheme, scrapePluginValidator{}))
+	return server
+}
+
+type scrapeConfigValidator struct{}
+
+func (scrapeConfigValidator) ValidateCreate(_ context.Context, sc *v1.ScrapeConfig) (admission.Warnings, error) {
+	return nil, invalid("ScrapeConfig", sc.Name, scrapers.ValidateScrapeConfig(*sc))
+}
+
+func (scrapeConfigValidator) ValidateUpdate(_ context.Context, _, sc *v1.ScrapeConfig) (admission.Warnings, error) {
+	return nil, invalid("ScrapeConfig", sc.Name, scrapers.ValidateScrapeConfig(*sc))
+}
+
+func (scrapeConfigValidator) ValidateDelete(context.Context, *v1.ScrapeConfig) (admission.Warnings, error) {
+	return nil, nil
+}
+
+type scrapePluginValidator struct{}
+
+func (scrapePluginValidator) ValidateCreate(_ context.Context, plugin *v1.ScrapePlugin) (admission.Warnings, error) {
+	return nil, invalid("ScrapePlugin", plugin.Name, scrapers.ValidateScrapePlugin(*plugin))
+}
+
+func (scrapePluginValidator) ValidateUpdate(_ context.Context, _, plugin *v1.ScrapePlugin) (admission.Warnings, error) {
+	return nil, invalid("ScrapePlugin", plugin.Name, scrapers.ValidateScrapePlugin(*plugin))
+}
+
+func (scrapePluginValidator) ValidateDelete(context.Context, *v1.ScrapePlugin) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func invalid(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(v1.GroupVersion.WithKind(kind).GroupKind(), name, errs)
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/collections"
+	dutyModels "github.com/flanksource/duty/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm/clause"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db/models"
+)
+
+// attributeChanges sets the author of the diff changes from the nearest audit event
+// on the same config item within the attribution window.
+// Scrapers without audit events, e.g. Kubernetes or file scrapers, skip the lookups unless sources are set.
+//
+// New diff changes are attributed, in place, to the audit events of this batch or to the saved ones.
+// Audit events usually arrive after the diff they caused, so the saved diff changes without an author
+// are attributed to the new audit events, and returned to be updated.
+func attributeChanges(ctx api.ScrapeContext, changes []*models.ConfigChange) ([]*models.ConfigChange, error) {
+	spec := ctx.ScrapeConfig().Spec.Attribution
+	if !spec.IsEnabled(ctx.ScrapeConfig().Spec) || ctx.Properties().On(false, "changes.attribution.disable") || len(changes) == 0 {
+		return nil, nil
+	}
+
+	window, err := spec.GetWindow()
+	if err != nil {
+		return nil, err
+	}
+	sources := spec.GetSources()
+
+	var diffs, events []*models.ConfigChange
+	for _, change := range changes {
+		if change.ConfigID == "" {
+			continue
+		}
+		if isUnattributedDiff(change) {
+			diffs = append(diffs, change)
+		} else if isAuditEvent(change, sources) {
+			events = append(events, change)
+		}
+	}
+
+	if len(diffs) > 0 {
+		saved, err := findAuditEvents(ctx, diffs, sources, window)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates := slices.Concat(events, saved)
+		for _, diff := range diffs {
+			if event := nearestChange(diff, candidates, window); event != nil {
+				attribute(ctx, diff, event)
+			}
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	saved, err := findUnattributedDiffs(ctx, events, window)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []*models.ConfigChange
+	for _, diff := range saved {
+		if event := nearestChange(diff, events, window); event != nil {
+			attribute(ctx, diff, event)
+			updates = append(updates, diff)
+		}
+	}
+	return updates, nil
+}
+
+// attribute sets the author of the diff from the audit event, and links the event and its external user.
+func attribute(ctx api.ScrapeContext, diff, event *models.ConfigChange) {
+	diff.CreatedBy = event.CreatedBy
+	diff.ExternalCreatedBy = event.ExternalCreatedBy
+
+	attribution := map[string]any{
+		"change_id":   event.ID,
+		"change_type": event.ChangeType,
+		"source":      event.Source,
+		"created_at":  event.CreatedAt,
+	}
+	if event.ExternalChangeID != nil {
+		attribution["event_id"] = *event.ExternalChangeID
+	}
+
+	if event.ExternalCreatedBy != nil {
+		if id, err := findExternalEntityIDByAliases[dutyModels.ExternalUser](ctx, []string{*event.ExternalCreatedBy}); err != nil {
+			ctx.Logger.V(3).Infof("failed to find external user %s: %v", *event.ExternalCreatedBy, err)
+		} else if id != nil {
+			attribution["external_user_id"] = id.String()
+		}
+	}
+
+	if diff.Details == nil {
+		diff.Details = v1.JSON{}
+	}
+	diff.Details["attribution"] = attribution
+}
+
+func isUnattributedDiff(change *models.ConfigChange) bool {
+	return change.ChangeType == v1.ChangeTypeDiff && change.CreatedBy == nil && change.ExternalCreatedBy == nil
+}
+
+func isAuditEvent(change *models.ConfigChange, sources []string) bool {
+	if change.CreatedBy == nil && change.ExternalCreatedBy == nil {
+		return false
+	}
+	return collections.MatchItems(change.Source, sources...)
+}
+
+// nearestChange returns the candidate on the config item of the change that is closest in time to it.
+func nearestChange(change *models.ConfigChange, candidates []*models.ConfigChange, window time.Duration) *models.ConfigChange {
+	var (
+		nearest  *models.ConfigChange
+		distance time.Duration
+	)
+
+	createdAt := changeTime(change)
+	for _, candidate := range candidates {
+		if candidate.ConfigID != change.ConfigID || candidate.ID == change.ID {
+			continue
+		}
+
+		d := changeTime(candidate).Sub(createdAt)
+		if d < 0 {
+			d = -d
+		}
+		if d <= window && (nearest == nil || d < distance) {
+			nearest, distance = candidate, d
+		}
+	}
+
+	return nearest
+}
+
+// findAuditEvents returns the saved audit events on the config items of the changes within the window.
+func findAuditEvents(ctx api.ScrapeContext, changes []*models.ConfigChange, sources []string, window time.Duration) ([]*models.ConfigChange, error) {
+	from, to := changeTimeRange(changes)
+
+	var events []*models.ConfigChange
+	if err := ctx.DB().
+		Where("config_id IN ?", lo.Uniq(lo.Map(changes, func(c *models.ConfigChange, _ int) string { return c.ConfigID }))).
+		Where("created_at BETWEEN ? AND ?", from.Add(-window), to.Add(window)).
+		Where("(created_by IS NOT NULL OR external_created_by IS NOT NULL)").
+		Where(sourcesClause(sources)).
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to find audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// findUnattributedDiffs returns the saved diff changes without an author on the config items of the events within the window.
+func findUnattributedDiffs(ctx api.ScrapeContext, events []*models.ConfigChange, window time.Duration) ([]*models.ConfigChange, error) {
+	from, to := changeTimeRange(events)
+
+	var diffs []*models.ConfigChange
+	if err := ctx.DB().
+		Where("config_id IN ?", lo.Uniq(lo.Map(events, func(c *models.ConfigChange, _ int) string { return c.ConfigID }))).
+		Where("change_type = ?", v1.ChangeTypeDiff).
+		Where("created_at BETWEEN ? AND ?", from.Add(-window), to.Add(window)).
+		Where("created_by IS NULL AND external_created_by IS NULL").
+		Find(&diffs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find unattributed diff changes: %w", err)
+	}
+
+	return diffs, nil
+}
+
+// sourcesClause matches the source of a change to any of the sources, with * as a wildcard.
+func sourcesClause(sources []string) clause.Expr {
+	escape := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%")
+
+	conditions := make([]string, 0, len(sources))
+	args := make([]any, 0, len(sources))
+	for _, source := range sources {
+		conditions = append(conditions, "source LIKE ?")
+		args = append(args, escape.Replace(source))
+	}
+	return clause.Expr{SQL: "(" + strings.Join(conditions, " OR ") + ")", Vars: args}
+}
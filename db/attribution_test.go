@@ -0,0 +1,111 @@
+package db
+
+import (
+	"time"
+
+	dutymodels "github.com/flanksource/duty/models"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db/models"
+)
+
+var _ = Describe("attributeChanges", Ordered, func() {
+	var (
+		ctx      api.ScrapeContext
+		configID = uuid.New()
+		start    = time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	)
+
+	event := func(minutes int, user string) *models.ConfigChange {
+		return &models.ConfigChange{
+			ID:                uuid.NewString(),
+			ConfigID:          configID.String(),
+			ChangeType:        "ModifyDBInstance",
+			Source:            "AWS::CloudTrail::eu-west-1:123456789012",
+			ExternalChangeID:  lo.ToPtr(uuid.NewString()),
+			ExternalCreatedBy: lo.ToPtr(user),
+			CreatedAt:         start.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+
+	diff := func(minutes int) *models.ConfigChange {
+		return &models.ConfigChange{
+			ID:         uuid.NewString(),
+			ConfigID:   configID.String(),
+			ChangeType: v1.ChangeTypeDiff,
+			Source:     "attribution-test",
+			CreatedAt:  start.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+
+	BeforeAll(func() {
+		ctx = api.NewScrapeContext(DefaultContext).WithScrapeConfig(&v1.ScrapeConfig{Spec: v1.ScraperSpec{AWS: []v1.AWS{{}}}})
+
+		Expect(ctx.DB().Create(&dutymodels.ConfigItem{
+			ID:          configID,
+			ConfigClass: "Database",
+			Type:        lo.ToPtr("AWS::RDS::DBInstance"),
+		}).Error).ToNot(HaveOccurred())
+	})
+
+	AfterAll(func() {
+		Expect(ctx.DB().Where("config_id = ?", configID).Delete(&models.ConfigChange{}).Error).ToNot(HaveOccurred())
+		Expect(ctx.DB().Delete(&dutymodels.ConfigItem{}, configID).Error).ToNot(HaveOccurred())
+	})
+
+	It("should attribute a new diff to the nearest audit event", func() {
+		saved := event(0, "alice")
+		Expect(ctx.DB().Create(saved).Error).ToNot(HaveOccurred())
+
+		nearest := event(3, "bob")
+		change := diff(2)
+
+		updates, err := attributeChanges(ctx, []*models.ConfigChange{change, nearest, event(20, "carol")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updates).To(BeEmpty())
+
+		Expect(lo.FromPtr(change.ExternalCreatedBy)).To(Equal("bob"))
+		Expect(change.Details["attribution"]).To(HaveKeyWithValue("event_id", *nearest.ExternalChangeID))
+		Expect(change.Details["attribution"]).To(HaveKeyWithValue("change_id", nearest.ID))
+	})
+
+	It("should attribute saved diffs to a new audit event", func() {
+		unattributed := diff(30)
+		outside := diff(45)
+		Expect(ctx.DB().Create(unattributed).Error).ToNot(HaveOccurred())
+		Expect(ctx.DB().Create(outside).Error).ToNot(HaveOccurred())
+
+		late := event(33, "dave")
+		updates, err := attributeChanges(ctx, []*models.ConfigChange{late})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updates).To(HaveLen(1))
+		Expect(updates[0].ID).To(Equal(unattributed.ID))
+		Expect(lo.FromPtr(updates[0].ExternalCreatedBy)).To(Equal("dave"))
+		Expect(updates[0].Details["attribution"]).To(HaveKeyWithValue("event_id", *late.ExternalChangeID))
+	})
+
+	It("should not attribute changes from other sources", func() {
+		other := event(60, "eve")
+		other.Source = "Kubernetes"
+		change := diff(61)
+
+		_, err := attributeChanges(ctx, []*models.ConfigChange{change, other})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(change.ExternalCreatedBy).To(BeNil())
+		Expect(change.Details).ToNot(HaveKey("attribution"))
+	})
+
+	It("should not attribute changes of scrapers without audit events", func() {
+		kubernetes := api.NewScrapeContext(DefaultContext).WithScrapeConfig(&v1.ScrapeConfig{Spec: v1.ScraperSpec{Kubernetes: []v1.Kubernetes{{}}}})
+		change := diff(70)
+
+		_, err := attributeChanges(kubernetes, []*models.ConfigChange{change, event(71, "frank")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(change.ExternalCreatedBy).To(BeNil())
+	})
+})
@@ -1,12 +1,16 @@
 package db
 
 import (
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
 	"github.com/flanksource/config-db/db/models"
 	"github.com/flanksource/duty/context"
 	dutyModels "github.com/flanksource/duty/models"
+	"github.com/patrickmn/go-cache"
 )
 
 func InitChangeFingerprintCache(ctx context.Context, window time.Duration) error {
@@ -67,6 +71,83 @@ func dedupChanges(window time.Duration, changes []*models.ConfigChange) ([]*mode
 	return nonDuped, deduped
 }
 
+type aggregatedChange struct {
+	ID               string
+	ExternalChangeID *string
+	Group            v1.ChangeAggregate
+}
+
+// aggregatedChanges keeps the rolled up changes of aggregate change mappings while their window is open,
+// so that the changes of the same group from later scrapes are rolled up into them.
+var (
+	aggregatedChanges     = cache.New(time.Hour, 10*time.Minute)
+	aggregatedChangesLock sync.Mutex
+)
+
+// aggregatedChangesOf returns the cache of the rolled up changes, or its copy in a dry run.
+func aggregatedChangesOf(ctx api.ScrapeContext) *cache.Cache {
+	return api.DryRunState(ctx, "aggregatedChanges", aggregatedChanges, copyCache)
+}
+
+// copyCache returns a cache with the unexpired items of c.
+func copyCache(c *cache.Cache) *cache.Cache {
+	return cache.NewFrom(time.Hour, 10*time.Minute, c.Items())
+}
+
+// mergeAggregatedChange rolls up the change into the change of the same group saved by a previous scrape.
+// Returns true if the change now refers to the saved change, which should be updated instead of inserted.
+func mergeAggregatedChange(ctx api.ScrapeContext, change *models.ConfigChange, group v1.ChangeAggregate) bool {
+	aggregatedChangesLock.Lock()
+	defer aggregatedChangesLock.Unlock()
+
+	cached, ok := aggregatedChangesOf(ctx).Get(aggregatedChangeKey(change.ConfigID, group))
+	if !ok {
+		return false
+	}
+
+	existing := cached.(aggregatedChange)
+	if !existing.Group.InWindow(group.First) {
+		return false
+	}
+
+	merged := existing.Group
+	merged.ExternalChangeIDs = slices.Clone(merged.ExternalChangeIDs)
+	merged.SampleDetails = slices.Clone(merged.SampleDetails)
+	merged.Merge(group)
+
+	change.ID = existing.ID
+	change.ExternalChangeID = existing.ExternalChangeID
+	change.SetAggregate(merged)
+	return true
+}
+
+// cacheAggregatedChanges keeps the saved rolled up changes for the scrapes within their window.
+// It must only be called once the changes are saved.
+func cacheAggregatedChanges(ctx api.ScrapeContext, changes []*models.ConfigChange) {
+	aggregatedChangesLock.Lock()
+	defer aggregatedChangesLock.Unlock()
+
+	aggregated := aggregatedChangesOf(ctx)
+	for _, change := range changes {
+		if change.Aggregate == nil || change.ConfigID == "" {
+			continue
+		}
+
+		group := *change.Aggregate
+		if ttl := time.Until(group.First.Add(group.Window)); ttl > 0 {
+			aggregated.Set(aggregatedChangeKey(change.ConfigID, group), aggregatedChange{
+				ID:               change.ID,
+				ExternalChangeID: change.ExternalChangeID,
+				Group:            group,
+			}, ttl)
+		}
+	}
+}
+
+func aggregatedChangeKey(configID string, group v1.ChangeAggregate) string {
+	return configID + "/" + group.Key
+}
+
 func GetWorkflowRunCount(ctx api.ScrapeContext, workflowID string) (int64, error) {
 	var count int64
 	err := ctx.DB().Table("config_changes").
@@ -0,0 +1,350 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flanksource/duty/query"
+	"github.com/flanksource/duty/types"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db/models"
+)
+
+// correlator links changes to the changes that caused them, on related config items,
+// as configured by the correlation rules of the scraper.
+type correlator struct {
+	ctx api.ScrapeContext
+
+	// changes of this scrape, keyed by id, kept in sync with the links saved in the db
+	changes map[string]*models.ConfigChange
+
+	// related config items (id -> type) keyed by config id, direction and depth
+	related map[string]map[string]string
+}
+
+// correlateChanges runs the correlation rules on the newly saved changes.
+// A new change that matches the effect of a rule is linked to the latest cause before it,
+// and a new change that matches the cause is linked to the unlinked effects that followed it.
+func correlateChanges(ctx api.ScrapeContext, changes []*models.ConfigChange) error {
+	rules := ctx.ScrapeConfig().Spec.Correlation
+	if len(rules) == 0 || len(changes) == 0 {
+		return nil
+	}
+
+	c := correlator{
+		ctx:     ctx,
+		changes: make(map[string]*models.ConfigChange, len(changes)),
+		related: map[string]map[string]string{},
+	}
+	for _, change := range changes {
+		c.changes[change.ID] = change
+	}
+
+	for _, rule := range rules {
+		window, err := rule.GetWindow()
+		if err != nil {
+			return err
+		}
+
+		var effects, causes []*models.ConfigChange
+		for _, change := range changes {
+			if change.ConfigID == "" {
+				continue
+			}
+
+			configType := c.configType(change)
+			if rule.Effect.Matches(change.ChangeType, configType) && v1.CorrelationFromDetails(change.Details).Parent == nil {
+				effects = append(effects, change)
+			}
+			if rule.Cause.Matches(change.ChangeType, configType) {
+				causes = append(causes, change)
+			}
+		}
+
+		// The candidates are looked up once for all the changes of a config item
+		for _, batch := range groupByConfig(effects) {
+			if err := c.linkCauses(rule, window, batch); err != nil {
+				return fmt.Errorf("failed to correlate changes of %s with rule %s: %w", batch[0].ConfigID, rule.Name, err)
+			}
+		}
+
+		for _, batch := range groupByConfig(causes) {
+			if err := c.linkEffects(rule, window, batch); err != nil {
+				return fmt.Errorf("failed to correlate changes of %s with rule %s: %w", batch[0].ConfigID, rule.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// linkCauses links each effect, all on the same config item, to the most recent cause within the window before it.
+func (c *correlator) linkCauses(rule v1.ChangeCorrelation, window time.Duration, effects []*models.ConfigChange) error {
+	related, err := c.relatedConfigs(effects[0], rule.Relationship.GetDirection(), rule.Relationship.GetMaxDepth())
+	if err != nil {
+		return err
+	}
+
+	from, to := changeTimeRange(effects)
+	candidates, err := c.candidates(rule.Cause, related, from.Add(-window), to, false)
+	if err != nil {
+		return err
+	}
+
+	for _, effect := range effects {
+		createdAt := changeTime(effect)
+		for _, cause := range candidates {
+			if cause.ID == effect.ID || cause.CreatedAt.After(createdAt) || cause.CreatedAt.Before(createdAt.Add(-window)) {
+				continue
+			}
+
+			if err := c.link(rule, &cause, effect); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// linkEffects links each cause, all on the same config item, to the effects within the window after it
+// that aren't linked to a cause yet. An effect that follows several causes is linked to the latest one.
+func (c *correlator) linkEffects(rule v1.ChangeCorrelation, window time.Duration, causes []*models.ConfigChange) error {
+	// The relationship path goes from the effect to the cause
+	related, err := c.relatedConfigs(causes[0], reverseDirection(rule.Relationship.GetDirection()), rule.Relationship.GetMaxDepth())
+	if err != nil {
+		return err
+	}
+
+	from, to := changeTimeRange(causes)
+	candidates, err := c.candidates(rule.Effect, related, from, to.Add(window), true)
+	if err != nil {
+		return err
+	}
+
+	causes = slices.Clone(causes)
+	slices.SortStableFunc(causes, func(a, b *models.ConfigChange) int {
+		return changeTime(b).Compare(changeTime(a))
+	})
+
+	linked := map[string]bool{}
+	for _, cause := range causes {
+		createdAt := changeTime(cause)
+		for _, effect := range candidates {
+			if effect.ID == cause.ID || linked[effect.ID] || effect.CreatedAt.Before(createdAt) || effect.CreatedAt.After(createdAt.Add(window)) {
+				continue
+			}
+
+			if existing, ok := c.changes[effect.ID]; ok {
+				if v1.CorrelationFromDetails(existing.Details).Parent != nil {
+					continue
+				}
+			}
+
+			if err := c.link(rule, cause, &effect); err != nil {
+				return err
+			}
+			linked[effect.ID] = true
+		}
+	}
+
+	return nil
+}
+
+// candidates returns the changes between from and to on the related config items that match the selector,
+// the effects that aren't linked to a cause yet sorted by time, or else the causes latest first.
+// Config types and exact change types are filtered before querying.
+func (c *correlator) candidates(selector v1.ChangeCorrelationSelector, related map[string]string, from, to time.Time, effects bool) ([]models.ConfigChange, error) {
+	configIDs := lo.Filter(lo.Keys(related), func(id string, _ int) bool {
+		return selector.ConfigType == "" || selector.ConfigType.Match(related[id])
+	})
+	if len(configIDs) == 0 {
+		return nil, nil
+	}
+
+	q := c.ctx.DB().
+		Where("config_id IN ?", configIDs).
+		Where("created_at BETWEEN ? AND ?", from, to)
+	if changeTypes, ok := exactChangeTypes(selector.ChangeType); ok {
+		q = q.Where("change_type IN ?", changeTypes)
+	}
+	if effects {
+		q = q.Where("details->'correlation'->'parent' IS NULL").Order("created_at")
+	} else {
+		q = q.Order("created_at DESC")
+	}
+
+	var candidates []models.ConfigChange
+	if err := q.Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	return lo.Filter(candidates, func(change models.ConfigChange, _ int) bool {
+		return selector.Matches(change.ChangeType, related[change.ConfigID])
+	}), nil
+}
+
+// link records the cause as the parent of the effect and the effect as a child of the cause.
+func (c *correlator) link(rule v1.ChangeCorrelation, cause, effect *models.ConfigChange) error {
+	parent, err := json.Marshal(changeLink(rule, cause))
+	if err != nil {
+		return err
+	}
+	children, err := json.Marshal([]v1.ChangeLink{changeLink(rule, effect)})
+	if err != nil {
+		return err
+	}
+
+	if err := c.ctx.DB().Exec(`UPDATE config_changes SET details = COALESCE(details, '{}'::jsonb) ||
+		jsonb_build_object('correlation', COALESCE(details->'correlation', '{}'::jsonb) || jsonb_build_object('parent', ?::jsonb))
+		WHERE id = ?`, string(parent), effect.ID).Error; err != nil {
+		return fmt.Errorf("failed to link change %s to its cause: %w", effect.ID, err)
+	}
+
+	if err := c.ctx.DB().Exec(`UPDATE config_changes SET details = COALESCE(details, '{}'::jsonb) ||
+		jsonb_build_object('correlation', COALESCE(details->'correlation', '{}'::jsonb) ||
+			jsonb_build_object('children', COALESCE(details->'correlation'->'children', '[]'::jsonb) || ?::jsonb))
+		WHERE id = ? AND NOT COALESCE(details->'correlation'->'children', '[]'::jsonb) @> jsonb_build_array(jsonb_build_object('id', ?::text))`,
+		string(children), cause.ID, effect.ID).Error; err != nil {
+		return fmt.Errorf("failed to link change %s to its effect: %w", cause.ID, err)
+	}
+
+	if change, ok := c.changes[effect.ID]; ok {
+		correlation := v1.CorrelationFromDetails(change.Details)
+		correlation.Parent = lo.ToPtr(changeLink(rule, cause))
+		setCorrelation(change, correlation)
+	}
+
+	if change, ok := c.changes[cause.ID]; ok {
+		correlation := v1.CorrelationFromDetails(change.Details)
+		if !lo.ContainsBy(correlation.Children, func(l v1.ChangeLink) bool { return l.ID == effect.ID }) {
+			correlation.Children = append(correlation.Children, changeLink(rule, effect))
+		}
+		setCorrelation(change, correlation)
+	}
+
+	c.ctx.Logger.V(3).Infof("correlated change %s (%s) with its cause %s (%s)", effect.ID, effect.ChangeType, cause.ID, cause.ChangeType)
+	return nil
+}
+
+// relatedConfigs returns the type of the config item of the change, and of the config items
+// related to it in the direction and up to the depth, keyed by their id.
+func (c *correlator) relatedConfigs(change *models.ConfigChange, direction string, depth int) (map[string]string, error) {
+	key := fmt.Sprintf("%s/%s/%d", change.ConfigID, direction, depth)
+	if related, ok := c.related[key]; ok {
+		return related, nil
+	}
+
+	related := map[string]string{change.ConfigID: c.configType(change)}
+	if depth > 0 {
+		id, err := uuid.Parse(change.ConfigID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config id %s: %w", change.ConfigID, err)
+		}
+
+		configs, err := query.GetRelatedConfigs(c.ctx.DutyContext(), query.RelationQuery{
+			ID:       id,
+			Relation: query.RelationDirection(direction),
+			MaxDepth: &depth,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get related configs of %s: %w", change.ConfigID, err)
+		}
+
+		for _, config := range configs {
+			related[config.ID.String()] = config.Type
+		}
+	}
+
+	c.related[key] = related
+	return related, nil
+}
+
+func (c *correlator) configType(change *models.ConfigChange) string {
+	return changeConfigType(c.ctx, change)
+}
+
+// changeConfigType returns the type of the config item of the change,
+// which is only set on changes that refer to their config item by external id.
+func changeConfigType(ctx api.ScrapeContext, change *models.ConfigChange) string {
+	if change.ConfigType != "" {
+		return change.ConfigType
+	}
+
+	if ci, err := ctx.TempCache().Get(ctx, change.ConfigID); err == nil && ci != nil {
+		return ci.Type
+	}
+	return ""
+}
+
+func changeLink(rule v1.ChangeCorrelation, change *models.ConfigChange) v1.ChangeLink {
+	return v1.ChangeLink{
+		ID:         change.ID,
+		ConfigID:   change.ConfigID,
+		ChangeType: change.ChangeType,
+		CreatedAt:  changeTime(change),
+		Rule:       rule.Name,
+	}
+}
+
+func setCorrelation(change *models.ConfigChange, correlation v1.ChangeCorrelationDetails) {
+	if change.Details == nil {
+		change.Details = v1.JSON{}
+	}
+	change.Details["correlation"] = correlation
+}
+
+func changeTime(change *models.ConfigChange) time.Time {
+	if change.CreatedAt.IsZero() {
+		return time.Now()
+	}
+	return change.CreatedAt
+}
+
+func changeTimeRange(changes []*models.ConfigChange) (from, to time.Time) {
+	for i, change := range changes {
+		createdAt := changeTime(change)
+		if i == 0 || createdAt.Before(from) {
+			from = createdAt
+		}
+		if i == 0 || createdAt.After(to) {
+			to = createdAt
+		}
+	}
+	return from, to
+}
+
+// groupByConfig groups the changes by their config item, in the order they first appear.
+func groupByConfig(changes []*models.ConfigChange) [][]*models.ConfigChange {
+	groups := lo.GroupBy(changes, func(change *models.ConfigChange) string { return change.ConfigID })
+	return lo.Map(lo.Uniq(lo.Map(changes, func(change *models.ConfigChange, _ int) string { return change.ConfigID })),
+		func(configID string, _ int) []*models.ConfigChange { return groups[configID] })
+}
+
+// exactChangeTypes returns the change types of the expression when it only lists exact change types.
+func exactChangeTypes(expr types.MatchExpression) ([]string, bool) {
+	if expr == "" || strings.ContainsAny(string(expr), "*!") {
+		return nil, false
+	}
+
+	changeTypes := lo.Compact(lo.Map(strings.Split(string(expr), ","), func(t string, _ int) string { return strings.TrimSpace(t) }))
+	return changeTypes, len(changeTypes) > 0
+}
+
+func reverseDirection(direction string) string {
+	switch query.RelationDirection(direction) {
+	case query.Incoming:
+		return string(query.Outgoing)
+	case query.Outgoing:
+		return string(query.Incoming)
+	}
+	return direction
+}
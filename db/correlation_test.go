@@ -0,0 +1,158 @@
+package db
+
+import (
+	"time"
+
+	dutymodels "github.com/flanksource/duty/models"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db/models"
+)
+
+var _ = Describe("correlateChanges", Ordered, func() {
+	var (
+		ctx          api.ScrapeContext
+		deploymentID = uuid.New()
+		configMapID  = uuid.New()
+		start        = time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	)
+
+	newChange := func(configID uuid.UUID, changeType string, minutes int) *models.ConfigChange {
+		change := &models.ConfigChange{
+			ID:         uuid.NewString(),
+			ConfigID:   configID.String(),
+			ChangeType: changeType,
+			Source:     "correlation-test",
+			CreatedAt:  start.Add(time.Duration(minutes) * time.Minute),
+		}
+		Expect(ctx.DB().Create(change).Error).ToNot(HaveOccurred())
+		return change
+	}
+
+	savedCorrelation := func(id string) v1.ChangeCorrelationDetails {
+		var change models.ConfigChange
+		Expect(ctx.DB().Where("id = ?", id).First(&change).Error).ToNot(HaveOccurred())
+		return v1.CorrelationFromDetails(change.Details)
+	}
+
+	BeforeAll(func() {
+		ctx = api.NewScrapeContext(DefaultContext).WithScrapeConfig(&v1.ScrapeConfig{
+			Spec: v1.ScraperSpec{
+				Correlation: []v1.ChangeCorrelation{{
+					Name:         "configmap-rollout",
+					Cause:        v1.ChangeCorrelationSelector{ConfigType: "Kubernetes::ConfigMap", ChangeType: "diff"},
+					Effect:       v1.ChangeCorrelationSelector{ConfigType: "Kubernetes::Deployment", ChangeType: "Scaling*"},
+					Relationship: v1.ChangeCorrelationPath{Direction: "all", MaxDepth: lo.ToPtr(1)},
+					Window:       "10m",
+				}},
+			},
+		})
+
+		for id, configType := range map[uuid.UUID]string{deploymentID: "Kubernetes::Deployment", configMapID: "Kubernetes::ConfigMap"} {
+			Expect(ctx.DB().Create(&dutymodels.ConfigItem{
+				ID:          id,
+				ConfigClass: "Kubernetes",
+				Type:        lo.ToPtr(configType),
+			}).Error).ToNot(HaveOccurred())
+		}
+
+		Expect(ctx.DB().Create(&dutymodels.ConfigRelationship{
+			ConfigID:  deploymentID.String(),
+			RelatedID: configMapID.String(),
+			Relation:  "DeploymentConfigMap",
+		}).Error).ToNot(HaveOccurred())
+	})
+
+	AfterAll(func() {
+		Expect(ctx.DB().Where("source = ?", "correlation-test").Delete(&models.ConfigChange{}).Error).ToNot(HaveOccurred())
+		Expect(ctx.DB().Where("config_id = ?", deploymentID).Delete(&dutymodels.ConfigRelationship{}).Error).ToNot(HaveOccurred())
+		Expect(ctx.DB().Delete(&dutymodels.ConfigItem{}, []uuid.UUID{deploymentID, configMapID}).Error).ToNot(HaveOccurred())
+	})
+
+	It("should link a new effect to the latest cause within the window", func() {
+		newChange(configMapID, "diff", 0)
+		cause := newChange(configMapID, "diff", 5)
+		unrelated := newChange(deploymentID, "diff", 6)
+		effect := newChange(deploymentID, "ScalingReplicaSet", 8)
+
+		Expect(correlateChanges(ctx, []*models.ConfigChange{effect})).To(Succeed())
+
+		parent := savedCorrelation(effect.ID).Parent
+		Expect(parent).ToNot(BeNil())
+		Expect(parent.ID).To(Equal(cause.ID))
+		Expect(parent.Rule).To(Equal("configmap-rollout"))
+		Expect(v1.CorrelationFromDetails(effect.Details).Parent.ID).To(Equal(cause.ID))
+
+		children := savedCorrelation(cause.ID).Children
+		Expect(children).To(HaveLen(1))
+		Expect(children[0].ID).To(Equal(effect.ID))
+		Expect(savedCorrelation(unrelated.ID).Parent).To(BeNil())
+	})
+
+	It("should link a new cause to the unlinked effects that followed it", func() {
+		linked := newChange(deploymentID, "ScalingReplicaSet", 9)
+		Expect(correlateChanges(ctx, []*models.ConfigChange{linked})).To(Succeed())
+
+		cause := newChange(configMapID, "diff", 20)
+		effect := newChange(deploymentID, "ScalingReplicaSet", 25)
+		late := newChange(deploymentID, "ScalingReplicaSet", 40)
+
+		Expect(correlateChanges(ctx, []*models.ConfigChange{cause})).To(Succeed())
+
+		Expect(savedCorrelation(effect.ID).Parent.ID).To(Equal(cause.ID))
+		Expect(savedCorrelation(late.ID).Parent).To(BeNil())
+		Expect(savedCorrelation(cause.ID).Children).To(HaveLen(1))
+		Expect(savedCorrelation(linked.ID).Parent.ID).ToNot(Equal(cause.ID))
+
+		// Correlating again doesn't duplicate the links
+		Expect(correlateChanges(ctx, []*models.ConfigChange{cause, effect})).To(Succeed())
+		Expect(savedCorrelation(cause.ID).Children).To(HaveLen(1))
+	})
+
+	It("should link each change of a config item to its own cause", func() {
+		first := newChange(configMapID, "diff", 60)
+		second := newChange(configMapID, "diff", 66)
+		early := newChange(deploymentID, "ScalingReplicaSet", 62)
+		later := newChange(deploymentID, "ScalingReplicaSet", 68)
+
+		Expect(correlateChanges(ctx, []*models.ConfigChange{later, early})).To(Succeed())
+
+		Expect(savedCorrelation(early.ID).Parent.ID).To(Equal(first.ID))
+		Expect(savedCorrelation(later.ID).Parent.ID).To(Equal(second.ID))
+	})
+
+	It("should link the stored row of an upserted change", func() {
+		cause := newChange(configMapID, "diff", 50)
+		stored := &models.ConfigChange{
+			ConfigID:         deploymentID.String(),
+			ChangeType:       "ScalingReplicaSet",
+			Source:           "correlation-test",
+			ExternalChangeID: lo.ToPtr("scaling-1"),
+			CreatedAt:        start.Add(52 * time.Minute),
+		}
+		Expect(ctx.DB().Create(stored).Error).ToNot(HaveOccurred())
+
+		// The same change scraped again keeps the id of the existing row
+		rescraped := &models.ConfigChange{
+			ID:               uuid.NewString(),
+			ConfigID:         deploymentID.String(),
+			ChangeType:       "ScalingReplicaSet",
+			Source:           "correlation-test",
+			ExternalChangeID: lo.ToPtr("scaling-1"),
+			CreatedAt:        start.Add(52 * time.Minute),
+		}
+		Expect(ctx.DB().Create(rescraped).Error).ToNot(HaveOccurred())
+
+		resolveStoredChangeIDs(ctx, []*models.ConfigChange{rescraped})
+		Expect(rescraped.ID).To(Equal(stored.ID))
+
+		Expect(correlateChanges(ctx, []*models.ConfigChange{rescraped})).To(Succeed())
+		Expect(savedCorrelation(stored.ID).Parent.ID).To(Equal(cause.ID))
+		Expect(savedCorrelation(cause.ID).Children[0].ID).To(Equal(stored.ID))
+	})
+})
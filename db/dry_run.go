@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/flanksource/config-db/api"
+	"gorm.io/gorm"
+)
+
+// DryRun runs fn in a database transaction that is rolled back once fn returns,
+// so that nothing fn writes is persisted. Transactions begun by fn become
+// savepoints of the dry run transaction.
+//
+// The caches of parents, orphans and external entities are reloaded afterwards,
+// as fn may have cached rows that no longer exist. The state kept across scrapes,
+// e.g. the rate limit windows and the rolled up changes, is isolated with
+// api.ScrapeContext.WithDryRun, so that it is left as it was for the next scrape.
+func DryRun(ctx api.ScrapeContext, fn func(ctx api.ScrapeContext) error) error {
+	if ctx.DB() == nil {
+		return fmt.Errorf("dry run requires a database")
+	}
+
+	tx := ctx.DB().Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to begin dry run transaction: %w", tx.Error)
+	}
+	tx.Statement.ConnPool = &savepointPool{ConnPool: tx.Statement.ConnPool}
+
+	dryRunCtx := ctx.WithDryRun()
+	dryRunCtx.Context = ctx.Context.WithDB(tx, ctx.Pool())
+	err := fn(dryRunCtx)
+
+	if rollbackErr := tx.Rollback().Error; rollbackErr != nil {
+		return fmt.Errorf("failed to roll back dry run transaction: %w", rollbackErr)
+	}
+
+	ParentCache.Flush()
+	OrphanCache.Flush()
+	if warmErr := WarmExternalEntityCaches(ctx.DutyContext()); warmErr != nil {
+		ctx.Logger.Warnf("failed to reload external entity caches after dry run: %v", warmErr)
+	}
+
+	return err
+}
+
+var dryRunSavepoints atomic.Int64
+
+// savepointPool is the connection of a dry run transaction. Beginning a transaction on it
+// creates a savepoint, and committing or rolling back that transaction releases or rolls back
+// to the savepoint, so that code beginning its own transactions runs unchanged.
+type savepointPool struct {
+	gorm.ConnPool
+
+	// savepoint is empty for the dry run transaction itself
+	savepoint string
+}
+
+func (p *savepointPool) BeginTx(ctx context.Context, _ *sql.TxOptions) (gorm.ConnPool, error) {
+	name := fmt.Sprintf("dry_run_%d", dryRunSavepoints.Add(1))
+	if _, err := p.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	return &savepointPool{ConnPool: p.ConnPool, savepoint: name}, nil
+}
+
+// Commit releases the savepoint. The dry run transaction itself is never committed.
+func (p *savepointPool) Commit() error {
+	if p.savepoint == "" {
+		return nil
+	}
+	_, err := p.ExecContext(context.Background(), "RELEASE SAVEPOINT "+p.savepoint)
+	return err
+}
+
+func (p *savepointPool) Rollback() error {
+	if p.savepoint == "" {
+		committer, ok := p.ConnPool.(gorm.TxCommitter)
+		if !ok {
+			return gorm.ErrInvalidTransaction
+		}
+		return committer.Rollback()
+	}
+	_, err := p.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+p.savepoint)
+	return err
+}
+
+// CountChangesByType returns the number of changes of each change type on the config items of the scraper.
+func CountChangesByType(ctx api.ScrapeContext, scraperID string) (map[string]int, error) {
+	var rows []struct {
+		ChangeType string
+		Count      int
+	}
+	if err := ctx.DB().Raw(`
+SELECT cc.change_type, COUNT(*) AS count
+FROM config_changes cc
+JOIN config_items ci ON ci.id = cc.config_id
+WHERE ci.scraper_id = ?
+GROUP BY cc.change_type`, scraperID).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.ChangeType] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"errors"
+
+	dutymodels "github.com/flanksource/duty/models"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+var _ = Describe("DryRun", func() {
+	var ctx api.ScrapeContext
+
+	configItem := func() *dutymodels.ConfigItem {
+		return &dutymodels.ConfigItem{
+			ID:          uuid.New(),
+			ConfigClass: "Database",
+			Type:        lo.ToPtr("AWS::RDS::DBInstance"),
+		}
+	}
+
+	exists := func(id uuid.UUID) bool {
+		var count int64
+		Expect(ctx.DB().Model(&dutymodels.ConfigItem{}).Where("id = ?", id).Count(&count).Error).ToNot(HaveOccurred())
+		return count > 0
+	}
+
+	BeforeEach(func() {
+		ctx = api.NewScrapeContext(DefaultContext).WithScrapeConfig(&v1.ScrapeConfig{})
+	})
+
+	It("should roll back everything written", func() {
+		outer, committed, rolledBack := configItem(), configItem(), configItem()
+
+		err := DryRun(ctx, func(ctx api.ScrapeContext) error {
+			Expect(ctx.DB().Create(outer).Error).ToNot(HaveOccurred())
+
+			tx := ctx.DB().Begin()
+			Expect(tx.Error).ToNot(HaveOccurred())
+			Expect(tx.Create(committed).Error).ToNot(HaveOccurred())
+			Expect(tx.Commit().Error).ToNot(HaveOccurred())
+
+			Expect(ctx.DB().Transaction(func(tx *gorm.DB) error {
+				Expect(tx.Create(rolledBack).Error).ToNot(HaveOccurred())
+				return errors.New("rollback")
+			})).To(MatchError("rollback"))
+
+			// a failed statement only rolls back to its savepoint
+			Expect(ctx.DB().Create(&dutymodels.ConfigItem{ID: outer.ID, ConfigClass: "Database"}).Error).To(HaveOccurred())
+
+			var ids []uuid.UUID
+			Expect(ctx.DB().Model(&dutymodels.ConfigItem{}).Where("id IN ?", []uuid.UUID{outer.ID, committed.ID, rolledBack.ID}).Pluck("id", &ids).Error).ToNot(HaveOccurred())
+			Expect(ids).To(ConsistOf(outer.ID, committed.ID))
+			return nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(exists(outer.ID)).To(BeFalse())
+		Expect(exists(committed.ID)).To(BeFalse())
+	})
+
+	It("should return the error of the dry run", func() {
+		item := configItem()
+
+		err := DryRun(ctx, func(ctx api.ScrapeContext) error {
+			Expect(ctx.DB().Create(item).Error).ToNot(HaveOccurred())
+			return errors.New("failed")
+		})
+		Expect(err).To(MatchError("failed"))
+		Expect(exists(item.ID)).To(BeFalse())
+	})
+})
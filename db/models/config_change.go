@@ -20,6 +20,9 @@ type ConfigChange struct {
 	ConfigType string `gorm:"-"`
 	ScraperID  string `gorm:"-"`
 
+	// Aggregate is the group of changes rolled up into this change
+	Aggregate *v1.ChangeAggregate `gorm:"-" json:"-"`
+
 	Fingerprint       *string    `gorm:"column:fingerprint" json:"fingerprint"`
 	ExternalChangeID  *string    `gorm:"column:external_change_id;default:null" json:"external_change_id"`
 	ID                string     `gorm:"primaryKey;unique_index;not null;column:id" json:"id"`
@@ -75,9 +78,22 @@ func NewConfigChangeFromV1(result v1.ScrapeResult, change v1.ChangeResult) *Conf
 		_change.ExternalChangeID = &change.ExternalChangeID
 	}
 
+	if change.Aggregate != nil {
+		_change.SetAggregate(*change.Aggregate)
+	}
+
 	return &_change
 }
 
+// SetAggregate sets the count, timestamps and details of a change that rolls up a group of changes.
+func (c *ConfigChange) SetAggregate(group v1.ChangeAggregate) {
+	c.Aggregate = &group
+	c.Count = group.Count
+	c.FirstObserved = lo.ToPtr(group.First)
+	c.CreatedAt = group.Last
+	c.Details = v1.JSON(lo.Assign(map[string]any(c.Details), map[string]any{"aggregate": group.AsDetails()}))
+}
+
 func (c ConfigChange) Columns() []api.ColumnDef {
 	return []api.ColumnDef{
 		clicky.Column("ConfigType").Build(),
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db/models"
+)
+
+// changeRateWindow counts the changes of a type of a config item in the window of a rate limit.
+type changeRateWindow struct {
+	Start   time.Time
+	Window  time.Duration
+	Count   int
+	Dropped int
+	First   time.Time
+	Last    time.Time
+
+	// SummaryID is the id of the RateLimited change that counts the dropped changes of the window
+	SummaryID string
+}
+
+// changeRateWindows keeps the open windows across scrapes, so that a config item that
+// produces a few changes on every scrape is limited in the same way as one that produces them all at once.
+var (
+	changeRateWindows     = cache.New(time.Hour, 10*time.Minute)
+	changeRateWindowsLock sync.Mutex
+)
+
+type rateLimitResult struct {
+	// allowed are the changes within the limits, in their original order
+	allowed []*models.ConfigChange
+
+	// newSummaries are the RateLimited changes of the windows that dropped their first changes
+	newSummaries []*models.ConfigChange
+
+	// updatedSummaries are the RateLimited changes, saved by previous scrapes, of the windows that dropped more changes
+	updatedSummaries []*models.ConfigChange
+
+	// dropped counts the dropped changes by config type and change type
+	dropped map[string]map[string]int
+
+	// windows are the windows counted in this scrape, cached by cacheRateWindows once the changes are saved
+	windows map[string]changeRateWindow
+}
+
+// rateLimitChanges drops the changes over the change rate limits of the scraper.
+// The dropped changes are folded into a RateLimited change per config item, change type and window.
+func rateLimitChanges(ctx api.ScrapeContext, changes []*models.ConfigChange) (rateLimitResult, error) {
+	result := rateLimitResult{allowed: changes}
+
+	limits := ctx.ScrapeConfig().Spec.ChangeRateLimits
+	if len(limits) == 0 || len(changes) == 0 {
+		return result, nil
+	}
+
+	windows := make([]time.Duration, len(limits))
+	for i, limit := range limits {
+		if limit.Max <= 0 {
+			return result, fmt.Errorf("change rate limit for %s must allow at least 1 change", lo.CoalesceOrEmpty(string(limit.ChangeType), "all change types"))
+		}
+
+		window, err := limit.GetWindow()
+		if err != nil {
+			return result, err
+		}
+		windows[i] = window
+	}
+
+	// Changes that were saved by previous scrapes are updated in place and don't count against the limits
+	stored, err := storedChangeIDs(ctx, changes)
+	if err != nil {
+		return result, fmt.Errorf("failed to find saved changes: %w", err)
+	}
+
+	// Count the changes in the order they happened
+	sorted := lo.Filter(changes, func(c *models.ConfigChange, _ int) bool {
+		_, exists := stored[storedChangeKey(c)]
+		return !exists
+	})
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return changeTime(sorted[i]).Before(changeTime(sorted[j]))
+	})
+
+	changeRateWindowsLock.Lock()
+	defer changeRateWindowsLock.Unlock()
+	rateWindows := api.DryRunState(ctx, "changeRateWindows", changeRateWindows, copyCache)
+
+	type droppedWindow struct {
+		change *models.ConfigChange
+		limit  v1.ChangeRateLimit
+		window *changeRateWindow
+	}
+
+	var (
+		touched = map[string]*changeRateWindow{}
+		dropped = map[*models.ConfigChange]bool{}
+		// windows that dropped changes in this scrape, in the order of their first drop
+		droppedWindows []droppedWindow
+		seen           = map[*changeRateWindow]bool{}
+	)
+
+	result.dropped = map[string]map[string]int{}
+	for _, change := range sorted {
+		if change.ConfigID == "" {
+			continue
+		}
+
+		configType := changeConfigType(ctx, change)
+		found := slices.IndexFunc(limits, func(l v1.ChangeRateLimit) bool { return l.Matches(change.ChangeType, configType) })
+		if found < 0 {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s/%d/%s", ctx.ScraperID(), change.ConfigID, found, change.ChangeType)
+		createdAt := changeTime(change)
+
+		window, ok := touched[key]
+		if !ok {
+			if cached, exists := rateWindows.Get(key); exists {
+				w := cached.(changeRateWindow)
+				window = &w
+			}
+		}
+		if window == nil || !createdAt.Before(window.Start.Add(window.Window)) {
+			window = &changeRateWindow{Start: createdAt, Window: windows[found]}
+		}
+		touched[key] = window
+
+		window.Count++
+		if window.Count <= limits[found].Max {
+			continue
+		}
+
+		if window.Dropped == 0 {
+			window.First = createdAt
+		}
+		window.Dropped++
+		window.Last = createdAt
+		dropped[change] = true
+
+		if !seen[window] {
+			seen[window] = true
+			droppedWindows = append(droppedWindows, droppedWindow{change: change, limit: limits[found], window: window})
+		}
+
+		if result.dropped[configType] == nil {
+			result.dropped[configType] = map[string]int{}
+		}
+		result.dropped[configType][change.ChangeType]++
+	}
+
+	for _, d := range droppedWindows {
+		summary := rateLimitedChange(d.change, d.limit, *d.window)
+		if d.window.SummaryID == "" {
+			d.window.SummaryID = summary.ID
+			result.newSummaries = append(result.newSummaries, summary)
+		} else {
+			summary.ID = d.window.SummaryID
+			result.updatedSummaries = append(result.updatedSummaries, summary)
+		}
+	}
+
+	result.windows = make(map[string]changeRateWindow, len(touched))
+	for key, window := range touched {
+		result.windows[key] = *window
+	}
+
+	if len(dropped) > 0 {
+		result.allowed = lo.Filter(changes, func(c *models.ConfigChange, _ int) bool { return !dropped[c] })
+	}
+	return result, nil
+}
+
+// cacheRateWindows keeps the windows counted by a scrape for the next scrapes, once its changes are saved.
+// Windows whose new RateLimited change could not be saved are dropped.
+func cacheRateWindows(ctx api.ScrapeContext, windows map[string]changeRateWindow, unsaved map[string]bool) {
+	if len(windows) == 0 {
+		return
+	}
+
+	changeRateWindowsLock.Lock()
+	defer changeRateWindowsLock.Unlock()
+	rateWindows := api.DryRunState(ctx, "changeRateWindows", changeRateWindows, copyCache)
+
+	for key, window := range windows {
+		if unsaved[window.SummaryID] {
+			rateWindows.Delete(key)
+		} else if ttl := time.Until(window.Start.Add(window.Window)); ttl > 0 {
+			rateWindows.Set(key, window, ttl)
+		}
+	}
+}
+
+// rateLimitedChange returns the change that counts the changes dropped in the window.
+func rateLimitedChange(change *models.ConfigChange, limit v1.ChangeRateLimit, window changeRateWindow) *models.ConfigChange {
+	return &models.ConfigChange{
+		ID:            uuid.NewString(),
+		ExternalID:    change.ExternalID,
+		ConfigType:    change.ConfigType,
+		ScraperID:     change.ScraperID,
+		ConfigID:      change.ConfigID,
+		ChangeType:    v1.ChangeTypeRateLimited,
+		Source:        change.Source,
+		Severity:      "info",
+		Summary:       fmt.Sprintf("%d %s changes over the limit of %d per %s were dropped", window.Dropped, change.ChangeType, limit.Max, window.Window),
+		Count:         window.Dropped,
+		FirstObserved: lo.ToPtr(window.First),
+		CreatedAt:     window.Last,
+		Details: v1.JSON{
+			"rate_limit": map[string]any{
+				"change_type": change.ChangeType,
+				"max":         limit.Max,
+				"window":      window.Window.String(),
+				"start":       window.Start,
+				"dropped":     window.Dropped,
+				"first":       window.First,
+				"last":        window.Last,
+			},
+		},
+	}
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	dutymodels "github.com/flanksource/duty/models"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db/models"
+)
+
+var _ = Describe("rateLimitChanges", func() {
+	var (
+		ctx      api.ScrapeContext
+		configID string
+		start    = time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	)
+
+	BeforeEach(func() {
+		configID = uuid.NewString()
+		ctx = api.NewScrapeContext(DefaultContext).WithScrapeConfig(&v1.ScrapeConfig{
+			ObjectMeta: metav1.ObjectMeta{UID: k8stypes.UID(uuid.NewString())},
+			Spec: v1.ScraperSpec{
+				ChangeRateLimits: []v1.ChangeRateLimit{
+					{ChangeType: "SuccessfulRescale", ConfigType: "Kubernetes::HorizontalPodAutoscaler", Max: 2, Window: "1h"},
+				},
+			},
+		})
+	})
+
+	newChanges := func(changeType string, count int, offset int) []*models.ConfigChange {
+		var changes []*models.ConfigChange
+		for i := 0; i < count; i++ {
+			changes = append(changes, &models.ConfigChange{
+				ID:         uuid.NewString(),
+				ConfigID:   configID,
+				ConfigType: "Kubernetes::HorizontalPodAutoscaler",
+				ChangeType: changeType,
+				CreatedAt:  start.Add(time.Duration(offset+i) * time.Second),
+			})
+		}
+		return changes
+	}
+
+	It("should drop the changes over the limit into a summary change", func() {
+		changes := append(newChanges("SuccessfulRescale", 5, 0), newChanges("Normal", 3, 0)...)
+
+		result, err := rateLimitChanges(ctx, changes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.allowed).To(HaveLen(5))
+		Expect(result.allowed[:2]).To(Equal(changes[:2]))
+		Expect(result.dropped).To(Equal(map[string]map[string]int{
+			"Kubernetes::HorizontalPodAutoscaler": {"SuccessfulRescale": 3},
+		}))
+
+		Expect(result.newSummaries).To(HaveLen(1))
+		summary := result.newSummaries[0]
+		Expect(summary.ChangeType).To(Equal(v1.ChangeTypeRateLimited))
+		Expect(summary.ConfigID).To(Equal(configID))
+		Expect(summary.Count).To(Equal(3))
+		Expect(summary.CreatedAt).To(Equal(changes[4].CreatedAt))
+		Expect(*summary.FirstObserved).To(Equal(changes[2].CreatedAt))
+	})
+
+	It("should update the summary change of the window in later scrapes", func() {
+		first, err := rateLimitChanges(ctx, newChanges("SuccessfulRescale", 3, 0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.newSummaries).To(HaveLen(1))
+		cacheRateWindows(ctx, first.windows, nil)
+
+		second, err := rateLimitChanges(ctx, newChanges("SuccessfulRescale", 2, 10))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second.allowed).To(BeEmpty())
+		Expect(second.newSummaries).To(BeEmpty())
+		Expect(second.updatedSummaries).To(HaveLen(1))
+		Expect(second.updatedSummaries[0].ID).To(Equal(first.newSummaries[0].ID))
+		Expect(second.updatedSummaries[0].Count).To(Equal(3))
+		Expect(second.dropped["Kubernetes::HorizontalPodAutoscaler"]["SuccessfulRescale"]).To(Equal(2))
+	})
+
+	It("should not keep the windows of dry runs", func() {
+		dryRunCtx := ctx.WithDryRun()
+		dryRun, err := rateLimitChanges(dryRunCtx, newChanges("SuccessfulRescale", 3, 0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dryRun.newSummaries).To(HaveLen(1))
+		cacheRateWindows(dryRunCtx, dryRun.windows, nil)
+
+		result, err := rateLimitChanges(ctx, newChanges("SuccessfulRescale", 2, 10))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.allowed).To(HaveLen(2))
+		Expect(result.newSummaries).To(BeEmpty())
+	})
+
+	It("should not keep the windows until the changes are saved", func() {
+		first, err := rateLimitChanges(ctx, newChanges("SuccessfulRescale", 3, 0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first.newSummaries).To(HaveLen(1))
+
+		// the save failed, so the next scrape counts the changes again
+		second, err := rateLimitChanges(ctx, newChanges("SuccessfulRescale", 2, 10))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second.allowed).To(HaveLen(2))
+
+		// the RateLimited change failed foreign key checks, so the window is not kept
+		cacheRateWindows(ctx, first.windows, map[string]bool{first.newSummaries[0].ID: true})
+		third, err := rateLimitChanges(ctx, newChanges("SuccessfulRescale", 2, 20))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(third.allowed).To(HaveLen(2))
+		Expect(third.updatedSummaries).To(BeEmpty())
+	})
+
+	It("should not count the changes saved by previous scrapes", func() {
+		Expect(ctx.DB().Create(&dutymodels.ConfigItem{
+			ID:          uuid.MustParse(configID),
+			ConfigClass: "Kubernetes",
+			Type:        lo.ToPtr("Kubernetes::HorizontalPodAutoscaler"),
+		}).Error).ToNot(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(ctx.DB().Where("config_id = ?", configID).Delete(&models.ConfigChange{}).Error).ToNot(HaveOccurred())
+			Expect(ctx.DB().Delete(&dutymodels.ConfigItem{}, "id = ?", configID).Error).ToNot(HaveOccurred())
+		})
+
+		saved := newChanges("SuccessfulRescale", 2, 0)
+		for i, change := range saved {
+			change.ExternalChangeID = lo.ToPtr(fmt.Sprintf("rescale-%d", i))
+		}
+		Expect(ctx.DB().Create(&saved).Error).ToNot(HaveOccurred())
+
+		rescraped := newChanges("SuccessfulRescale", 2, 0)
+		for i, change := range rescraped {
+			change.ExternalChangeID = lo.ToPtr(fmt.Sprintf("rescale-%d", i))
+		}
+		changes := append(rescraped, newChanges("SuccessfulRescale", 2, 10)...)
+
+		result, err := rateLimitChanges(ctx, changes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.allowed).To(Equal(changes))
+		Expect(result.newSummaries).To(BeEmpty())
+	})
+
+	It("should reject a limit that allows no changes", func() {
+		ctx.ScrapeConfig().Spec.ChangeRateLimits[0].Max = 0
+
+		changes := newChanges("SuccessfulRescale", 3, 0)
+		result, err := rateLimitChanges(ctx, changes)
+		Expect(err).To(HaveOccurred())
+		Expect(result.allowed).To(Equal(changes))
+	})
+})
@@ -44,6 +44,11 @@ func LoadAllPlugins(ctx context.Context) ([]v1.ScrapePluginSpec, error) {
 }
 
 func ReloadAllScrapePlugins(ctx context.Context) ([]v1.ScrapePluginSpec, error) {
+	if ctx.DB() == nil {
+		// scrapers run without a database (e.g. config-db test) have no plugins
+		return nil, nil
+	}
+
 	var plugins []models.ScrapePlugin
 	if err := ctx.DB().Where("deleted_at IS NULL").Find(&plugins).Error; err != nil {
 		return nil, err
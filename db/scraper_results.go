@@ -0,0 +1,96 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/context"
+	"github.com/flanksource/duty/models"
+	"github.com/lib/pq"
+	"github.com/samber/lo"
+)
+
+// GetScraperResults returns the live config items, relationships and config access of a scraper
+// in the shape of scrape results, so they can be compared with the results of a scrape run.
+//
+// The config items carry their id in ConfigID and their first external id in ID.
+// The config access rows carry the external id of their config item and the aliases of their user, group and role.
+func GetScraperResults(ctx context.Context, scraperID string) (v1.FullScrapeResults, error) {
+	var results v1.FullScrapeResults
+
+	var items []models.ConfigItem
+	if err := ctx.DB().Where("scraper_id = ? AND deleted_at IS NULL", scraperID).Find(&items).Error; err != nil {
+		return results, fmt.Errorf("failed to get config items of scraper %s: %w", scraperID, err)
+	}
+
+	for _, item := range items {
+		result := v1.ScrapeResult{
+			ConfigID:    lo.ToPtr(item.ID.String()),
+			ConfigClass: item.ConfigClass,
+			Type:        lo.FromPtr(item.Type),
+			Name:        lo.FromPtr(item.Name),
+			Status:      lo.FromPtr(item.Status),
+			Health:      lo.FromPtr(item.Health),
+			Source:      lo.FromPtr(item.Source),
+			Tags:        v1.JSONStringMap(item.Tags),
+		}
+		if len(item.ExternalID) > 0 {
+			result.ID = item.ExternalID[0]
+			result.Aliases = item.ExternalID[1:]
+		}
+		if item.Labels != nil {
+			result.Labels = v1.JSONStringMap(*item.Labels)
+		}
+		if item.Config != nil {
+			var config map[string]any
+			if err := json.Unmarshal([]byte(*item.Config), &config); err != nil {
+				return results, fmt.Errorf("failed to parse config of %s: %w", item.ID, err)
+			}
+			result.Config = config
+		}
+		results.Configs = append(results.Configs, result)
+	}
+
+	if err := ctx.DB().
+		Where("scraper_id = ? AND deleted_at IS NULL", scraperID).
+		Find(&results.Relationships).Error; err != nil {
+		return results, fmt.Errorf("failed to get relationships of scraper %s: %w", scraperID, err)
+	}
+
+	var access []struct {
+		ID           string
+		ConfigType   string
+		ExternalID   pq.StringArray `gorm:"type:[]text"`
+		UserAliases  pq.StringArray `gorm:"type:[]text"`
+		GroupAliases pq.StringArray `gorm:"type:[]text"`
+		RoleAliases  pq.StringArray `gorm:"type:[]text"`
+	}
+	if err := ctx.DB().Raw(`
+		SELECT config_access.id, config_items.type AS config_type, config_items.external_id,
+			external_users.aliases AS user_aliases, external_groups.aliases AS group_aliases, external_roles.aliases AS role_aliases
+		FROM config_access
+		INNER JOIN config_items ON config_items.id = config_access.config_id
+		LEFT JOIN external_users ON external_users.id = config_access.external_user_id
+		LEFT JOIN external_groups ON external_groups.id = config_access.external_group_id
+		LEFT JOIN external_roles ON external_roles.id = config_access.external_role_id
+		WHERE config_access.scraper_id = ? AND config_access.deleted_at IS NULL`, scraperID).
+		Scan(&access).Error; err != nil {
+		return results, fmt.Errorf("failed to get config access of scraper %s: %w", scraperID, err)
+	}
+
+	for _, a := range access {
+		results.ConfigAccess = append(results.ConfigAccess, v1.ExternalConfigAccess{
+			ID: a.ID,
+			ConfigExternalID: v1.ExternalID{
+				ConfigType: a.ConfigType,
+				ExternalID: lo.FirstOrEmpty(a.ExternalID),
+			},
+			ExternalUserAliases:  a.UserAliases,
+			ExternalGroupAliases: a.GroupAliases,
+			ExternalRoleAliases:  a.RoleAliases,
+		})
+	}
+
+	return results, nil
+}
@@ -304,6 +304,7 @@ func extractChanges(ctx api.ScrapeContext, result *v1.ScrapeResult, ci *models.C
 		ctx.JobHistory().AddError(fmt.Sprintf("error running change mapping transformation: %v", err))
 	}
 
+	result.Changes = changes.AggregateChanges(result.Changes)
 	result.Changes = append(result.Changes, processMoveUpCopyUp(ctx, result, ci)...)
 	result.Changes = append(result.Changes, processCopyMove(ctx, result, ci)...)
 
@@ -349,10 +350,13 @@ func extractChanges(ctx api.ScrapeContext, result *v1.ScrapeResult, ci *models.C
 		}
 
 		change := models.NewConfigChangeFromV1(*result, *changeResult)
-		if fingerprint, err := pkgChanges.Fingerprint(change); err != nil {
-			logger.Errorf("failed to fingerprint change: %v", err)
-		} else if fingerprint != "" {
-			change.Fingerprint = &fingerprint
+		// Rolled up changes are counted by their group, not deduped by fingerprint
+		if changeResult.Aggregate == nil {
+			if fingerprint, err := pkgChanges.Fingerprint(change); err != nil {
+				logger.Errorf("failed to fingerprint change: %v", err)
+			} else if fingerprint != "" {
+				change.Fingerprint = &fingerprint
+			}
 		}
 
 		if change.CreatedBy != nil {
@@ -407,7 +411,9 @@ func extractChanges(ctx api.ScrapeContext, result *v1.ScrapeResult, ci *models.C
 			continue
 		}
 
-		if changeResult.UpdateExisting {
+		if changeResult.Aggregate != nil && mergeAggregatedChange(ctx, change, *changeResult.Aggregate) {
+			updates = append(updates, change)
+		} else if changeResult.UpdateExisting {
 			updates = append(updates, change)
 		} else {
 			newOnes = append(newOnes, change)
@@ -422,6 +428,51 @@ func extractChanges(ctx api.ScrapeContext, result *v1.ScrapeResult, ci *models.C
 	}, nil
 }
 
+// ApplyChangeMappings runs the change mappings, aggregations and exclusions that extractChanges
+// applies on save, without a database, and drops the changes they ignore.
+func ApplyChangeMappings(ctx api.ScrapeContext, results v1.ScrapeResults) error {
+	var errs []string
+	for i := range results {
+		result := &results[i]
+		if len(result.Changes) == 0 {
+			continue
+		}
+
+		var ci *models.ConfigItem
+		if result.ID != "" {
+			var err error
+			if ci, err = NewConfigItemFromResult(ctx, *result); err != nil {
+				return fmt.Errorf("unable to create config item(%s): %w", result, err)
+			}
+		}
+
+		if err := changes.ProcessRules(ctx, result, ci, result.BaseScraper.Transform.Change.Mapping...); err != nil {
+			errs = append(errs, fmt.Sprintf("error running change mapping transformation: %v", err))
+		}
+		result.Changes = changes.AggregateChanges(result.Changes)
+
+		var kept []v1.ChangeResult
+		for _, change := range result.Changes {
+			if change.Action == v1.Ignore {
+				continue
+			}
+
+			if exclude, err := shouldExcludeChange(ctx, result, change); err != nil {
+				errs = append(errs, fmt.Sprintf("error running change exclusion: %v", err))
+			} else if exclude {
+				continue
+			}
+			kept = append(kept, change)
+		}
+		result.Changes = kept
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(lo.Uniq(errs), "; "))
+	}
+	return nil
+}
+
 // validateExistingUsers checks which external user IDs exist in the database
 // Returns a set of existing user IDs for efficient lookup
 func ValidateExistingUsers(ctx api.ScrapeContext, userIDs []string) (map[uuid.UUID]struct{}, error) {
@@ -577,6 +628,12 @@ func saveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) (v1.ScrapeSum
 	for _, w := range extractResult.warnings {
 		summary.AddScrapeWarning(w)
 	}
+	for name, c := range extractResult.collectors {
+		summary.AddCollector(name, c)
+	}
+	for key, value := range extractResult.state {
+		summary.SetState(key, value)
+	}
 	for configType, cs := range extractResult.changeSummary {
 		summary.AddChangeSummary(configType, cs)
 	}
@@ -923,7 +980,35 @@ func saveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) (v1.ScrapeSum
 		newChanges, deduped = dedupChanges(dedupWindow, extractResult.newChanges)
 	}
 
-	for _, c := range newChanges {
+	var (
+		rateLimited        rateLimitResult
+		rateLimitSummaries []*models.ConfigChange
+	)
+	if result, err := rateLimitChanges(ctx, newChanges); err != nil {
+		ctx.JobHistory().AddError(fmt.Sprintf("error rate limiting changes: %v", err))
+	} else {
+		rateLimited = result
+		newChanges = append(rateLimited.allowed, rateLimited.newSummaries...)
+		rateLimitSummaries = rateLimited.newSummaries
+		extractResult.changesToUpdate = append(extractResult.changesToUpdate, rateLimited.updatedSummaries...)
+
+		for configType, changeTypes := range rateLimited.dropped {
+			for changeType, count := range changeTypes {
+				ctx.Counter("config_changes_rate_limited", "scraper_id", ctx.ScraperID(), "change_type", changeType, "config_type", configType).Add(count)
+				summary.AddRateLimited(configType, changeType, count)
+			}
+		}
+	}
+
+	if attributed, err := attributeChanges(ctx, newChanges); err != nil {
+		ctx.JobHistory().AddError(fmt.Sprintf("error attributing changes: %v", err))
+	} else {
+		extractResult.changesToUpdate = append(extractResult.changesToUpdate, attributed...)
+	}
+
+	// the changes that were written, rate limit summaries aside
+	savedChanges := lo.Without(newChanges, rateLimitSummaries...)
+	for _, c := range savedChanges {
 		ctx.Counter("config_changes", "scraper_id", ctx.ScraperID(), "change_type", c.ChangeType, "config_type", c.ConfigType).Add(1)
 		summary.AddChanges(c.ConfigType, 1)
 	}
@@ -933,11 +1018,14 @@ func saveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) (v1.ScrapeSum
 		summary.AddDeduped(c.Change.ConfigType, 1)
 	}
 
+	// the ids of the changes that failed foreign key checks
+	unsaved := map[string]bool{}
 	if err := ctx.DB().CreateInBatches(&newChanges, configItemsBulkInsertSize).Error; err != nil {
 		if !dutydb.IsForeignKeyError(err) {
 			return summary, ctx.Oops().Wrapf(dutydb.ErrorDetails(err), "failed to create config changes")
 		}
 
+		savedChanges = nil
 		for _, c := range newChanges {
 			if err := ctx.DB().Create(&c).Error; err != nil {
 				if !dutydb.IsForeignKeyError(err) {
@@ -946,10 +1034,17 @@ func saveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) (v1.ScrapeSum
 
 				ctx.Errorf("failed to save config change: (config:%s, details:%v changeType:%s, externalChangeID:%s), err=%v", c.ConfigID, c.Details, c.ChangeType, lo.FromPtr(c.ExternalChangeID), err)
 				summary.AddChangeSummary(c.ConfigType, v1.ChangeSummary{ForeignKeyErrors: 1})
+				unsaved[c.ID] = true
+			} else if !lo.Contains(rateLimitSummaries, c) {
+				savedChanges = append(savedChanges, c)
 			}
 		}
 	}
 
+	// The upsert on (config_id, external_change_id) keeps the id of an existing row
+	resolveStoredChangeIDs(ctx, newChanges)
+	cacheAggregatedChanges(ctx, savedChanges)
+
 	// Link artifacts to their config changes
 	linkArtifactsToChanges(ctx, newChanges)
 
@@ -990,6 +1085,8 @@ func saveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) (v1.ScrapeSum
 			return summary, ctx.Oops().With("change_id", changeToUpdate.ID).Wrapf(err, "failed to update config changes")
 		}
 	}
+	cacheAggregatedChanges(ctx, extractResult.changesToUpdate)
+	cacheRateWindows(ctx, rateLimited.windows, unsaved)
 
 	var (
 		relationshipToForm               []relationshipWithOrigin
@@ -1036,6 +1133,11 @@ func saveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) (v1.ScrapeSum
 		return summary, ctx.Oops().Wrapf(err, "failed to form relationships")
 	}
 
+	// Correlation runs once the changes and the relationships they are correlated through are saved
+	if err := correlateChanges(ctx, savedChanges); err != nil {
+		ctx.JobHistory().AddError(fmt.Sprintf("error correlating changes: %v", err))
+	}
+
 	runAnalysisRetentionPass(ctx)
 
 	scraperId := ctx.ScraperID()
@@ -1080,6 +1182,13 @@ func saveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) (v1.ScrapeSum
 		summary.AccessLogs.LastCreatedAt = prev.AccessLogs.LastCreatedAt
 	}
 
+	// Retain scraper state that wasn't updated by this scrape
+	for key, value := range prev.State {
+		if _, ok := summary.State[key]; !ok {
+			summary.SetState(key, value)
+		}
+	}
+
 	if summary.HasUpdates() {
 		ctx.Logger.Debugf("Updates %s", summary)
 	} else {
@@ -1641,6 +1750,8 @@ type extractResult struct {
 	orphanedChanges []v1.ChangeResult
 	fkErrorChanges  []v1.ChangeResult
 	warnings        []v1.Warning
+	collectors      map[string]v1.CollectorSummary
+	state           map[string]any
 
 	transformInput any
 	transformExpr  string
@@ -1697,6 +1808,20 @@ func extractConfigsAndChangesFromResults(ctx api.ScrapeContext, results []v1.Scr
 			extractResult.warnings = append(extractResult.warnings, result.Warnings...)
 		}
 
+		for name, c := range result.Collectors {
+			if extractResult.collectors == nil {
+				extractResult.collectors = make(map[string]v1.CollectorSummary)
+			}
+			extractResult.collectors[name] = extractResult.collectors[name].Merge(c)
+		}
+
+		for key, value := range result.State {
+			if extractResult.state == nil {
+				extractResult.state = make(map[string]any)
+			}
+			extractResult.state[key] = value
+		}
+
 		if result.TransformInput != nil && extractResult.transformInput == nil {
 			extractResult.transformInput = result.TransformInput
 			extractResult.transformExpr = result.TransformExpr
@@ -2049,6 +2174,51 @@ func setConfigPaths(ctx api.ScrapeContext, allConfigs []*models.ConfigItem) erro
 	return nil
 }
 
+// resolveStoredChangeIDs sets the id of the changes with an external change id to the id of
+// their row, as ON CONFLICT (config_id, external_change_id) keeps the id of an existing row.
+func resolveStoredChangeIDs(ctx api.ScrapeContext, changes []*models.ConfigChange) {
+	ids, err := storedChangeIDs(ctx, changes)
+	if err != nil {
+		ctx.Logger.V(2).Infof("failed to resolve the ids of saved changes: %v", err)
+		return
+	}
+
+	for _, c := range changes {
+		if id, ok := ids[storedChangeKey(c)]; ok {
+			c.ID = id
+		}
+	}
+}
+
+// storedChangeIDs returns the ids of the saved changes with the config id and external change id
+// of the changes, keyed by storedChangeKey.
+func storedChangeIDs(ctx api.ScrapeContext, changes []*models.ConfigChange) (map[string]string, error) {
+	external := lo.Filter(changes, func(c *models.ConfigChange, _ int) bool {
+		return c.ConfigID != "" && lo.FromPtr(c.ExternalChangeID) != ""
+	})
+
+	ids := make(map[string]string, len(external))
+	for _, batch := range lo.Chunk(external, configItemsBulkInsertSize) {
+		keys := lo.Map(batch, func(c *models.ConfigChange, _ int) []any { return []any{c.ConfigID, *c.ExternalChangeID} })
+
+		var stored []models.ConfigChange
+		if err := ctx.DB().Select("id", "config_id", "external_change_id").
+			Where("(config_id, external_change_id) IN ?", keys).
+			Find(&stored).Error; err != nil {
+			return nil, err
+		}
+
+		for i := range stored {
+			ids[storedChangeKey(&stored[i])] = stored[i].ID
+		}
+	}
+	return ids, nil
+}
+
+func storedChangeKey(c *models.ConfigChange) string {
+	return c.ConfigID + "/" + lo.FromPtr(c.ExternalChangeID)
+}
+
 func linkArtifactsToChanges(ctx api.ScrapeContext, changes []*models.ConfigChange) {
 	for _, change := range changes {
 		if change.Details == nil {
@@ -2059,16 +2229,7 @@ func linkArtifactsToChanges(ctx api.ScrapeContext, changes []*models.ConfigChang
 			continue
 		}
 
-		// Resolve the actual change ID from the DB (ON CONFLICT may have used an existing row)
 		changeID := change.ID
-		if change.ExternalChangeID != nil && *change.ExternalChangeID != "" {
-			var dbChange models.ConfigChange
-			if err := ctx.DB().Select("id").
-				Where("config_id = ? AND external_change_id = ?", change.ConfigID, *change.ExternalChangeID).
-				First(&dbChange).Error; err == nil {
-				changeID = dbChange.ID
-			}
-		}
 		if changeID == "" {
 			continue
 		}
@@ -17,6 +17,7 @@ func ProcessChangeRetentionRules(ctx job.JobRuntime) error {
 		return err
 	}
 
+	var deletedAnalyses, deletedRelationships int64
 	for _, s := range allScrapers {
 		var spec v1.ScraperSpec
 		if err := json.Unmarshal([]byte(s.Spec), &spec); err != nil {
@@ -33,7 +34,31 @@ func ProcessChangeRetentionRules(ctx job.JobRuntime) error {
 				ctx.History.SuccessCount++
 			}
 		}
+
+		for _, analysisSpec := range spec.Retention.Analysis {
+			deleted, err := scrapers.ProcessAnalysisRetention(ctx.Context, s.ID, analysisSpec)
+			deletedAnalyses += deleted
+			if err != nil {
+				logger.Errorf("Error processing analysis retention for scraper[%s]: %v", s.ID, err)
+				ctx.History.AddError(err.Error())
+			} else {
+				ctx.History.SuccessCount++
+			}
+		}
+
+		for _, relationshipSpec := range spec.Retention.Relationships {
+			deleted, err := scrapers.ProcessRelationshipRetention(ctx.Context, s.ID, relationshipSpec)
+			deletedRelationships += deleted
+			if err != nil {
+				logger.Errorf("Error processing relationship retention for scraper[%s]: %v", s.ID, err)
+				ctx.History.AddError(err.Error())
+			} else {
+				ctx.History.SuccessCount++
+			}
+		}
 	}
 
+	ctx.History.AddDetails("deleted_analyses", deletedAnalyses)
+	ctx.History.AddDetails("deleted_relationships", deletedRelationships)
 	return nil
 }
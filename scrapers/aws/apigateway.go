@@ -0,0 +1,296 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const IncludeAPIGateway = "APIGateway"
+
+var (
+	stageVariableRegexp = regexp.MustCompile(`\$\{stageVariables\.([A-Za-z0-9_]+)\}`)
+	lambdaFunctionARN   = regexp.MustCompile(`arn:aws[a-z-]*:lambda:[a-z0-9-]+:\d{12}:function:[A-Za-z0-9_-]+`)
+)
+
+// apiGatewayRestAPIs scrapes API Gateway REST APIs and their stages.
+func (aws Scraper) apiGatewayRestAPIs(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes(IncludeAPIGateway) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping API Gateway REST APIs")
+
+	client := apigateway.NewFromConfig(*ctx.Session, getEndpointResolver[apigateway.Options](config))
+	region := ctx.Session.Region
+	input := &apigateway.GetRestApisInput{}
+	for {
+		apis, err := client.GetRestApis(ctx, input)
+		if err != nil {
+			results.Errorf(err, "failed to list API Gateway REST APIs")
+			return
+		}
+
+		for _, api := range apis.Items {
+			apiID, apiName := lo.FromPtr(api.Id), lo.FromPtr(api.Name)
+			labels := v1.JSONStringMap(api.Tags)
+			if config.ShouldExclude(v1.AWSAPIGatewayRestAPI, apiName, labels) {
+				continue
+			}
+
+			apiARN := awsAPIGatewayRestAPIARN(region, apiID)
+			*results = append(*results, v1.ScrapeResult{
+				Type:        v1.AWSAPIGatewayRestAPI,
+				CreatedAt:   api.CreatedDate,
+				Description: lo.FromPtr(api.Description),
+				Labels:      labels,
+				BaseScraper: config.BaseScraper,
+				Properties:  []*types.Property{getConsoleLink(region, v1.AWSAPIGatewayRestAPI, apiID, nil)},
+				Config:      api,
+				ConfigClass: "API",
+				Name:        getName(labels, apiName),
+				ID:          apiARN,
+				Aliases:     []string{apiID, awsDNSNameAlias(fmt.Sprintf("%s.execute-api.%s.amazonaws.com", apiID, region))},
+				Parents:     []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: lo.FromPtr(ctx.Caller.Account)}},
+			})
+
+			integrationURIs, err := getRestAPIIntegrationURIs(ctx, client, apiID)
+			if err != nil {
+				results.Errorf(err, "failed to get integrations of API Gateway REST API %s", apiName)
+			}
+
+			stages, err := client.GetStages(ctx, &apigateway.GetStagesInput{RestApiId: api.Id})
+			if err != nil {
+				results.Errorf(err, "failed to get stages of API Gateway REST API %s", apiName)
+				continue
+			}
+			for _, stage := range stages.Item {
+				stageName := lo.FromPtr(stage.StageName)
+				stageLabels := v1.JSONStringMap(stage.Tags)
+				if config.ShouldExclude(v1.AWSAPIGatewayStage, stageName, stageLabels) {
+					continue
+				}
+
+				stageARN := apiARN + "/stages/" + stageName
+				*results = append(*results, v1.ScrapeResult{
+					Type:        v1.AWSAPIGatewayStage,
+					CreatedAt:   stage.CreatedDate,
+					Description: lo.FromPtr(stage.Description),
+					Labels:      stageLabels,
+					BaseScraper: config.BaseScraper,
+					Properties:  []*types.Property{getConsoleLink(region, v1.AWSAPIGatewayStage, stageName, map[string]string{"api": apiID})},
+					Config:      stage,
+					ConfigClass: "APIStage",
+					Name:        fmt.Sprintf("%s/%s", apiName, stageName),
+					ID:          stageARN,
+					Ignore:      []string{"LastUpdatedDate"},
+					Parents:     []v1.ConfigExternalKey{{Type: v1.AWSAPIGatewayRestAPI, ExternalID: apiARN}},
+					RelationshipResults: awsLambdaIntegrationRelationships(integrationURIs, stage.Variables,
+						v1.ExternalID{ExternalID: stageARN, ConfigType: v1.AWSAPIGatewayStage}),
+				})
+			}
+		}
+
+		if apis.Position == nil {
+			return
+		}
+		input.Position = apis.Position
+	}
+}
+
+// getRestAPIIntegrationURIs returns the integration URIs of all the methods of a REST API.
+func getRestAPIIntegrationURIs(ctx *AWSContext, client *apigateway.Client, apiID string) ([]string, error) {
+	var uris []string
+	input := &apigateway.GetResourcesInput{RestApiId: &apiID, Embed: []string{"methods"}}
+	for {
+		resources, err := client.GetResources(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resource := range resources.Items {
+			for _, method := range resource.ResourceMethods {
+				if method.MethodIntegration != nil && method.MethodIntegration.Uri != nil {
+					uris = append(uris, *method.MethodIntegration.Uri)
+				}
+			}
+		}
+
+		if resources.Position == nil {
+			return uris, nil
+		}
+		input.Position = resources.Position
+	}
+}
+
+// apiGatewayV2APIs scrapes API Gateway HTTP and WebSocket APIs and their stages.
+func (aws Scraper) apiGatewayV2APIs(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes(IncludeAPIGateway) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping API Gateway V2 APIs")
+
+	client := apigatewayv2.NewFromConfig(*ctx.Session, getEndpointResolver[apigatewayv2.Options](config))
+	region := ctx.Session.Region
+	input := &apigatewayv2.GetApisInput{}
+	for {
+		apis, err := client.GetApis(ctx, input)
+		if err != nil {
+			results.Errorf(err, "failed to list API Gateway V2 APIs")
+			return
+		}
+
+		for _, api := range apis.Items {
+			apiID, apiName := lo.FromPtr(api.ApiId), lo.FromPtr(api.Name)
+			labels := v1.JSONStringMap(api.Tags)
+			if config.ShouldExclude(v1.AWSAPIGatewayV2API, apiName, labels) {
+				continue
+			}
+
+			apiARN := awsAPIGatewayV2APIARN(region, apiID)
+			aliases := []string{apiID}
+			if name := strings.TrimPrefix(strings.TrimPrefix(lo.FromPtr(api.ApiEndpoint), "https://"), "wss://"); name != "" {
+				aliases = append(aliases, awsDNSNameAlias(name))
+			}
+			*results = append(*results, v1.ScrapeResult{
+				Type:        v1.AWSAPIGatewayV2API,
+				CreatedAt:   api.CreatedDate,
+				Description: lo.FromPtr(api.Description),
+				Labels:      labels,
+				Tags:        v1.JSONStringMap{"protocol": string(api.ProtocolType)},
+				BaseScraper: config.BaseScraper,
+				Properties:  []*types.Property{getConsoleLink(region, v1.AWSAPIGatewayV2API, apiID, nil)},
+				Config:      api,
+				ConfigClass: "API",
+				Name:        getName(labels, apiName),
+				ID:          apiARN,
+				Aliases:     aliases,
+				Parents:     []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: lo.FromPtr(ctx.Caller.Account)}},
+			})
+
+			integrationURIs, err := getV2APIIntegrationURIs(ctx, client, apiID)
+			if err != nil {
+				results.Errorf(err, "failed to get integrations of API Gateway V2 API %s", apiName)
+			}
+
+			stagesInput := &apigatewayv2.GetStagesInput{ApiId: api.ApiId}
+			for {
+				stages, err := client.GetStages(ctx, stagesInput)
+				if err != nil {
+					results.Errorf(err, "failed to get stages of API Gateway V2 API %s", apiName)
+					break
+				}
+
+				for _, stage := range stages.Items {
+					stageName := lo.FromPtr(stage.StageName)
+					stageLabels := v1.JSONStringMap(stage.Tags)
+					if config.ShouldExclude(v1.AWSAPIGatewayV2Stage, stageName, stageLabels) {
+						continue
+					}
+
+					stageARN := apiARN + "/stages/" + stageName
+					*results = append(*results, v1.ScrapeResult{
+						Type:        v1.AWSAPIGatewayV2Stage,
+						CreatedAt:   stage.CreatedDate,
+						Description: lo.FromPtr(stage.Description),
+						Labels:      stageLabels,
+						BaseScraper: config.BaseScraper,
+						Properties:  []*types.Property{getConsoleLink(region, v1.AWSAPIGatewayV2Stage, stageName, map[string]string{"api": apiID})},
+						Config:      stage,
+						ConfigClass: "APIStage",
+						Name:        fmt.Sprintf("%s/%s", apiName, stageName),
+						ID:          stageARN,
+						Ignore:      []string{"LastUpdatedDate"},
+						Parents:     []v1.ConfigExternalKey{{Type: v1.AWSAPIGatewayV2API, ExternalID: apiARN}},
+						RelationshipResults: awsLambdaIntegrationRelationships(integrationURIs, stage.StageVariables,
+							v1.ExternalID{ExternalID: stageARN, ConfigType: v1.AWSAPIGatewayV2Stage}),
+					})
+				}
+
+				if stages.NextToken == nil {
+					break
+				}
+				stagesInput.NextToken = stages.NextToken
+			}
+		}
+
+		if apis.NextToken == nil {
+			return
+		}
+		input.NextToken = apis.NextToken
+	}
+}
+
+// getV2APIIntegrationURIs returns the integration URIs of an HTTP or WebSocket API.
+func getV2APIIntegrationURIs(ctx *AWSContext, client *apigatewayv2.Client, apiID string) ([]string, error) {
+	var uris []string
+	input := &apigatewayv2.GetIntegrationsInput{ApiId: &apiID}
+	for {
+		integrations, err := client.GetIntegrations(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, integration := range integrations.Items {
+			if integration.IntegrationUri != nil {
+				uris = append(uris, *integration.IntegrationUri)
+			}
+		}
+
+		if integrations.NextToken == nil {
+			return uris, nil
+		}
+		input.NextToken = integrations.NextToken
+	}
+}
+
+func awsAPIGatewayRestAPIARN(region, apiID string) string {
+	return fmt.Sprintf("arn:aws:apigateway:%s::/restapis/%s", region, apiID)
+}
+
+func awsAPIGatewayV2APIARN(region, apiID string) string {
+	return fmt.Sprintf("arn:aws:apigateway:%s::/apis/%s", region, apiID)
+}
+
+// awsLambdaFunctionFromIntegrationURI returns the unqualified ARN of the Lambda
+// function invoked by an integration, resolving any stage variables in the URI.
+// Lambda integrations are either the function ARN itself or an invocation path
+// of the form arn:aws:apigateway:<region>:lambda:path/2015-03-31/functions/<function arn>/invocations.
+func awsLambdaFunctionFromIntegrationURI(uri string, stageVariables map[string]string) string {
+	uri = stageVariableRegexp.ReplaceAllStringFunc(uri, func(match string) string {
+		return stageVariables[stageVariableRegexp.FindStringSubmatch(match)[1]]
+	})
+	return lambdaFunctionARN.FindString(uri)
+}
+
+// awsLambdaIntegrationRelationships links the Lambda functions invoked by the
+// integrations of an API to one of its stages.
+func awsLambdaIntegrationRelationships(integrationURIs []string, stageVariables map[string]string, stage v1.ExternalID) v1.RelationshipResults {
+	var functions []string
+	for _, uri := range integrationURIs {
+		if function := awsLambdaFunctionFromIntegrationURI(uri, stageVariables); function != "" {
+			functions = append(functions, function)
+		}
+	}
+	functions = lo.Uniq(functions)
+	slices.Sort(functions)
+
+	var relationships v1.RelationshipResults
+	for _, function := range functions {
+		relationships = append(relationships, v1.RelationshipResult{
+			ConfigExternalID:  v1.ExternalID{ExternalID: function, ConfigType: v1.AWSLambdaFunction},
+			RelatedExternalID: stage,
+			Relationship:      "LambdaAPIGatewayStage",
+		})
+	}
+	return relationships
+}
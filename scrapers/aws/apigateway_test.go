@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"testing"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSLambdaFunctionFromIntegrationURI(t *testing.T) {
+	const function = "arn:aws:lambda:eu-west-1:123456789012:function:orders"
+
+	tests := map[string]string{
+		"arn:aws:apigateway:eu-west-1:lambda:path/2015-03-31/functions/" + function + "/invocations":                                                    function,
+		"arn:aws:apigateway:eu-west-1:lambda:path/2015-03-31/functions/" + function + ":live/invocations":                                               function,
+		"arn:aws:apigateway:eu-west-1:lambda:path/2015-03-31/functions/arn:aws:lambda:eu-west-1:123456789012:function:${stageVariables.fn}/invocations": function,
+		function:                     function,
+		"https://example.com/orders": "",
+		"arn:aws:apigateway:eu-west-1:lambda:path/2015-03-31/functions/arn:aws:lambda:eu-west-1:123456789012:function:${stageVariables.missing}/invocations": "",
+	}
+
+	for uri, want := range tests {
+		assert.Equal(t, want, awsLambdaFunctionFromIntegrationURI(uri, map[string]string{"fn": "orders"}), uri)
+	}
+}
+
+func TestAWSLambdaIntegrationRelationships(t *testing.T) {
+	stage := v1.ExternalID{ExternalID: "arn:aws:apigateway:eu-west-1::/restapis/abc/stages/prod", ConfigType: v1.AWSAPIGatewayStage}
+	rels := awsLambdaIntegrationRelationships([]string{
+		"arn:aws:lambda:eu-west-1:123456789012:function:b",
+		"arn:aws:lambda:eu-west-1:123456789012:function:a",
+		"arn:aws:lambda:eu-west-1:123456789012:function:b:prod",
+		"http://backend.internal",
+	}, nil, stage)
+	require.Len(t, rels, 2)
+
+	assert.Equal(t, "arn:aws:lambda:eu-west-1:123456789012:function:a", rels[0].ConfigExternalID.ExternalID)
+	assert.Equal(t, "arn:aws:lambda:eu-west-1:123456789012:function:b", rels[1].ConfigExternalID.ExternalID)
+	for _, rel := range rels {
+		assert.Equal(t, v1.AWSLambdaFunction, rel.ConfigExternalID.ConfigType)
+		assert.Equal(t, stage, rel.RelatedExternalID)
+		assert.Equal(t, "LambdaAPIGatewayStage", rel.Relationship)
+	}
+}
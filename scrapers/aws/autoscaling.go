@@ -0,0 +1,340 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	asTypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const (
+	IncludeAutoScalingGroup = "AutoScalingGroup"
+	IncludeLaunchTemplate   = "LaunchTemplate"
+)
+
+// launchTemplateVersionHistory is the number of most recent launch template
+// versions kept in the config of a launch template.
+const launchTemplateVersionHistory = 10
+
+// autoScalingActivitiesLookback bounds how far back scaling activities are fetched.
+// AWS retains them for 6 weeks.
+const autoScalingActivitiesLookback = 14 * 24 * time.Hour
+
+var desiredCapacityChange = regexp.MustCompile(`desired capacity from (\d+) to (\d+)`)
+
+func (aws Scraper) autoScalingGroups(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes(IncludeAutoScalingGroup) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping Auto Scaling groups")
+
+	client := autoscaling.NewFromConfig(*ctx.Session, getEndpointResolver[autoscaling.Options](config))
+	elbv2 := elasticloadbalancingv2.NewFromConfig(*ctx.Session, getEndpointResolver[elasticloadbalancingv2.Options](config))
+	region := ctx.Session.Region
+	accountID := lo.FromPtr(ctx.Caller.Account)
+
+	scraped := map[string]bool{}
+	paginator := autoscaling.NewDescribeAutoScalingGroupsPaginator(client, &autoscaling.DescribeAutoScalingGroupsInput{})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			results.Errorf(err, "failed to describe Auto Scaling groups")
+			return
+		}
+
+		for _, group := range output.AutoScalingGroups {
+			groupName := lo.FromPtr(group.AutoScalingGroupName)
+			labels := make(v1.JSONStringMap)
+			for _, tag := range group.Tags {
+				labels[lo.FromPtr(tag.Key)] = lo.FromPtr(tag.Value)
+			}
+			if config.ShouldExclude(v1.AWSAutoScalingGroup, groupName, labels) {
+				continue
+			}
+			scraped[groupName] = true
+
+			groupARN := lo.FromPtr(group.AutoScalingGroupARN)
+			selfExternalID := v1.ExternalID{ExternalID: groupARN, ConfigType: v1.AWSAutoScalingGroup}
+
+			instanceIDs := make([]string, 0, len(group.Instances))
+			for _, instance := range group.Instances {
+				instanceIDs = append(instanceIDs, lo.FromPtr(instance.InstanceId))
+			}
+			relationships := awsAutoScalingGroupInstanceRelationships(groupARN, instanceIDs)
+
+			if launchTemplate := autoScalingGroupLaunchTemplate(group); launchTemplate != nil {
+				relationships = append(relationships, v1.RelationshipResult{
+					ConfigExternalID:  awsLaunchTemplateExternalID(accountID, region, *launchTemplate),
+					RelatedExternalID: selfExternalID,
+					Relationship:      "LaunchTemplateAutoScalingGroup",
+				})
+			}
+
+			for _, loadBalancerName := range group.LoadBalancerNames {
+				relationships = append(relationships, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ExternalID: awsClassicLoadBalancerAlias(accountID, region, loadBalancerName), ConfigType: v1.AWSLoadBalancer},
+					RelatedExternalID: selfExternalID,
+					Relationship:      "LoadBalancerAutoScalingGroup",
+				})
+			}
+
+			// Target groups are not scraped, so the group is related to the load balancers behind them
+			for _, targetGroups := range lo.Chunk(group.TargetGroupARNs, 20) {
+				output, err := elbv2.DescribeTargetGroups(ctx, &elasticloadbalancingv2.DescribeTargetGroupsInput{TargetGroupArns: targetGroups})
+				if err != nil {
+					results.Errorf(err, "failed to describe target groups of Auto Scaling group %s", groupName)
+					break
+				}
+				for _, targetGroup := range output.TargetGroups {
+					for _, loadBalancerARN := range targetGroup.LoadBalancerArns {
+						relationships = append(relationships, v1.RelationshipResult{
+							ConfigExternalID:  v1.ExternalID{ExternalID: loadBalancerARN, ConfigType: v1.AWSLoadBalancerV2},
+							RelatedExternalID: selfExternalID,
+							Relationship:      "LoadBalancerAutoScalingGroup",
+						})
+					}
+				}
+			}
+
+			*results = append(*results, v1.ScrapeResult{
+				Type:        v1.AWSAutoScalingGroup,
+				CreatedAt:   group.CreatedTime,
+				Status:      lo.FromPtr(group.Status),
+				Labels:      labels,
+				Tags:        v1.JSONStringMap{"region": region},
+				BaseScraper: config.BaseScraper,
+				Properties:  []*types.Property{getConsoleLink(region, v1.AWSAutoScalingGroup, groupName, nil)},
+				Config:      group,
+				ConfigClass: "AutoScalingGroup",
+				Name:        getName(labels, groupName),
+				ID:          groupARN,
+				Aliases:     []string{awsAutoScalingGroupAlias(accountID, region, groupName)},
+				Parents:     []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: accountID}},
+				RelationshipResults: lo.UniqBy(relationships, func(r v1.RelationshipResult) string {
+					return r.ConfigExternalID.Key() + r.RelatedExternalID.Key()
+				}),
+			})
+		}
+	}
+
+	if err := aws.autoScalingActivities(ctx, config, client, scraped, results); err != nil {
+		results.Errorf(err, "failed to get Auto Scaling activities")
+	}
+}
+
+// autoScalingActivities records the scaling activities of the scraped Auto Scaling
+// groups, keyed by name, as changes on the groups.
+func (aws Scraper) autoScalingActivities(ctx *AWSContext, config v1.AWS, client *autoscaling.Client, groups map[string]bool, results *v1.ScrapeResults) error {
+	if !config.Includes(IncludeAutoScalingActivities) || len(groups) == 0 {
+		return nil
+	}
+
+	ctx.Logger.V(2).Infof("scraping Auto Scaling activities")
+
+	cutoff := time.Now().Add(-autoScalingActivitiesLookback)
+	accountID := lo.FromPtr(ctx.Caller.Account)
+
+	var changes []v1.ChangeResult
+	paginator := autoscaling.NewDescribeScalingActivitiesPaginator(client, &autoscaling.DescribeScalingActivitiesInput{})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe scaling activities: %w", err)
+		}
+
+		// Activities are returned most recent first
+		var expired bool
+		for _, activity := range output.Activities {
+			if activity.StartTime != nil && activity.StartTime.Before(cutoff) {
+				expired = true
+				break
+			}
+			if !groups[lo.FromPtr(activity.AutoScalingGroupName)] {
+				continue
+			}
+
+			externalID := lo.FromPtr(activity.AutoScalingGroupARN)
+			if externalID == "" {
+				externalID = awsAutoScalingGroupAlias(accountID, ctx.Session.Region, lo.FromPtr(activity.AutoScalingGroupName))
+			}
+			changes = append(changes, autoScalingActivityChange(externalID, activity))
+		}
+
+		if expired {
+			break
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	result := v1.NewScrapeResult(config.BaseScraper)
+	result.Changes = changes
+	*results = append(*results, *result)
+	return nil
+}
+
+// autoScalingActivityChange converts a scaling activity into a Scaling change.
+// Activities are fetched while still in progress, so the change is updated
+// in place once the activity completes.
+func autoScalingActivityChange(groupExternalID string, activity asTypes.Activity) v1.ChangeResult {
+	severity := models.SeverityInfo
+	switch activity.StatusCode {
+	case asTypes.ScalingActivityStatusCodeFailed:
+		severity = models.SeverityHigh
+	case asTypes.ScalingActivityStatusCodeCancelled:
+		severity = models.SeverityLow
+	}
+
+	scale := types.Scale{Dimension: types.ScalingDimensionReplicas}
+	if match := desiredCapacityChange.FindStringSubmatch(lo.FromPtr(activity.Cause)); match != nil {
+		scale.PreviousValue.Desired = match[1]
+		scale.Value.Desired = match[2]
+	}
+
+	summary := lo.FromPtr(activity.Description)
+	if activity.StatusCode != asTypes.ScalingActivityStatusCodeSuccessful {
+		summary = fmt.Sprintf("%s (%s)", summary, activity.StatusCode)
+	}
+
+	return v1.ChangeResult{
+		ExternalChangeID: lo.FromPtr(activity.ActivityId),
+		ConfigType:       v1.AWSAutoScalingGroup,
+		ExternalID:       groupExternalID,
+		ChangeType:       types.ChangeTypeScaling,
+		Summary:          summary,
+		Severity:         string(severity),
+		Source:           SourceAutoScaling,
+		CreatedAt:        activity.StartTime,
+		Details:          v1.ChangeDetailsWithRaw(scale, activity),
+		UpdateExisting:   true,
+	}
+}
+
+// autoScalingGroupLaunchTemplate returns the launch template of a group,
+// which is set either directly or through a mixed instances policy.
+func autoScalingGroupLaunchTemplate(group asTypes.AutoScalingGroup) *asTypes.LaunchTemplateSpecification {
+	if group.LaunchTemplate != nil {
+		return group.LaunchTemplate
+	}
+	if group.MixedInstancesPolicy != nil && group.MixedInstancesPolicy.LaunchTemplate != nil {
+		return group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	return nil
+}
+
+func (aws Scraper) launchTemplates(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes(IncludeLaunchTemplate) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping launch templates")
+
+	region := ctx.Session.Region
+	accountID := lo.FromPtr(ctx.Caller.Account)
+
+	paginator := ec2.NewDescribeLaunchTemplatesPaginator(ctx.EC2, &ec2.DescribeLaunchTemplatesInput{})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			results.Errorf(err, "failed to describe launch templates")
+			return
+		}
+
+		for _, template := range output.LaunchTemplates {
+			templateID, templateName := lo.FromPtr(template.LaunchTemplateId), lo.FromPtr(template.LaunchTemplateName)
+			labels := getLabels(template.Tags)
+			if config.ShouldExclude(v1.AWSEC2LaunchTemplate, templateName, labels) {
+				continue
+			}
+
+			minVersion := max(lo.FromPtr(template.LatestVersionNumber)-launchTemplateVersionHistory+1, 1)
+			versions, err := ctx.EC2.DescribeLaunchTemplateVersions(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+				LaunchTemplateId: template.LaunchTemplateId,
+				MinVersion:       lo.ToPtr(strconv.FormatInt(minVersion, 10)),
+			})
+			if err != nil {
+				results.Errorf(err, "failed to describe versions of launch template %s", templateName)
+				continue
+			}
+
+			*results = append(*results, v1.ScrapeResult{
+				Type:        v1.AWSEC2LaunchTemplate,
+				CreatedAt:   template.CreateTime,
+				Labels:      labels,
+				Tags:        v1.JSONStringMap{"region": region},
+				BaseScraper: config.BaseScraper,
+				Properties:  []*types.Property{getConsoleLink(region, v1.AWSEC2LaunchTemplate, templateID, nil)},
+				Config: map[string]any{
+					"LaunchTemplate": template,
+					"Versions":       withoutUserData(versions.LaunchTemplateVersions),
+				},
+				ConfigClass: "LaunchTemplate",
+				Name:        getName(labels, templateName),
+				ID:          templateID,
+				Aliases:     []string{awsLaunchTemplateAlias(accountID, region, templateName)},
+				Parents:     []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: accountID}},
+			})
+		}
+	}
+}
+
+// withoutUserData drops the user data of launch template versions, as the
+// bootstrap scripts it holds often embed credentials.
+func withoutUserData(versions []ec2Types.LaunchTemplateVersion) []ec2Types.LaunchTemplateVersion {
+	stripped := make([]ec2Types.LaunchTemplateVersion, 0, len(versions))
+	for _, version := range versions {
+		if version.LaunchTemplateData != nil {
+			data := *version.LaunchTemplateData
+			data.UserData = nil
+			version.LaunchTemplateData = &data
+		}
+		stripped = append(stripped, version)
+	}
+	return stripped
+}
+
+func awsAutoScalingGroupInstanceRelationships(groupARN string, instanceIDs []string) v1.RelationshipResults {
+	parentExternalID := v1.ExternalID{ExternalID: groupARN, ConfigType: v1.AWSAutoScalingGroup}
+	relationships := make(v1.RelationshipResults, 0, len(instanceIDs))
+	for _, instanceID := range instanceIDs {
+		if instanceID == "" {
+			continue
+		}
+		relationships = append(relationships, v1.RelationshipResult{
+			ConfigExternalID:  parentExternalID,
+			RelatedExternalID: v1.ExternalID{ExternalID: instanceID, ConfigType: v1.AWSEC2Instance},
+			Relationship:      "AutoScalingGroupInstance",
+		})
+	}
+	return relationships
+}
+
+// awsLaunchTemplateExternalID resolves a launch template referenced either by id or by name.
+func awsLaunchTemplateExternalID(accountID, region string, spec asTypes.LaunchTemplateSpecification) v1.ExternalID {
+	if id := lo.FromPtr(spec.LaunchTemplateId); id != "" {
+		return v1.ExternalID{ExternalID: id, ConfigType: v1.AWSEC2LaunchTemplate}
+	}
+	return v1.ExternalID{ExternalID: awsLaunchTemplateAlias(accountID, region, lo.FromPtr(spec.LaunchTemplateName)), ConfigType: v1.AWSEC2LaunchTemplate}
+}
+
+func awsAutoScalingGroupAlias(accountID, region, groupName string) string {
+	return fmt.Sprintf("aws://autoscaling-group/%s/%s/%s", accountID, region, groupName)
+}
+
+func awsLaunchTemplateAlias(accountID, region, templateName string) string {
+	return fmt.Sprintf("aws://launch-template/%s/%s/%s", accountID, region, templateName)
+}
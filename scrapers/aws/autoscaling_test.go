@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	asTypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func TestAWSAutoScalingGroupInstanceRelationshipsDirection(t *testing.T) {
+	groupARN := "arn:aws:autoscaling:eu-west-1:123456789012:autoScalingGroup:uuid:autoScalingGroupName/web"
+	rels := awsAutoScalingGroupInstanceRelationships(groupARN, []string{"i-1", ""})
+	require.Len(t, rels, 1)
+
+	assert.Equal(t, groupARN, rels[0].ConfigExternalID.ExternalID)
+	assert.Equal(t, v1.AWSAutoScalingGroup, rels[0].ConfigExternalID.ConfigType)
+	assert.Equal(t, "i-1", rels[0].RelatedExternalID.ExternalID)
+	assert.Equal(t, v1.AWSEC2Instance, rels[0].RelatedExternalID.ConfigType)
+	assert.Equal(t, "AutoScalingGroupInstance", rels[0].Relationship)
+}
+
+func TestAWSLaunchTemplateExternalID(t *testing.T) {
+	byID := awsLaunchTemplateExternalID("123456789012", "eu-west-1", asTypes.LaunchTemplateSpecification{
+		LaunchTemplateId:   lo.ToPtr("lt-0abc"),
+		LaunchTemplateName: lo.ToPtr("web"),
+	})
+	assert.Equal(t, v1.ExternalID{ExternalID: "lt-0abc", ConfigType: v1.AWSEC2LaunchTemplate}, byID)
+
+	byName := awsLaunchTemplateExternalID("123456789012", "eu-west-1", asTypes.LaunchTemplateSpecification{
+		LaunchTemplateName: lo.ToPtr("web"),
+	})
+	assert.Equal(t, v1.ExternalID{ExternalID: "aws://launch-template/123456789012/eu-west-1/web", ConfigType: v1.AWSEC2LaunchTemplate}, byName)
+}
+
+func TestLaunchTemplateVersionsWithoutUserData(t *testing.T) {
+	data := &ec2Types.ResponseLaunchTemplateData{
+		ImageId:  lo.ToPtr("ami-1"),
+		UserData: lo.ToPtr("ZXhwb3J0IFRPS0VOPXNlY3JldA=="),
+	}
+	versions := withoutUserData([]ec2Types.LaunchTemplateVersion{
+		{VersionNumber: lo.ToPtr(int64(1)), LaunchTemplateData: data},
+		{VersionNumber: lo.ToPtr(int64(2))},
+	})
+	require.Len(t, versions, 2)
+
+	assert.Nil(t, versions[0].LaunchTemplateData.UserData)
+	assert.Equal(t, "ami-1", lo.FromPtr(versions[0].LaunchTemplateData.ImageId))
+	assert.NotNil(t, data.UserData, "the described versions are not modified")
+	assert.Nil(t, versions[1].LaunchTemplateData)
+}
+
+func TestAutoScalingActivityChange(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	activity := asTypes.Activity{
+		ActivityId:  lo.ToPtr("act-1"),
+		Description: lo.ToPtr("Launching a new EC2 instance: i-1"),
+		Cause:       lo.ToPtr("At 2025-01-02T03:04:00Z a user request update of AutoScalingGroup constraints to min: 1, max: 5, desired: 3 changing the desired capacity from 2 to 3."),
+		StartTime:   &start,
+		StatusCode:  asTypes.ScalingActivityStatusCodeSuccessful,
+	}
+
+	change := autoScalingActivityChange("arn:asg", activity)
+	assert.Equal(t, "act-1", change.ExternalChangeID)
+	assert.Equal(t, "arn:asg", change.ExternalID)
+	assert.Equal(t, v1.AWSAutoScalingGroup, change.ConfigType)
+	assert.Equal(t, types.ChangeTypeScaling, change.ChangeType)
+	assert.Equal(t, "Launching a new EC2 instance: i-1", change.Summary)
+	assert.Equal(t, string(models.SeverityInfo), change.Severity)
+	assert.Equal(t, SourceAutoScaling, change.Source)
+	assert.Equal(t, "Scale/v1", change.Details["kind"])
+	assert.Equal(t, map[string]any{"kind": "Dimension/v1", "desired": "2"}, change.Details["previous_value"])
+	assert.Equal(t, map[string]any{"kind": "Dimension/v1", "desired": "3"}, change.Details["value"])
+
+	activity.StatusCode = asTypes.ScalingActivityStatusCodeFailed
+	change = autoScalingActivityChange("arn:asg", activity)
+	assert.Equal(t, string(models.SeverityHigh), change.Severity)
+	assert.Equal(t, "Launching a new EC2 instance: i-1 (Failed)", change.Summary)
+}
@@ -10,14 +10,20 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/gabs/v2"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/backup"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
 	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
 	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
@@ -30,23 +36,27 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	sqsTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go-v2/service/support"
+	"github.com/aws/aws-sdk-go-v2/service/wafv2"
 	"github.com/flanksource/commons/logger"
 	"github.com/flanksource/duty/models"
 	"github.com/flanksource/duty/types"
 	"github.com/flanksource/is-healthy/pkg/health"
 	"github.com/samber/lo"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
@@ -71,15 +81,17 @@ type AWSContext struct {
 }
 
 const (
-	IncludeRDSEvents  = "RDSEvents"
-	IncludeRDSBackups = "RDSBackups"
+	IncludeRDSEvents             = "RDSEvents"
+	IncludeRDSBackups            = "RDSBackups"
+	IncludeAutoScalingActivities = "AutoScalingActivities"
 )
 
 // Config changes sources
 const (
-	SourceRDSEvents  = "RDS Events"
-	SourceRDSBackups = "RDS Backups"
-	SourceAWSBackup  = "AWS Backup"
+	SourceRDSEvents   = "RDS Events"
+	SourceRDSBackups  = "RDS Backups"
+	SourceAWSBackup   = "AWS Backup"
+	SourceAutoScaling = "AWS AutoScaling"
 )
 
 func getLabels(tags []ec2Types.Tag) v1.JSONStringMap {
@@ -142,6 +154,22 @@ func getEndpointResolver[T any](awsConfig v1.AWS) func(o *T) {
 			opts.BaseEndpoint = val
 		case *cloudtrail.Options:
 			opts.BaseEndpoint = val
+		case *dynamodb.Options:
+			opts.BaseEndpoint = val
+		case *kms.Options:
+			opts.BaseEndpoint = val
+		case *secretsmanager.Options:
+			opts.BaseEndpoint = val
+		case *cloudfront.Options:
+			opts.BaseEndpoint = val
+		case *apigateway.Options:
+			opts.BaseEndpoint = val
+		case *apigatewayv2.Options:
+			opts.BaseEndpoint = val
+		case *wafv2.Options:
+			opts.BaseEndpoint = val
+		case *autoscaling.Options:
+			opts.BaseEndpoint = val
 		default:
 			logger.Errorf("unsupported type for resolver endpoint: %T", o)
 		}
@@ -1752,6 +1780,9 @@ func (aws Scraper) dnsZones(ctx *AWSContext, config v1.AWS, results *v1.ScrapeRe
 		if config.ShouldExclude(v1.AWSZone, lo.FromPtr(zone.Name), nil) {
 			continue
 		}
+		zoneID := strings.ReplaceAll(*zone.Id, "/hostedzone/", "")
+		zoneExternalID := v1.ExternalID{ExternalID: zoneID, ConfigType: v1.AWSZone}
+		var relationships v1.RelationshipResults
 		var recordSets []map[string]interface{}
 		records, err := Route53.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
 			HostedZoneId: zone.Id,
@@ -1765,6 +1796,7 @@ func (aws Scraper) dnsZones(ctx *AWSContext, config v1.AWS, results *v1.ScrapeRe
 				if record.AliasTarget != nil {
 					comments = append(comments, fmt.Sprintf("AliasTarget=%s.%s", lo.FromPtrOr(record.AliasTarget.DNSName, ""),
 						lo.FromPtrOr(record.AliasTarget.HostedZoneId, "")))
+					relationships = append(relationships, awsDNSTargetRelationships(lo.FromPtr(record.AliasTarget.DNSName), zoneExternalID, "HostedZone")...)
 				}
 				if record.Failover != "" {
 					comments = append(comments, fmt.Sprintf("Failover=%s", strings.Join(
@@ -1811,6 +1843,9 @@ func (aws Scraper) dnsZones(ctx *AWSContext, config v1.AWS, results *v1.ScrapeRe
 				if record.ResourceRecords != nil {
 					for _, rr := range record.ResourceRecords {
 						values = append(values, *rr.Value)
+						if record.Type == r53types.RRTypeCname {
+							relationships = append(relationships, awsDNSTargetRelationships(*rr.Value, zoneExternalID, "HostedZone")...)
+						}
 					}
 				}
 
@@ -1846,7 +1881,6 @@ func (aws Scraper) dnsZones(ctx *AWSContext, config v1.AWS, results *v1.ScrapeRe
 		})
 
 		labels := make(map[string]string)
-		zoneID := strings.ReplaceAll(*zone.Id, "/hostedzone/", "")
 		*results = append(*results, v1.ScrapeResult{
 			Type:        v1.AWSZone,
 			BaseScraper: config.BaseScraper,
@@ -1869,6 +1903,9 @@ func (aws Scraper) dnsZones(ctx *AWSContext, config v1.AWS, results *v1.ScrapeRe
 			},
 			ID:      zoneID,
 			Parents: []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: lo.FromPtr(ctx.Caller.Account)}},
+			RelationshipResults: lo.UniqBy(relationships, func(r v1.RelationshipResult) string {
+				return r.ConfigExternalID.Key() + r.Relationship
+			}),
 		})
 	}
 }
@@ -1927,26 +1964,31 @@ func (aws Scraper) loadBalancers(ctx *AWSContext, config v1.AWS, results *v1.Scr
 			continue
 		}
 
+		aliases := []string{
+			"AWSELB/" + arn,
+			awsClassicLoadBalancerAlias(lo.FromPtr(ctx.Caller.Account), region, lo.FromPtr(lb.LoadBalancerName)),
+			awsClassicLoadBalancerCanonicalZoneAlias(lo.FromPtr(ctx.Caller.Account), region, lo.FromPtr(lb.CanonicalHostedZoneName)),
+		}
+		if name := lo.FromPtr(lb.DNSName); name != "" {
+			aliases = append(aliases, awsDNSNameAlias(name))
+		}
+
 		labels := make(map[string]string)
 		tags := v1.Tags{}
 		tags.Append("zone", az)
 		tags.Append("region", region)
 		*results = append(*results, v1.ScrapeResult{
-			Type:        v1.AWSLoadBalancer,
-			CreatedAt:   lb.CreatedTime,
-			Ignore:      []string{"createdTime"},
-			BaseScraper: config.BaseScraper,
-			Properties:  []*types.Property{getConsoleLink(ctx.Session.Region, v1.AWSLoadBalancer, lo.FromPtr(lb.LoadBalancerName), nil)},
-			Config:      lb,
-			ConfigClass: "LoadBalancer",
-			Name:        *lb.LoadBalancerName,
-			Labels:      labels,
-			Tags:        tags.AsMap(),
-			Aliases: []string{
-				"AWSELB/" + arn,
-				awsClassicLoadBalancerAlias(lo.FromPtr(ctx.Caller.Account), region, lo.FromPtr(lb.LoadBalancerName)),
-				awsClassicLoadBalancerCanonicalZoneAlias(lo.FromPtr(ctx.Caller.Account), region, lo.FromPtr(lb.CanonicalHostedZoneName)),
-			},
+			Type:                v1.AWSLoadBalancer,
+			CreatedAt:           lb.CreatedTime,
+			Ignore:              []string{"createdTime"},
+			BaseScraper:         config.BaseScraper,
+			Properties:          []*types.Property{getConsoleLink(ctx.Session.Region, v1.AWSLoadBalancer, lo.FromPtr(lb.LoadBalancerName), nil)},
+			Config:              lb,
+			ConfigClass:         "LoadBalancer",
+			Name:                *lb.LoadBalancerName,
+			Labels:              labels,
+			Tags:                tags.AsMap(),
+			Aliases:             aliases,
 			ID:                  arn,
 			Parents:             []v1.ConfigExternalKey{{Type: v1.AWSEC2VPC, ExternalID: lo.FromPtr(lb.VPCId)}},
 			RelationshipResults: relationships,
@@ -1992,6 +2034,10 @@ func (aws Scraper) loadBalancers(ctx *AWSContext, config v1.AWS, results *v1.Scr
 			continue
 		}
 		labels := make(map[string]string)
+		aliases := []string{"AWSELB/" + *lb.LoadBalancerArn}
+		if name := lo.FromPtr(lb.DNSName); name != "" {
+			aliases = append(aliases, awsDNSNameAlias(name))
+		}
 
 		*results = append(*results, v1.ScrapeResult{
 			Type:                v1.AWSLoadBalancerV2,
@@ -2003,7 +2049,7 @@ func (aws Scraper) loadBalancers(ctx *AWSContext, config v1.AWS, results *v1.Scr
 			Config:              lb,
 			ConfigClass:         "LoadBalancer",
 			Name:                *lb.LoadBalancerName,
-			Aliases:             []string{"AWSELB/" + *lb.LoadBalancerArn},
+			Aliases:             aliases,
 			ID:                  *lb.LoadBalancerArn,
 			Labels:              labels,
 			Parents:             []v1.ConfigExternalKey{{Type: v1.AWSEC2VPC, ExternalID: lo.FromPtr(lb.VpcId)}},
@@ -2373,124 +2419,118 @@ func (aws Scraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 	allResults := v1.ScrapeResults{}
 
 	for _, awsConfig := range ctx.ScrapeConfig().Spec.AWS {
-		results := &v1.ScrapeResults{}
-		var totalResults int
+		allResults = append(allResults, aws.scrape(ctx, awsConfig)...)
+	}
 
-		if len(awsConfig.Regions) == 0 {
-			// Use an empty region and the sdk picks the default region
-			awsConfig.Regions = []string{""}
-		}
+	return allResults
+}
 
-		for _, region := range awsConfig.Regions {
+// scrape runs every region, and the global collectors, concurrently through a
+// pool bounded by maxConcurrency.
+func (aws Scraper) scrape(ctx api.ScrapeContext, awsConfig v1.AWS) v1.ScrapeResults {
+	results := &v1.ScrapeResults{}
+	summaries := make(map[string]v1.CollectorSummary)
+
+	if len(awsConfig.Regions) == 0 {
+		// Use an empty region and the sdk picks the default region
+		awsConfig.Regions = []string{""}
+	}
+
+	pool := semaphore.NewWeighted(int64(getMaxConcurrency(ctx, awsConfig)))
+
+	regionRuns := make([][]collectorRun, len(awsConfig.Regions))
+	regionErrs := make([]error, len(awsConfig.Regions))
+	var wg sync.WaitGroup
+	for i, region := range awsConfig.Regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 			awsCtx, err := aws.getContext(ctx, awsConfig, region)
 			if err != nil {
-				results.Errorf(err, "failed to create AWS context")
-				allResults = append(allResults, *results...)
-				continue
+				regionErrs[i] = err
+				return
 			}
 
 			ctx.Logger.V(1).Infof("scraping %s", awsCtx)
-			aws.cloudformationStacks(awsCtx, awsConfig, results)
-			aws.ecsClusters(awsCtx, awsConfig, results)
-			aws.ecsTaskDefinitions(awsCtx, awsConfig, results)
-			aws.elastiCache(awsCtx, awsConfig, results)
-			aws.lambdaFunctions(awsCtx, awsConfig, results)
-			aws.snsTopics(awsCtx, awsConfig, results)
-			aws.sqs(awsCtx, awsConfig, results)
-			aws.subnets(awsCtx, awsConfig, results)
-			aws.instances(awsCtx, awsConfig, results)
-			aws.vpcs(awsCtx, awsConfig, results)
-			aws.securityGroups(awsCtx, awsConfig, results)
-			aws.routes(awsCtx, awsConfig, results)
-			aws.dhcp(awsCtx, awsConfig, results)
-			aws.eksClusters(awsCtx, awsConfig, results)
-			aws.ebs(awsCtx, awsConfig, results)
-			aws.efs(awsCtx, awsConfig, results)
-			aws.rds(awsCtx, awsConfig, results)
-			aws.config(awsCtx, awsConfig, results)
-			aws.loadBalancers(awsCtx, awsConfig, results)
-			aws.availabilityZones(awsCtx, awsConfig, results)
-			aws.containerImages(awsCtx, awsConfig, results)
-			aws.cloudtrail(awsCtx, awsConfig, results)
-			aws.awsBackups(awsCtx, awsConfig, results)
-			// We are querying half a million amis, need to optimize for this
-			// aws.ami(awsCtx, awsConfig, results)
-
-			ctx.Logger.V(2).Infof("scraped %d results from region %s", len(*results)-totalResults, region)
-		}
-
-		awsCtx, err := aws.getContext(ctx, awsConfig, "us-east-1")
-		if err != nil {
-			results.Errorf(err, "failed to create AWS context")
-			allResults = append(allResults, *results...)
+			regionRuns[i] = aws.scrapeRegion(awsCtx, awsConfig, pool, region)
+		}()
+	}
+
+	awsCtx, globalErr := aws.getContext(ctx, awsConfig, "us-east-1")
+	var globalRuns []collectorRun
+	if globalErr == nil {
+		globalRuns = runCollectors(awsCtx, awsConfig, pool, "global", aws.globalCollectors())
+	}
+	wg.Wait()
+
+	for i, region := range awsConfig.Regions {
+		if regionErrs[i] != nil {
+			results.Errorf(regionErrs[i], "failed to create AWS context")
 			continue
 		}
 
-		aws.account(awsCtx, awsConfig, results)
-		aws.users(awsCtx, awsConfig, results)
-		aws.iamRoles(awsCtx, awsConfig, results)
-		aws.iamProfiles(awsCtx, awsConfig, results)
-		aws.iamGroups(awsCtx, awsConfig, results)
-		aws.iamOIDCProviders(awsCtx, awsConfig, results)
-		aws.iamSAMLProviders(awsCtx, awsConfig, results)
-		aws.dnsZones(awsCtx, awsConfig, results)
-		aws.trustedAdvisor(awsCtx, awsConfig, results)
-		aws.s3Buckets(awsCtx, awsConfig, results)
+		totalResults := len(*results)
+		mergeCollectorRuns(results, summaries, regionRuns[i])
+		ctx.Logger.V(2).Infof("scraped %d results from region %s", len(*results)-totalResults, region)
+	}
 
-		for i, r := range *results {
+	if globalErr != nil {
+		results.Errorf(globalErr, "failed to create AWS context")
+		return append(*results, v1.ScrapeResult{BaseScraper: awsConfig.BaseScraper, Collectors: summaries})
+	}
+	mergeCollectorRuns(results, summaries, globalRuns)
 
-			rh := health.GetHealthByConfigType(r.Type, r.ConfigMap(), r.Status)
+	for i, r := range *results {
 
-			if rh.Status != "" {
-				(*results)[i].Status = string(rh.Status)
-			}
+		rh := health.GetHealthByConfigType(r.Type, r.ConfigMap(), r.Status)
 
-			(*results)[i].Health = models.Health(rh.Health)
-			(*results)[i].Ready = rh.Ready
-			(*results)[i].Description = rh.Message
+		if rh.Status != "" {
+			(*results)[i].Status = string(rh.Status)
+		}
 
-			if lo.Contains([]string{v1.AWSRegion, v1.AWSAvailabilityZoneID}, r.Type) {
-				// We do not need to add tags to these resources.
-				// They are global resources.
-				continue
-			}
+		(*results)[i].Health = models.Health(rh.Health)
+		(*results)[i].Ready = rh.Ready
+		(*results)[i].Description = rh.Message
 
-			if stack, ok := r.Labels["aws:cloudformation:stack-id"]; ok {
-				if len(r.Parents) != 0 {
-					// the default parent should be moved to soft relationship
-					defaultParent := r.Parents[0]
-					(*results)[i].RelationshipResults = append((*results)[i].RelationshipResults, v1.RelationshipResult{
-						ConfigExternalID:  v1.ExternalID{ConfigType: defaultParent.Type, ExternalID: defaultParent.ExternalID},
-						RelatedExternalID: v1.ExternalID{ConfigType: r.Type, ExternalID: r.ID},
-					})
-				}
+		if lo.Contains([]string{v1.AWSRegion, v1.AWSAvailabilityZoneID}, r.Type) {
+			// We do not need to add tags to these resources.
+			// They are global resources.
+			continue
+		}
 
-				(*results)[i].Parents = append([]v1.ConfigExternalKey{{
-					Type:       v1.AWSCloudFormationStack,
-					ExternalID: stack,
-				}}, (*results)[i].Parents...)
+		if stack, ok := r.Labels["aws:cloudformation:stack-id"]; ok {
+			if len(r.Parents) != 0 {
+				// the default parent should be moved to soft relationship
+				defaultParent := r.Parents[0]
+				(*results)[i].RelationshipResults = append((*results)[i].RelationshipResults, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ConfigType: defaultParent.Type, ExternalID: defaultParent.ExternalID},
+					RelatedExternalID: v1.ExternalID{ConfigType: r.Type, ExternalID: r.ID},
+				})
 			}
 
+			(*results)[i].Parents = append([]v1.ConfigExternalKey{{
+				Type:       v1.AWSCloudFormationStack,
+				ExternalID: stack,
+			}}, (*results)[i].Parents...)
+		}
+
+		if (*results)[i].Tags == nil {
+			(*results)[i].Tags = v1.JSONStringMap{}
+		}
+		(*results)[i].Tags["account"] = lo.FromPtr(awsCtx.Caller.Account)
+
+		for _, t := range awsConfig.Tags {
 			if (*results)[i].Tags == nil {
 				(*results)[i].Tags = v1.JSONStringMap{}
 			}
-			(*results)[i].Tags["account"] = lo.FromPtr(awsCtx.Caller.Account)
-
-			for _, t := range awsConfig.Tags {
-				if (*results)[i].Tags == nil {
-					(*results)[i].Tags = v1.JSONStringMap{}
-				}
-				(*results)[i].Tags[t.Name] = t.Value
-			}
-
-			delete((*results)[i].Labels, "name")
-			delete((*results)[i].Labels, "Name")
+			(*results)[i].Tags[t.Name] = t.Value
 		}
 
-		allResults = append(allResults, *results...)
+		delete((*results)[i].Labels, "name")
+		delete((*results)[i].Labels, "Name")
 	}
 
-	return allResults
+	return append(*results, v1.ScrapeResult{BaseScraper: awsConfig.BaseScraper, Collectors: summaries})
 }
 
 func getConfigTypeById(id string) string {
@@ -2583,6 +2623,30 @@ func getConsoleLink(region, resourceType, resourceID string, opt map[string]stri
 		url = fmt.Sprintf("https://%s.console.aws.amazon.com/vpcconsole/home?region=%s#DhcpOptionsDetails:DhcpOptionsId=%s", region, region, resourceID)
 	case v1.AWSZone:
 		url = fmt.Sprintf("https://%s.console.aws.amazon.com/route53/v2/hostedzones?region=%s#ListRecordSets/%s", region, region, resourceID)
+	case v1.AWSDynamoDBTable:
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/dynamodbv2/home?region=%s#table?name=%s", region, region, resourceID)
+	case v1.AWSKMSKey:
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/kms/home?region=%s#/kms/keys/%s", region, region, resourceID)
+	case v1.AWSSecretsManagerSecret:
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/secretsmanager/secret?name=%s&region=%s", region, resourceID, region)
+	case v1.AWSCloudFrontDistribution:
+		url = fmt.Sprintf("https://console.aws.amazon.com/cloudfront/v4/home#/distributions/%s", resourceID)
+	case v1.AWSAPIGatewayRestAPI:
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/apigateway/main/apis/%s/resources?api=%s&region=%s", region, resourceID, resourceID, region)
+	case v1.AWSAPIGatewayStage:
+		api := opt["api"]
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/apigateway/main/apis/%s/stages?api=%s&stage=%s&region=%s", region, api, api, resourceID, region)
+	case v1.AWSAPIGatewayV2API:
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/apigateway/main/api-detail?api=%s&region=%s", region, resourceID, region)
+	case v1.AWSAPIGatewayV2Stage:
+		api := opt["api"]
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/apigateway/main/develop/stages?api=%s&stage=%s&region=%s", region, api, resourceID, region)
+	case v1.AWSAutoScalingGroup:
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/ec2/home?region=%s#AutoScalingGroupDetails:id=%s;view=details", region, region, resourceID)
+	case v1.AWSEC2LaunchTemplate:
+		url = fmt.Sprintf("https://%s.console.aws.amazon.com/ec2/home?region=%s#LaunchTemplateDetails:launchTemplateId=%s", region, region, resourceID)
+	case v1.AWSWAFv2WebACL:
+		url = fmt.Sprintf("https://console.aws.amazon.com/wafv2/homev2/web-acl/%s/%s/overview?region=%s", resourceID, opt["id"], region)
 
 	case v1.AWSRegion, v1.AWSAvailabilityZone, v1.AWSAvailabilityZoneID:
 		// Not applicable
@@ -2695,3 +2759,60 @@ func awsClassicLoadBalancerCanonicalZoneAlias(accountID, region, canonicalHosted
 	}
 	return fmt.Sprintf("aws://classic-load-balancer-canonical-zone/%s/%s/%s", accountID, region, canonicalHostedZoneName)
 }
+
+// awsDNSNameAlias is the alias of a resource reachable on an AWS assigned DNS name
+// (load balancers, CloudFront distributions, API Gateway APIs), allowing Route53
+// records and CloudFront origins to be resolved to the resource they point to.
+func awsDNSNameAlias(dnsName string) string {
+	dnsName = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(dnsName), "."), "dualstack.")
+	if dnsName == "" {
+		return ""
+	}
+	return fmt.Sprintf("aws://dns-name/%s", dnsName)
+}
+
+var s3BucketEndpointRegexp = regexp.MustCompile(`^(.+)\.s3(?:-website)?(?:[.-][a-z0-9-]+)?\.amazonaws\.com$`)
+
+// awsDNSTargetRelationships links the AWS resources that serve the given DNS name
+// to a resource that points at it (a Route53 record or a CloudFront origin).
+// Load balancer and API Gateway hostnames do not reveal the exact type, so a
+// relationship is emitted for each candidate type and the unmatched one is dropped.
+func awsDNSTargetRelationships(dnsName string, related v1.ExternalID, relatedName string) v1.RelationshipResults {
+	dnsName = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(dnsName), "."), "dualstack.")
+
+	type target struct {
+		externalID string
+		configType string
+		name       string
+	}
+
+	var targets []target
+	switch {
+	case strings.HasSuffix(dnsName, ".cloudfront.net"):
+		targets = append(targets, target{awsDNSNameAlias(dnsName), v1.AWSCloudFrontDistribution, "CloudFrontDistribution"})
+	case strings.HasSuffix(dnsName, ".amazonaws.com") && strings.Contains(dnsName, ".elb."):
+		targets = append(targets,
+			target{awsDNSNameAlias(dnsName), v1.AWSLoadBalancer, "LoadBalancer"},
+			target{awsDNSNameAlias(dnsName), v1.AWSLoadBalancerV2, "LoadBalancer"},
+		)
+	case strings.HasSuffix(dnsName, ".amazonaws.com") && strings.Contains(dnsName, ".execute-api."):
+		targets = append(targets,
+			target{awsDNSNameAlias(dnsName), v1.AWSAPIGatewayRestAPI, "APIGateway"},
+			target{awsDNSNameAlias(dnsName), v1.AWSAPIGatewayV2API, "APIGateway"},
+		)
+	default:
+		if match := s3BucketEndpointRegexp.FindStringSubmatch(dnsName); match != nil {
+			targets = append(targets, target{match[1], v1.AWSS3Bucket, "S3Bucket"})
+		}
+	}
+
+	var relationships v1.RelationshipResults
+	for _, t := range targets {
+		relationships = append(relationships, v1.RelationshipResult{
+			ConfigExternalID:  v1.ExternalID{ExternalID: t.externalID, ConfigType: t.configType},
+			RelatedExternalID: related,
+			Relationship:      t.name + relatedName,
+		})
+	}
+	return relationships
+}
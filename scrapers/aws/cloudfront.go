@@ -0,0 +1,107 @@
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const IncludeCloudFront = "CloudFront"
+
+// cloudFrontDistributions scrapes CloudFront distributions.
+// CloudFront is a global service and is only scraped from us-east-1.
+func (aws Scraper) cloudFrontDistributions(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes(IncludeCloudFront) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping CloudFront distributions")
+
+	client := cloudfront.NewFromConfig(*ctx.Session, getEndpointResolver[cloudfront.Options](config))
+	input := &cloudfront.ListDistributionsInput{}
+	for {
+		output, err := client.ListDistributions(ctx, input)
+		if err != nil {
+			results.Errorf(err, "failed to list CloudFront distributions")
+			return
+		}
+		if output.DistributionList == nil {
+			return
+		}
+
+		for _, distribution := range output.DistributionList.Items {
+			distributionID := lo.FromPtr(distribution.Id)
+			var cnames []string
+			if distribution.Aliases != nil {
+				cnames = distribution.Aliases.Items
+			}
+			name := lo.CoalesceOrEmpty(lo.FirstOrEmpty(cnames), lo.FromPtr(distribution.DomainName))
+			if config.ShouldExclude(v1.AWSCloudFrontDistribution, name, nil) {
+				continue
+			}
+
+			tags, err := client.ListTagsForResource(ctx, &cloudfront.ListTagsForResourceInput{Resource: distribution.ARN})
+			if err != nil {
+				results.Errorf(err, "failed to list tags for CloudFront distribution %s", distributionID)
+				continue
+			}
+			labels := make(v1.JSONStringMap)
+			if tags.Tags != nil {
+				for _, tag := range tags.Tags.Items {
+					labels[lo.FromPtr(tag.Key)] = lo.FromPtr(tag.Value)
+				}
+			}
+			if config.ShouldExclude(v1.AWSCloudFrontDistribution, name, labels) {
+				continue
+			}
+
+			distributionARN := lo.FromPtr(distribution.ARN)
+			selfExternalID := v1.ExternalID{ExternalID: distributionARN, ConfigType: v1.AWSCloudFrontDistribution}
+
+			var relationships v1.RelationshipResults
+			if distribution.Origins != nil {
+				for _, origin := range distribution.Origins.Items {
+					relationships = append(relationships, awsDNSTargetRelationships(lo.FromPtr(origin.DomainName), selfExternalID, "CloudFrontDistribution")...)
+				}
+			}
+
+			// Distributions protected by classic WAF reference the web ACL by id instead of ARN
+			if webACLARN := lo.FromPtr(distribution.WebACLId); strings.HasPrefix(webACLARN, "arn:") {
+				relationships = append(relationships, awsWebACLRelationship(webACLARN, selfExternalID, "WebACLCloudFrontDistribution"))
+			}
+
+			aliases := []string{distributionID}
+			for _, dnsName := range append([]string{lo.FromPtr(distribution.DomainName)}, cnames...) {
+				if dnsName != "" {
+					aliases = append(aliases, awsDNSNameAlias(dnsName))
+				}
+			}
+
+			*results = append(*results, v1.ScrapeResult{
+				Type:                v1.AWSCloudFrontDistribution,
+				Status:              lo.FromPtr(distribution.Status),
+				Description:         lo.FromPtr(distribution.Comment),
+				Labels:              labels,
+				BaseScraper:         config.BaseScraper,
+				Properties:          []*types.Property{getConsoleLink(ctx.Session.Region, v1.AWSCloudFrontDistribution, distributionID, nil)},
+				Config:              distribution,
+				ConfigClass:         "CDN",
+				Name:                getName(labels, name),
+				ID:                  distributionARN,
+				Aliases:             aliases,
+				Ignore:              []string{"LastModifiedTime"},
+				Parents:             []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: lo.FromPtr(ctx.Caller.Account)}},
+				RelationshipResults: relationships,
+			})
+		}
+
+		if !lo.FromPtr(output.DistributionList.IsTruncated) || output.DistributionList.NextMarker == nil {
+			return
+		}
+		input.Marker = output.DistributionList.NextMarker
+	}
+}
@@ -0,0 +1,149 @@
+package aws
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const defaultAWSConcurrency = 5
+
+// collector scrapes a single AWS service for one region.
+type collector struct {
+	name string
+	fn   func(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults)
+}
+
+// collectorRun is the isolated output of a collector. Every collector writes
+// into its own ScrapeResults so that no locking is required while scraping,
+// and the runs are merged afterwards in declaration order to keep the output
+// deterministic.
+type collectorRun struct {
+	name     string
+	results  v1.ScrapeResults
+	duration time.Duration
+}
+
+func (r collectorRun) summary() v1.CollectorSummary {
+	return v1.CollectorSummary{
+		Results:     r.results.NonErrorCount(),
+		Errors:      r.results.Errors(),
+		DurationSec: r.duration.Seconds(),
+	}
+}
+
+// regionalCollectors are run once per region.
+// subnets is not included, as it must complete before instances (which reads ctx.Subnets).
+func (aws Scraper) regionalCollectors() []collector {
+	return []collector{
+		{"cloudformation", aws.cloudformationStacks},
+		{"ecs", aws.ecsClusters},
+		{"ecsTaskDefinitions", aws.ecsTaskDefinitions},
+		{"elasticache", aws.elastiCache},
+		{"lambda", aws.lambdaFunctions},
+		{"sns", aws.snsTopics},
+		{"sqs", aws.sqs},
+		{"instances", aws.instances},
+		{"vpcs", aws.vpcs},
+		{"securityGroups", aws.securityGroups},
+		{"routes", aws.routes},
+		{"dhcp", aws.dhcp},
+		{"eks", aws.eksClusters},
+		{"ebs", aws.ebs},
+		{"efs", aws.efs},
+		{"rds", aws.rds},
+		{"config", aws.config},
+		{"loadBalancers", aws.loadBalancers},
+		{"availabilityZones", aws.availabilityZones},
+		{"ecr", aws.containerImages},
+		{"cloudtrail", aws.cloudtrail},
+		{"backups", aws.awsBackups},
+		{"dynamodb", aws.dynamoDBTables},
+		{"kms", aws.kmsKeys},
+		{"secretsmanager", aws.secretsManagerSecrets},
+		{"apigateway", aws.apiGatewayRestAPIs},
+		{"apigatewayv2", aws.apiGatewayV2APIs},
+		{"wafv2", aws.wafv2WebACLs},
+		{"autoscaling", aws.autoScalingGroups},
+		{"launchTemplates", aws.launchTemplates},
+		// We are querying half a million amis, need to optimize for this
+		// {"ami", aws.ami},
+	}
+}
+
+// globalCollectors scrape account wide resources and are run once against us-east-1.
+func (aws Scraper) globalCollectors() []collector {
+	return []collector{
+		{"account", aws.account},
+		{"users", aws.users},
+		{"iamRoles", aws.iamRoles},
+		{"iamProfiles", aws.iamProfiles},
+		{"iamGroups", aws.iamGroups},
+		{"iamOIDCProviders", aws.iamOIDCProviders},
+		{"iamSAMLProviders", aws.iamSAMLProviders},
+		{"dnsZones", aws.dnsZones},
+		{"trustedAdvisor", aws.trustedAdvisor},
+		{"s3", aws.s3Buckets},
+		{"cloudfront", aws.cloudFrontDistributions},
+		{"wafv2CloudFront", aws.wafv2CloudFrontWebACLs},
+	}
+}
+
+func getMaxConcurrency(ctx api.ScrapeContext, config v1.AWS) int {
+	if config.MaxConcurrency > 0 {
+		return config.MaxConcurrency
+	}
+	return max(ctx.Properties().Int("aws.concurrency", defaultAWSConcurrency), 1)
+}
+
+// runCollector runs a single collector once a slot is available in the shared pool.
+func runCollector(ctx *AWSContext, config v1.AWS, pool *semaphore.Weighted, prefix string, c collector) collectorRun {
+	run := collectorRun{name: fmt.Sprintf("%s/%s", prefix, c.name)}
+	if err := pool.Acquire(ctx, 1); err != nil {
+		run.results.Errorf(err, "failed to scrape %s", run.name)
+		return run
+	}
+	defer pool.Release(1)
+
+	start := time.Now()
+	c.fn(ctx, config, &run.results)
+	run.duration = time.Since(start)
+	return run
+}
+
+// runCollectors runs all collectors concurrently, bounded by the pool, and
+// returns their runs in the same order as the collectors.
+func runCollectors(ctx *AWSContext, config v1.AWS, pool *semaphore.Weighted, prefix string, collectors []collector) []collectorRun {
+	runs := make([]collectorRun, len(collectors))
+	var wg sync.WaitGroup
+	for i, c := range collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runs[i] = runCollector(ctx, config, pool, prefix, c)
+		}()
+	}
+	wg.Wait()
+	return runs
+}
+
+func (aws Scraper) scrapeRegion(ctx *AWSContext, config v1.AWS, pool *semaphore.Weighted, region string) []collectorRun {
+	prefix := lo.CoalesceOrEmpty(region, "default")
+	subnets := runCollector(ctx, config, pool, prefix, collector{"subnets", aws.subnets})
+	return append([]collectorRun{subnets}, runCollectors(ctx, config, pool, prefix, aws.regionalCollectors())...)
+}
+
+// mergeCollectorRuns appends the results of every run in order and records
+// each run in the collector summaries.
+func mergeCollectorRuns(results *v1.ScrapeResults, summaries map[string]v1.CollectorSummary, runs []collectorRun) {
+	for _, run := range runs {
+		*results = append(*results, run.results...)
+		summaries[run.name] = summaries[run.name].Merge(run.summary())
+	}
+}
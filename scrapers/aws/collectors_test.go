@@ -0,0 +1,75 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	dutyContext "github.com/flanksource/duty/context"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("runCollectors", func() {
+	ctx := &AWSContext{ScrapeContext: api.NewScrapeContext(dutyContext.New())}
+
+	It("preserves collector order and bounds concurrency", func() {
+		var running, peak int32
+		var collectors []collector
+		for i := range 10 {
+			collectors = append(collectors, collector{
+				name: fmt.Sprintf("c%d", i),
+				fn: func(_ *AWSContext, _ v1.AWS, results *v1.ScrapeResults) {
+					n := atomic.AddInt32(&running, 1)
+					for {
+						p := atomic.LoadInt32(&peak)
+						if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+							break
+						}
+					}
+					// Later collectors finish first
+					time.Sleep(time.Duration(10-i) * time.Millisecond)
+					atomic.AddInt32(&running, -1)
+					results.Add(v1.ScrapeResult{ID: fmt.Sprintf("item-%d", i)})
+				},
+			})
+		}
+
+		runs := runCollectors(ctx, v1.AWS{}, semaphore.NewWeighted(3), "us-east-1", collectors)
+
+		results := &v1.ScrapeResults{}
+		summaries := map[string]v1.CollectorSummary{}
+		mergeCollectorRuns(results, summaries, runs)
+
+		Expect(peak).To(BeNumerically("<=", 3))
+		Expect(*results).To(HaveLen(10))
+		for i, r := range *results {
+			Expect(r.ID).To(Equal(fmt.Sprintf("item-%d", i)))
+		}
+		Expect(summaries).To(HaveLen(10))
+		Expect(summaries["us-east-1/c0"].Results).To(Equal(1))
+	})
+
+	It("records collector errors in the summary", func() {
+		runs := runCollectors(ctx, v1.AWS{}, semaphore.NewWeighted(1), "eu-west-1", []collector{{
+			name: "rds",
+			fn: func(_ *AWSContext, _ v1.AWS, results *v1.ScrapeResults) {
+				results.Errorf(errors.New("access denied"), "failed to get rds")
+			},
+		}})
+
+		results := &v1.ScrapeResults{}
+		summaries := map[string]v1.CollectorSummary{}
+		mergeCollectorRuns(results, summaries, runs)
+
+		Expect(results.HasErr()).To(BeTrue())
+		Expect(summaries["eu-west-1/rds"].Results).To(Equal(0))
+		Expect(summaries["eu-west-1/rds"].Errors).To(ConsistOf("failed to get rds: access denied"))
+	})
+})
@@ -0,0 +1,87 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const IncludeDynamoDB = "DynamoDB"
+
+func (aws Scraper) dynamoDBTables(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes(IncludeDynamoDB) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping DynamoDB tables")
+
+	client := dynamodb.NewFromConfig(*ctx.Session, getEndpointResolver[dynamodb.Options](config))
+	input := &dynamodb.ListTablesInput{}
+	for {
+		tables, err := client.ListTables(ctx, input)
+		if err != nil {
+			results.Errorf(err, "failed to list DynamoDB tables")
+			return
+		}
+
+		for _, tableName := range tables.TableNames {
+			if config.ShouldExclude(v1.AWSDynamoDBTable, tableName, nil) {
+				continue
+			}
+
+			describeOutput, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: lo.ToPtr(tableName)})
+			if err != nil {
+				results.Errorf(err, "failed to describe DynamoDB table %s", tableName)
+				continue
+			}
+			table := describeOutput.Table
+			tableARN := lo.FromPtr(table.TableArn)
+
+			tags, err := client.ListTagsOfResource(ctx, &dynamodb.ListTagsOfResourceInput{ResourceArn: table.TableArn})
+			if err != nil {
+				results.Errorf(err, "failed to list tags for DynamoDB table %s", tableName)
+				continue
+			}
+			labels := make(v1.JSONStringMap)
+			for _, tag := range tags.Tags {
+				labels[lo.FromPtr(tag.Key)] = lo.FromPtr(tag.Value)
+			}
+			if config.ShouldExclude(v1.AWSDynamoDBTable, tableName, labels) {
+				continue
+			}
+
+			var relationships v1.RelationshipResults
+			if table.SSEDescription != nil && lo.FromPtr(table.SSEDescription.KMSMasterKeyArn) != "" {
+				relationships = append(relationships, awsKMSKeyRelationship(
+					awsKMSKeyExternalID(ctx.Session.Region, lo.FromPtr(ctx.Caller.Account), lo.FromPtr(table.SSEDescription.KMSMasterKeyArn)),
+					v1.ExternalID{ExternalID: tableARN, ConfigType: v1.AWSDynamoDBTable},
+					"KMSKeyDynamoDBTable",
+				))
+			}
+
+			*results = append(*results, v1.ScrapeResult{
+				Type:                v1.AWSDynamoDBTable,
+				CreatedAt:           table.CreationDateTime,
+				Status:              string(table.TableStatus),
+				Labels:              labels,
+				BaseScraper:         config.BaseScraper,
+				Properties:          []*types.Property{getConsoleLink(ctx.Session.Region, v1.AWSDynamoDBTable, tableName, nil)},
+				Config:              table,
+				ConfigClass:         "Database",
+				Name:                getName(labels, tableName),
+				ID:                  tableARN,
+				Aliases:             []string{"AmazonDynamoDB/" + tableARN},
+				Ignore:              []string{"ItemCount", "TableSizeBytes"},
+				Parents:             []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: lo.FromPtr(ctx.Caller.Account)}},
+				RelationshipResults: relationships,
+			})
+		}
+
+		if tables.LastEvaluatedTableName == nil {
+			break
+		}
+		input.ExclusiveStartTableName = tables.LastEvaluatedTableName
+	}
+}
@@ -0,0 +1,70 @@
+package aws
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAllAWSIncludes checks that every resource gated on config.Includes is accepted by the validation of the include list.
+func TestAllAWSIncludes(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	fset := token.NewFileSet()
+	constants := map[string]string{}
+	var args []ast.Expr
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		require.NoError(t, err)
+
+		ast.Inspect(parsed, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.ValueSpec:
+				for i, name := range n.Names {
+					if i < len(n.Values) {
+						if lit, ok := n.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+							constants[name.Name], _ = strconv.Unquote(lit.Value)
+						}
+					}
+				}
+			case *ast.CallExpr:
+				if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Includes" && len(n.Args) == 1 {
+					args = append(args, n.Args[0])
+				}
+			}
+			return true
+		})
+	}
+
+	var includes []string
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case *ast.BasicLit:
+			value, err := strconv.Unquote(a.Value)
+			require.NoError(t, err)
+			includes = append(includes, value)
+		case *ast.Ident:
+			value, ok := constants[a.Name]
+			require.True(t, ok, "unresolved include constant %s", a.Name)
+			includes = append(includes, value)
+		}
+	}
+	require.NotEmpty(t, includes)
+
+	for _, include := range includes {
+		require.True(t, lo.ContainsBy(v1.AllAWSIncludes, func(s string) bool { return strings.EqualFold(s, include) }),
+			"%s is scraped but missing from v1.AllAWSIncludes", include)
+	}
+}
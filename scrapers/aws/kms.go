@@ -0,0 +1,232 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const IncludeKMS = "KMS"
+
+func (aws Scraper) kmsKeys(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes(IncludeKMS) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping KMS keys")
+
+	client := kms.NewFromConfig(*ctx.Session, getEndpointResolver[kms.Options](config))
+
+	aliases, err := listKMSAliases(ctx, client)
+	if err != nil {
+		results.Errorf(err, "failed to list KMS aliases")
+		return
+	}
+
+	input := &kms.ListKeysInput{}
+	for {
+		keys, err := client.ListKeys(ctx, input)
+		if err != nil {
+			results.Errorf(err, "failed to list KMS keys")
+			return
+		}
+
+		for _, key := range keys.Keys {
+			keyID := lo.FromPtr(key.KeyId)
+			keyAliases := aliases[keyID]
+			name := keyID
+			if len(keyAliases) > 0 {
+				name = strings.TrimPrefix(lo.FromPtr(keyAliases[0].AliasName), "alias/")
+			}
+			if config.ShouldExclude(v1.AWSKMSKey, name, nil) {
+				continue
+			}
+
+			describeOutput, err := client.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: key.KeyId})
+			if err != nil {
+				results.Errorf(err, "failed to describe KMS key %s", keyID)
+				continue
+			}
+			metadata := describeOutput.KeyMetadata
+
+			// The key is kept without its tags or policy when they cannot be read
+			var warnings []v1.Warning
+			labels := make(v1.JSONStringMap)
+			// Tags can only be listed on customer managed keys
+			if metadata.KeyManager == kmsTypes.KeyManagerTypeCustomer {
+				if tags, err := client.ListResourceTags(ctx, &kms.ListResourceTagsInput{KeyId: key.KeyId}); err != nil {
+					warnings = append(warnings, v1.Warning{Error: fmt.Sprintf("failed to list tags for KMS key %s: %v", keyID, err)})
+				} else {
+					for _, tag := range tags.Tags {
+						labels[lo.FromPtr(tag.TagKey)] = lo.FromPtr(tag.TagValue)
+					}
+				}
+			}
+			if config.ShouldExclude(v1.AWSKMSKey, name, labels) {
+				continue
+			}
+
+			keyARN := lo.FromPtr(metadata.Arn)
+			var relationships v1.RelationshipResults
+			keyConfig := map[string]any{"KeyMetadata": metadata}
+			policyOutput, err := client.GetKeyPolicy(ctx, &kms.GetKeyPolicyInput{KeyId: key.KeyId, PolicyName: lo.ToPtr("default")})
+			if err != nil {
+				warnings = append(warnings, v1.Warning{Error: fmt.Sprintf("failed to get policy for KMS key %s: %v", keyID, err)})
+			} else if policy := lo.FromPtr(policyOutput.Policy); policy != "" {
+				var policyDoc map[string]any
+				if err := json.Unmarshal([]byte(policy), &policyDoc); err != nil {
+					warnings = append(warnings, v1.Warning{Error: fmt.Sprintf("failed to parse policy for KMS key %s: %v", keyID, err)})
+				} else {
+					keyConfig["KeyPolicy"] = policyDoc
+					relationships = awsKMSKeyPolicyRoleRelationships(keyARN, policyDoc)
+				}
+			}
+
+			resultAliases := []string{keyID}
+			if len(keyAliases) > 0 {
+				var aliasNames []string
+				for _, alias := range keyAliases {
+					aliasNames = append(aliasNames, lo.FromPtr(alias.AliasName))
+					resultAliases = append(resultAliases, lo.FromPtr(alias.AliasArn))
+				}
+				keyConfig["Aliases"] = aliasNames
+			}
+
+			*results = append(*results, v1.ScrapeResult{
+				Type:                v1.AWSKMSKey,
+				CreatedAt:           metadata.CreationDate,
+				Status:              string(metadata.KeyState),
+				Description:         lo.FromPtr(metadata.Description),
+				Labels:              labels,
+				BaseScraper:         config.BaseScraper,
+				Properties:          []*types.Property{getConsoleLink(ctx.Session.Region, v1.AWSKMSKey, keyID, nil)},
+				Config:              keyConfig,
+				ConfigClass:         "EncryptionKey",
+				Name:                getName(labels, name),
+				ID:                  keyARN,
+				Aliases:             resultAliases,
+				Parents:             []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: lo.FromPtr(ctx.Caller.Account)}},
+				RelationshipResults: relationships,
+				Warnings:            warnings,
+			})
+		}
+
+		if !keys.Truncated || keys.NextMarker == nil {
+			break
+		}
+		input.Marker = keys.NextMarker
+	}
+}
+
+// listKMSAliases returns all the aliases in the region keyed by their target key id.
+func listKMSAliases(ctx *AWSContext, client *kms.Client) (map[string][]kmsTypes.AliasListEntry, error) {
+	aliases := make(map[string][]kmsTypes.AliasListEntry)
+	input := &kms.ListAliasesInput{}
+	for {
+		output, err := client.ListAliases(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alias := range output.Aliases {
+			if alias.TargetKeyId == nil {
+				continue
+			}
+			aliases[*alias.TargetKeyId] = append(aliases[*alias.TargetKeyId], alias)
+		}
+
+		if !output.Truncated || output.NextMarker == nil {
+			return aliases, nil
+		}
+		input.Marker = output.NextMarker
+	}
+}
+
+// awsKMSKeyExternalID resolves the different forms a KMS key can be referenced
+// by (key id, key ARN, alias name or alias ARN) to an external id that matches
+// either the ID or one of the aliases of the scraped key.
+func awsKMSKeyExternalID(region, accountID, keyID string) v1.ExternalID {
+	externalID := keyID
+	switch {
+	case keyID == "", strings.HasPrefix(keyID, "arn:"):
+	case strings.HasPrefix(keyID, "alias/"):
+		externalID = fmt.Sprintf("arn:aws:kms:%s:%s:%s", region, accountID, keyID)
+	default:
+		externalID = fmt.Sprintf("arn:aws:kms:%s:%s:key/%s", region, accountID, keyID)
+	}
+
+	return v1.ExternalID{ExternalID: externalID, ConfigType: v1.AWSKMSKey}
+}
+
+// awsKMSKeyRelationship links a KMS key to the resource it encrypts.
+func awsKMSKeyRelationship(keyExternalID, related v1.ExternalID, relationship string) v1.RelationshipResult {
+	return v1.RelationshipResult{
+		ConfigExternalID:  keyExternalID,
+		RelatedExternalID: related,
+		Relationship:      relationship,
+	}
+}
+
+// awsKMSKeyPolicyRoleRelationships links the IAM roles granted access in a
+// key policy to the key.
+func awsKMSKeyPolicyRoleRelationships(keyARN string, policyDoc map[string]any) v1.RelationshipResults {
+	var relationships v1.RelationshipResults
+	for _, roleARN := range awsPolicyRolePrincipals(policyDoc) {
+		relationships = append(relationships, v1.RelationshipResult{
+			ConfigExternalID:  v1.ExternalID{ExternalID: roleARN, ConfigType: v1.AWSIAMRole},
+			RelatedExternalID: v1.ExternalID{ExternalID: keyARN, ConfigType: v1.AWSKMSKey},
+			Relationship:      "IAMRoleKMSKey",
+		})
+	}
+	return relationships
+}
+
+// awsPolicyRolePrincipals returns the sorted, unique IAM role ARNs referenced
+// as AWS principals in the statements of a policy document.
+func awsPolicyRolePrincipals(policyDoc map[string]any) []string {
+	var statements []any
+	switch s := policyDoc["Statement"].(type) {
+	case []any:
+		statements = s
+	case map[string]any:
+		statements = []any{s}
+	}
+
+	var roles []string
+	for _, statement := range statements {
+		stmt, ok := statement.(map[string]any)
+		if !ok {
+			continue
+		}
+		principal, ok := stmt["Principal"].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		var principals []string
+		switch p := principal["AWS"].(type) {
+		case string:
+			principals = []string{p}
+		case []any:
+			principals, _ = lo.FromAnySlice[string](p)
+		}
+
+		for _, p := range principals {
+			if strings.HasPrefix(p, "arn:") && strings.Contains(p, ":role/") {
+				roles = append(roles, p)
+			}
+		}
+	}
+
+	roles = lo.Uniq(roles)
+	slices.Sort(roles)
+	return roles
+}
@@ -0,0 +1,54 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSKMSKeyExternalID(t *testing.T) {
+	const region, account = "eu-west-1", "123456789012"
+
+	tests := map[string]string{
+		"1234abcd-12ab-34cd-56ef-1234567890ab":                                 "arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+		"alias/aws/secretsmanager":                                             "arn:aws:kms:eu-west-1:123456789012:alias/aws/secretsmanager",
+		"arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-12345": "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-12345",
+	}
+
+	for keyID, want := range tests {
+		got := awsKMSKeyExternalID(region, account, keyID)
+		assert.Equal(t, want, got.ExternalID, keyID)
+		assert.Equal(t, v1.AWSKMSKey, got.ConfigType)
+	}
+}
+
+func TestAWSKMSKeyPolicyRoleRelationships(t *testing.T) {
+	policy := `{
+		"Version": "2012-10-17",
+		"Statement": [
+			{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::123456789012:root"}, "Action": "kms:*"},
+			{"Effect": "Allow", "Principal": {"AWS": ["arn:aws:iam::123456789012:role/app", "arn:aws:iam::123456789012:role/admin"]}, "Action": "kms:Decrypt"},
+			{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::123456789012:role/app"}, "Action": "kms:Encrypt"},
+			{"Effect": "Allow", "Principal": {"Service": "logs.amazonaws.com"}, "Action": "kms:Encrypt"}
+		]
+	}`
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(policy), &doc))
+
+	keyARN := "arn:aws:kms:eu-west-1:123456789012:key/abc"
+	rels := awsKMSKeyPolicyRoleRelationships(keyARN, doc)
+	require.Len(t, rels, 2)
+
+	assert.Equal(t, "arn:aws:iam::123456789012:role/admin", rels[0].ConfigExternalID.ExternalID)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/app", rels[1].ConfigExternalID.ExternalID)
+	for _, rel := range rels {
+		assert.Equal(t, v1.AWSIAMRole, rel.ConfigExternalID.ConfigType)
+		assert.Equal(t, keyARN, rel.RelatedExternalID.ExternalID)
+		assert.Equal(t, v1.AWSKMSKey, rel.RelatedExternalID.ConfigType)
+		assert.Equal(t, "IAMRoleKMSKey", rel.Relationship)
+	}
+}
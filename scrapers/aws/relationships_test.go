@@ -90,3 +90,50 @@ func TestAWSEKSLoadBalancerRelationshipDirection(t *testing.T) {
 	assert.Equal(t, v1.AWSLoadBalancerV2, rel.RelatedExternalID.ConfigType)
 	assert.Equal(t, "EKSLoadBalancer", rel.Relationship)
 }
+
+func TestAWSDNSTargetRelationships(t *testing.T) {
+	zone := v1.ExternalID{ExternalID: "Z123", ConfigType: v1.AWSZone}
+
+	tests := []struct {
+		dnsName string
+		want    []v1.ExternalID
+		rel     string
+	}{
+		{
+			dnsName: "d111111abcdef8.cloudfront.net.",
+			want:    []v1.ExternalID{{ExternalID: "aws://dns-name/d111111abcdef8.cloudfront.net", ConfigType: v1.AWSCloudFrontDistribution}},
+			rel:     "CloudFrontDistributionHostedZone",
+		},
+		{
+			dnsName: "dualstack.my-alb-123.eu-west-1.elb.amazonaws.com.",
+			want: []v1.ExternalID{
+				{ExternalID: "aws://dns-name/my-alb-123.eu-west-1.elb.amazonaws.com", ConfigType: v1.AWSLoadBalancer},
+				{ExternalID: "aws://dns-name/my-alb-123.eu-west-1.elb.amazonaws.com", ConfigType: v1.AWSLoadBalancerV2},
+			},
+			rel: "LoadBalancerHostedZone",
+		},
+		{
+			dnsName: "my.bucket.s3.eu-west-1.amazonaws.com",
+			want:    []v1.ExternalID{{ExternalID: "my.bucket", ConfigType: v1.AWSS3Bucket}},
+			rel:     "S3BucketHostedZone",
+		},
+		{
+			dnsName: "assets.s3-website-us-east-1.amazonaws.com",
+			want:    []v1.ExternalID{{ExternalID: "assets", ConfigType: v1.AWSS3Bucket}},
+			rel:     "S3BucketHostedZone",
+		},
+		{
+			dnsName: "www.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		rels := awsDNSTargetRelationships(tt.dnsName, zone, "HostedZone")
+		require.Len(t, rels, len(tt.want), tt.dnsName)
+		for i, rel := range rels {
+			assert.Equal(t, tt.want[i], rel.ConfigExternalID, tt.dnsName)
+			assert.Equal(t, zone, rel.RelatedExternalID, tt.dnsName)
+			assert.Equal(t, tt.rel, rel.Relationship, tt.dnsName)
+		}
+	}
+}
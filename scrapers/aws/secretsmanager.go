@@ -0,0 +1,85 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const IncludeSecretsManager = "SecretsManager"
+
+// secretsManagerSecrets scrapes the metadata of Secrets Manager secrets.
+// Secret values are never fetched.
+func (aws Scraper) secretsManagerSecrets(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes(IncludeSecretsManager) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping Secrets Manager secrets")
+
+	client := secretsmanager.NewFromConfig(*ctx.Session, getEndpointResolver[secretsmanager.Options](config))
+	input := &secretsmanager.ListSecretsInput{}
+	for {
+		secrets, err := client.ListSecrets(ctx, input)
+		if err != nil {
+			results.Errorf(err, "failed to list Secrets Manager secrets")
+			return
+		}
+
+		for _, secret := range secrets.SecretList {
+			labels := make(v1.JSONStringMap)
+			for _, tag := range secret.Tags {
+				labels[lo.FromPtr(tag.Key)] = lo.FromPtr(tag.Value)
+			}
+
+			secretName := lo.FromPtr(secret.Name)
+			if config.ShouldExclude(v1.AWSSecretsManagerSecret, secretName, labels) {
+				continue
+			}
+
+			secretARN := lo.FromPtr(secret.ARN)
+			selfExternalID := v1.ExternalID{ExternalID: secretARN, ConfigType: v1.AWSSecretsManagerSecret}
+
+			var relationships v1.RelationshipResults
+			// Secrets without a KmsKeyId are encrypted with the AWS managed key
+			kmsKeyID := lo.CoalesceOrEmpty(lo.FromPtr(secret.KmsKeyId), "alias/aws/secretsmanager")
+			relationships = append(relationships, awsKMSKeyRelationship(
+				awsKMSKeyExternalID(ctx.Session.Region, lo.FromPtr(ctx.Caller.Account), kmsKeyID),
+				selfExternalID,
+				"KMSKeySecret",
+			))
+
+			if lambdaARN := lo.FromPtr(secret.RotationLambdaARN); lambdaARN != "" {
+				relationships = append(relationships, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ExternalID: lambdaARN, ConfigType: v1.AWSLambdaFunction},
+					RelatedExternalID: selfExternalID,
+					Relationship:      "LambdaSecretRotation",
+				})
+			}
+
+			*results = append(*results, v1.ScrapeResult{
+				Type:                v1.AWSSecretsManagerSecret,
+				CreatedAt:           secret.CreatedDate,
+				DeletedAt:           secret.DeletedDate,
+				Description:         lo.FromPtr(secret.Description),
+				Labels:              labels,
+				BaseScraper:         config.BaseScraper,
+				Properties:          []*types.Property{getConsoleLink(ctx.Session.Region, v1.AWSSecretsManagerSecret, secretName, nil)},
+				Config:              secret,
+				ConfigClass:         "Secret",
+				Name:                getName(labels, secretName),
+				ID:                  secretARN,
+				Ignore:              []string{"LastAccessedDate", "SecretVersionsToStages"},
+				Parents:             []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: lo.FromPtr(ctx.Caller.Account)}},
+				RelationshipResults: relationships,
+			})
+		}
+
+		if secrets.NextToken == nil {
+			break
+		}
+		input.NextToken = secrets.NextToken
+	}
+}
@@ -0,0 +1,137 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/wafv2"
+	wafv2Types "github.com/aws/aws-sdk-go-v2/service/wafv2/types"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const IncludeWAF = "WAF"
+
+// wafv2AssociatedResourceTypes are the regional resources a web ACL can be
+// associated with that are scraped as config items.
+var wafv2AssociatedResourceTypes = []struct {
+	resourceType wafv2Types.ResourceType
+	configType   string
+	relationship string
+}{
+	{wafv2Types.ResourceTypeApplicationLoadBalancer, v1.AWSLoadBalancerV2, "WebACLLoadBalancer"},
+	{wafv2Types.ResourceTypeApiGateway, v1.AWSAPIGatewayStage, "WebACLAPIGatewayStage"},
+}
+
+// wafv2WebACLs scrapes the regional web ACLs of the region.
+func (aws Scraper) wafv2WebACLs(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	aws.wafv2ScopedWebACLs(ctx, config, results, wafv2Types.ScopeRegional)
+}
+
+// wafv2CloudFrontWebACLs scrapes the web ACLs that protect CloudFront distributions.
+// They can only be managed from us-east-1, and are related to the distributions
+// from the distribution side.
+func (aws Scraper) wafv2CloudFrontWebACLs(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	aws.wafv2ScopedWebACLs(ctx, config, results, wafv2Types.ScopeCloudfront)
+}
+
+func (aws Scraper) wafv2ScopedWebACLs(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults, scope wafv2Types.Scope) {
+	if !config.Includes(IncludeWAF) {
+		return
+	}
+
+	ctx.Logger.V(2).Infof("scraping WAFv2 web ACLs (scope=%s)", scope)
+
+	region := ctx.Session.Region
+	if scope == wafv2Types.ScopeCloudfront {
+		region = "global"
+	}
+
+	client := wafv2.NewFromConfig(*ctx.Session, getEndpointResolver[wafv2.Options](config))
+	input := &wafv2.ListWebACLsInput{Scope: scope}
+	for {
+		output, err := client.ListWebACLs(ctx, input)
+		if err != nil {
+			results.Errorf(err, "failed to list WAFv2 web ACLs")
+			return
+		}
+
+		for _, summary := range output.WebACLs {
+			name := lo.FromPtr(summary.Name)
+			if config.ShouldExclude(v1.AWSWAFv2WebACL, name, nil) {
+				continue
+			}
+
+			webACL, err := client.GetWebACL(ctx, &wafv2.GetWebACLInput{Id: summary.Id, Name: summary.Name, Scope: scope})
+			if err != nil {
+				results.Errorf(err, "failed to get WAFv2 web ACL %s", name)
+				continue
+			}
+
+			tags, err := client.ListTagsForResource(ctx, &wafv2.ListTagsForResourceInput{ResourceARN: summary.ARN})
+			if err != nil {
+				results.Errorf(err, "failed to list tags for WAFv2 web ACL %s", name)
+				continue
+			}
+			labels := make(v1.JSONStringMap)
+			if tags.TagInfoForResource != nil {
+				for _, tag := range tags.TagInfoForResource.TagList {
+					labels[lo.FromPtr(tag.Key)] = lo.FromPtr(tag.Value)
+				}
+			}
+			if config.ShouldExclude(v1.AWSWAFv2WebACL, name, labels) {
+				continue
+			}
+
+			webACLARN := lo.FromPtr(summary.ARN)
+			var relationships v1.RelationshipResults
+			if scope == wafv2Types.ScopeRegional {
+				for _, associated := range wafv2AssociatedResourceTypes {
+					resources, err := client.ListResourcesForWebACL(ctx, &wafv2.ListResourcesForWebACLInput{
+						WebACLArn:    summary.ARN,
+						ResourceType: associated.resourceType,
+					})
+					if err != nil {
+						results.Errorf(err, "failed to list %s resources for WAFv2 web ACL %s", associated.resourceType, name)
+						continue
+					}
+					for _, resourceARN := range resources.ResourceArns {
+						relationships = append(relationships, awsWebACLRelationship(webACLARN,
+							v1.ExternalID{ExternalID: resourceARN, ConfigType: associated.configType},
+							associated.relationship,
+						))
+					}
+				}
+			}
+
+			*results = append(*results, v1.ScrapeResult{
+				Type:                v1.AWSWAFv2WebACL,
+				Description:         lo.FromPtr(summary.Description),
+				Labels:              labels,
+				Tags:                v1.JSONStringMap{"scope": string(scope)},
+				BaseScraper:         config.BaseScraper,
+				Properties:          []*types.Property{getConsoleLink(region, v1.AWSWAFv2WebACL, name, map[string]string{"id": lo.FromPtr(summary.Id)})},
+				Config:              webACL.WebACL,
+				ConfigClass:         "Firewall",
+				Name:                getName(labels, name),
+				ID:                  webACLARN,
+				Aliases:             []string{lo.FromPtr(summary.Id)},
+				Parents:             []v1.ConfigExternalKey{{Type: v1.AWSAccount, ExternalID: lo.FromPtr(ctx.Caller.Account)}},
+				RelationshipResults: relationships,
+			})
+		}
+
+		if lo.FromPtr(output.NextMarker) == "" {
+			return
+		}
+		input.NextMarker = output.NextMarker
+	}
+}
+
+// awsWebACLRelationship links a WAFv2 web ACL to the resource it protects.
+func awsWebACLRelationship(webACLARN string, related v1.ExternalID, relationship string) v1.RelationshipResult {
+	return v1.RelationshipResult{
+		ConfigExternalID:  v1.ExternalID{ExternalID: webACLARN, ConfigType: v1.AWSWAFv2WebACL},
+		RelatedExternalID: related,
+		Relationship:      relationship,
+	}
+}
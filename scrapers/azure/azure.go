@@ -12,11 +12,14 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerregistry/armcontainerregistry"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/cosmos/armcosmos/v3"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/eventhub/armeventhub"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/monitor/armmonitor"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/servicebus/armservicebus"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/subscription/armsubscription"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/trafficmanager/armtrafficmanager"
@@ -53,15 +56,20 @@ const (
 	IncludeAppServices         = "appServices"
 	IncludeSubscriptions       = "subscriptions"
 	IncludeContainerRegistries = "containerRegistries"
+	IncludeCosmosDB            = "cosmosDB"
 	IncludeDatabases           = "databases"
 	IncludeDNS                 = "dns"
+	IncludeEventHubs           = "eventHubs"
 	IncludeFirewalls           = "firewalls"
 	IncludeK8s                 = "k8s"
+	IncludeKeyVaults           = "keyVaults"
 	IncludeLoadBalancers       = "loadBalancers"
 	IncludePrivateDNS          = "privateDNS"
 	IncludePublicIPs           = "publicIPs"
+	IncludeResourceChanges     = "resourceChanges"
 	IncludeResourceGroups      = "resourceGroups"
 	IncludeSecurityGroups      = "securityGroups"
+	IncludeServiceBus          = "serviceBus"
 	IncludeStorageAccounts     = "storageAccounts"
 	IncludeTrafficManager      = "trafficManager"
 	IncludeVirtualMachines     = "virtualMachines"
@@ -78,6 +86,7 @@ var activityLogLastRecordTime = sync.Map{}
 
 var activityLogFilter = strings.Join([]string{
 	"authorization",
+	"caller",
 	// "claims",
 	"correlationId",
 	"description",
@@ -213,8 +222,13 @@ func (azure Scraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 		results = append(results, azure.fetchTrafficManagerProfiles()...)
 		results = append(results, azure.fetchNetworkSecurityGroups()...)
 		results = append(results, azure.fetchPublicIPAddresses()...)
+		results = append(results, azure.fetchKeyVaults()...)
+		results = append(results, azure.fetchCosmosDBAccounts()...)
+		results = append(results, azure.fetchServiceBusNamespaces()...)
+		results = append(results, azure.fetchEventHubNamespaces()...)
 		results = append(results, azure.fetchAdvisorAnalysis()...)
 		results = append(results, azure.fetchActivityLogs()...)
+		results = append(results, azure.fetchResourceChanges()...)
 
 		// Set subscription id and name as tags & parents where missing for all resources
 		// before we fetch active directory resources as they're not part of a subscription
@@ -387,6 +401,9 @@ func (azure Scraper) fetchActivityLogs() v1.ScrapeResults {
 				Source:           ConfigTypePrefix + "ActivityLog",
 				Summary:          utils.Deref(v.OperationName.LocalizedValue),
 			}
+			if caller := utils.Deref(v.Caller); caller != "" {
+				change.CreatedBy = &caller
+			}
 			corelatedActivities[utils.Deref(v.CorrelationID)] = append(corelatedActivities[utils.Deref(v.CorrelationID)], activityChangeRecord{
 				Result:    change,
 				EventData: v,
@@ -1098,6 +1115,198 @@ func (azure Scraper) fetchPublicIPAddresses() v1.ScrapeResults {
 	return results
 }
 
+// fetchCosmosDBAccounts gets Azure Cosmos DB accounts in a subscription.
+func (azure Scraper) fetchCosmosDBAccounts() v1.ScrapeResults {
+	if !azure.config.Includes(IncludeCosmosDB) {
+		return nil
+	}
+
+	azure.ctx.Logger.V(3).Infof("fetching cosmos db accounts for subscription %s", azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	client, err := armcosmos.NewDatabaseAccountsClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate cosmos db client: %w", err)})
+	}
+
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		respPage, err := pager.NextPage(azure.ctx)
+		if err != nil {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read cosmos db accounts page: %w", err)})
+		}
+
+		for _, v := range respPage.Value {
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: azure.config.BaseScraper,
+				ID:          getARMID(v.ID),
+				Name:        deref(v.Name),
+				Config:      v,
+				ConfigClass: "Database",
+				Type:        getARMType(v.Type),
+				Properties:  []*types.Property{getConsoleLink(lo.FromPtr(v.ID), getARMType(v.Type))},
+			})
+		}
+	}
+
+	return results
+}
+
+// fetchServiceBusNamespaces gets Azure Service Bus namespaces along with their queues and topics.
+func (azure Scraper) fetchServiceBusNamespaces() v1.ScrapeResults {
+	if !azure.config.Includes(IncludeServiceBus) {
+		return nil
+	}
+
+	azure.ctx.Logger.V(3).Infof("fetching service bus namespaces for subscription %s", azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	client, err := armservicebus.NewNamespacesClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate service bus namespaces client: %w", err)})
+	}
+	queuesClient, err := armservicebus.NewQueuesClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate service bus queues client: %w", err)})
+	}
+	topicsClient, err := armservicebus.NewTopicsClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate service bus topics client: %w", err)})
+	}
+
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		respPage, err := pager.NextPage(azure.ctx)
+		if err != nil {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read service bus namespaces page: %w", err)})
+		}
+
+		for _, v := range respPage.Value {
+			namespaceID := getARMID(v.ID)
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: azure.config.BaseScraper,
+				ID:          namespaceID,
+				Name:        deref(v.Name),
+				Config:      v,
+				ConfigClass: "MessageBroker",
+				Type:        getARMType(v.Type),
+				Properties:  []*types.Property{getConsoleLink(lo.FromPtr(v.ID), getARMType(v.Type))},
+			})
+			parents := []v1.ConfigExternalKey{{Type: getARMType(v.Type), ExternalID: namespaceID}}
+
+			queuesPager := queuesClient.NewListByNamespacePager(extractResourceGroup(namespaceID), deref(v.Name), nil)
+			for queuesPager.More() {
+				queuesPage, err := queuesPager.NextPage(azure.ctx)
+				if err != nil {
+					results = append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read service bus queues of %s: %w", deref(v.Name), err)})
+					break
+				}
+
+				for _, queue := range queuesPage.Value {
+					results = append(results, v1.ScrapeResult{
+						BaseScraper: azure.config.BaseScraper,
+						ID:          getARMID(queue.ID),
+						Name:        deref(queue.Name),
+						Config:      queue,
+						ConfigClass: "Queue",
+						Type:        getARMType(queue.Type),
+						Properties:  []*types.Property{getConsoleLink(lo.FromPtr(queue.ID), getARMType(queue.Type))},
+						Parents:     parents,
+					})
+				}
+			}
+
+			topicsPager := topicsClient.NewListByNamespacePager(extractResourceGroup(namespaceID), deref(v.Name), nil)
+			for topicsPager.More() {
+				topicsPage, err := topicsPager.NextPage(azure.ctx)
+				if err != nil {
+					results = append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read service bus topics of %s: %w", deref(v.Name), err)})
+					break
+				}
+
+				for _, topic := range topicsPage.Value {
+					results = append(results, v1.ScrapeResult{
+						BaseScraper: azure.config.BaseScraper,
+						ID:          getARMID(topic.ID),
+						Name:        deref(topic.Name),
+						Config:      topic,
+						ConfigClass: "Topic",
+						Type:        getARMType(topic.Type),
+						Properties:  []*types.Property{getConsoleLink(lo.FromPtr(topic.ID), getARMType(topic.Type))},
+						Parents:     parents,
+					})
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+// fetchEventHubNamespaces gets Azure Event Hub namespaces along with their event hubs.
+func (azure Scraper) fetchEventHubNamespaces() v1.ScrapeResults {
+	if !azure.config.Includes(IncludeEventHubs) {
+		return nil
+	}
+
+	azure.ctx.Logger.V(3).Infof("fetching event hub namespaces for subscription %s", azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	client, err := armeventhub.NewNamespacesClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate event hub namespaces client: %w", err)})
+	}
+	eventHubsClient, err := armeventhub.NewEventHubsClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate event hubs client: %w", err)})
+	}
+
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		respPage, err := pager.NextPage(azure.ctx)
+		if err != nil {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read event hub namespaces page: %w", err)})
+		}
+
+		for _, v := range respPage.Value {
+			namespaceID := getARMID(v.ID)
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: azure.config.BaseScraper,
+				ID:          namespaceID,
+				Name:        deref(v.Name),
+				Config:      v,
+				ConfigClass: "MessageBroker",
+				Type:        getARMType(v.Type),
+				Properties:  []*types.Property{getConsoleLink(lo.FromPtr(v.ID), getARMType(v.Type))},
+			})
+
+			eventHubsPager := eventHubsClient.NewListByNamespacePager(extractResourceGroup(namespaceID), deref(v.Name), nil)
+			for eventHubsPager.More() {
+				eventHubsPage, err := eventHubsPager.NextPage(azure.ctx)
+				if err != nil {
+					results = append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read event hubs of %s: %w", deref(v.Name), err)})
+					break
+				}
+
+				for _, eventHub := range eventHubsPage.Value {
+					results = append(results, v1.ScrapeResult{
+						BaseScraper: azure.config.BaseScraper,
+						ID:          getARMID(eventHub.ID),
+						Name:        deref(eventHub.Name),
+						Config:      eventHub,
+						ConfigClass: "EventHub",
+						Type:        getARMType(eventHub.Type),
+						Properties:  []*types.Property{getConsoleLink(lo.FromPtr(eventHub.ID), getARMType(eventHub.Type))},
+						Parents:     []v1.ConfigExternalKey{{Type: getARMType(v.Type), ExternalID: namespaceID}},
+					})
+				}
+			}
+		}
+	}
+
+	return results
+}
+
 func getConsoleLink(resourceID, resourceType string) *types.Property {
 	return &types.Property{
 		Name: "URL",
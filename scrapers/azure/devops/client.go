@@ -216,7 +216,7 @@ func NewAzureDevopsClient(ctx api.ScrapeContext, ado v1.AzureDevops) (*AzureDevo
 
 // newADOHTTPClient builds a commons HTTP client for Azure DevOps endpoints,
 // wiring authentication and feature-aware observability through duty's
-// connection layer.
+// connection layer, and HAR recording and replay through the scrape context.
 func newADOHTTPClient(ctx api.ScrapeContext, baseURL, org, token string) (*commonsHTTP.Client, error) {
 	conn := connection.HTTPConnection{
 		HTTPBasicAuth: types.HTTPBasicAuth{
@@ -226,7 +226,7 @@ func newADOHTTPClient(ctx api.ScrapeContext, baseURL, org, token string) (*commo
 			},
 		},
 	}
-	client, err := connection.CreateHTTPClient(ctx, conn, types.WithFeature("azure.devops"))
+	client, err := ctx.NewHTTPClient(conn, types.WithFeature("azure.devops"))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,380 @@
+package azure
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v2"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/keyvault/armkeyvault"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const (
+	keyVaultType = "Microsoft.KeyVault/vaults"
+
+	principalTypeUser             = "User"
+	principalTypeGroup            = "Group"
+	principalTypeServicePrincipal = "ServicePrincipal"
+)
+
+// azurePrincipal is an Entra ID object granted access to a resource.
+type azurePrincipal struct {
+	ID   uuid.UUID
+	Type string
+	Name string
+}
+
+// keyVaultAccess accumulates the access entries of all the key vaults along
+// with the principals and roles they reference, so each principal is
+// resolved and each role emitted only once.
+type keyVaultAccess struct {
+	principals map[string]*azurePrincipal
+	roleNames  map[string]string
+	roles      map[string]models.ExternalRole
+
+	users  []models.ExternalUser
+	groups []models.ExternalGroup
+}
+
+// fetchKeyVaults gets the key vaults in a subscription along with the metadata of
+// their secrets. Access policies and RBAC role assignments are recorded as config access.
+func (azure Scraper) fetchKeyVaults() v1.ScrapeResults {
+	if !azure.config.Includes(IncludeKeyVaults) {
+		return nil
+	}
+
+	azure.ctx.Logger.V(3).Infof("fetching key vaults for subscription %s", azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	client, err := armkeyvault.NewVaultsClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate key vault client: %w", err)})
+	}
+
+	secretsClient, err := armkeyvault.NewSecretsClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate key vault secrets client: %w", err)})
+	}
+
+	access := &keyVaultAccess{
+		principals: make(map[string]*azurePrincipal),
+		roleNames:  make(map[string]string),
+		roles:      make(map[string]models.ExternalRole),
+	}
+
+	pager := client.NewListBySubscriptionPager(nil)
+	for pager.More() {
+		respPage, err := pager.NextPage(azure.ctx)
+		if err != nil {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read key vault next page: %w", err)})
+		}
+
+		for _, v := range respPage.Value {
+			vaultID := getARMID(v.ID)
+			result := v1.ScrapeResult{
+				BaseScraper: azure.config.BaseScraper,
+				ID:          vaultID,
+				Name:        deref(v.Name),
+				Config:      v,
+				ConfigClass: "KeyVault",
+				Type:        getARMType(v.Type),
+				Properties:  []*types.Property{getConsoleLink(lo.FromPtr(v.ID), getARMType(v.Type))},
+			}
+
+			if v.Properties != nil {
+				for _, policy := range v.Properties.AccessPolicies {
+					result.ConfigAccess = append(result.ConfigAccess, azure.keyVaultAccessPolicyAccess(access, vaultID, policy)...)
+				}
+			}
+
+			roleAssignments, err := azure.keyVaultRoleAssignmentAccess(access, lo.FromPtr(v.ID), vaultID)
+			if err != nil {
+				results = append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to fetch role assignments for key vault %s: %w", deref(v.Name), err)})
+			}
+			result.ConfigAccess = append(result.ConfigAccess, roleAssignments...)
+			results = append(results, result)
+
+			results = append(results, azure.fetchKeyVaultSecrets(secretsClient, v)...)
+		}
+	}
+
+	if len(access.roles) > 0 || len(access.users) > 0 || len(access.groups) > 0 {
+		roles := lo.Values(access.roles)
+		slices.SortFunc(roles, func(a, b models.ExternalRole) int { return strings.Compare(a.Name, b.Name) })
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:    azure.config.BaseScraper,
+			ExternalRoles:  roles,
+			ExternalUsers:  access.users,
+			ExternalGroups: access.groups,
+		})
+	}
+
+	return results
+}
+
+// fetchKeyVaultSecrets gets the metadata of the secrets in a key vault.
+// The management plane never returns secret values, but the value is
+// cleared regardless so that it can never end up in a config.
+func (azure Scraper) fetchKeyVaultSecrets(client *armkeyvault.SecretsClient, vault *armkeyvault.Vault) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	vaultID := getARMID(vault.ID)
+	pager := client.NewListPager(extractResourceGroup(vaultID), deref(vault.Name), nil)
+	for pager.More() {
+		respPage, err := pager.NextPage(azure.ctx)
+		if err != nil {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read secrets of key vault %s: %w", deref(vault.Name), err)})
+		}
+
+		for _, secret := range respPage.Value {
+			secret = sanitizeKeyVaultSecret(secret)
+
+			result := v1.ScrapeResult{
+				BaseScraper: azure.config.BaseScraper,
+				ID:          getARMID(secret.ID),
+				Name:        deref(secret.Name),
+				Config:      secret,
+				ConfigClass: "Secret",
+				Type:        getARMType(secret.Type),
+				Properties:  []*types.Property{getConsoleLink(lo.FromPtr(secret.ID), getARMType(secret.Type))},
+				Parents:     []v1.ConfigExternalKey{{Type: getARMType(to.Ptr(keyVaultType)), ExternalID: vaultID}},
+			}
+			if secret.Properties != nil && secret.Properties.Attributes != nil {
+				result.CreatedAt = secret.Properties.Attributes.Created
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// sanitizeKeyVaultSecret returns a copy of the secret without its value.
+func sanitizeKeyVaultSecret(secret *armkeyvault.Secret) *armkeyvault.Secret {
+	if secret == nil || secret.Properties == nil {
+		return secret
+	}
+
+	sanitized := *secret
+	properties := *secret.Properties
+	properties.Value = nil
+	sanitized.Properties = &properties
+	return &sanitized
+}
+
+// keyVaultAccessPolicyAccess converts a vault access policy into config access
+// entries, one for each category (keys, secrets, certificates, storage) the
+// principal has permissions on.
+func (azure Scraper) keyVaultAccessPolicyAccess(access *keyVaultAccess, vaultID string, policy *armkeyvault.AccessPolicyEntry) []v1.ExternalConfigAccess {
+	if policy == nil {
+		return nil
+	}
+
+	principal := azure.resolvePrincipal(access, deref(policy.ObjectID), "")
+	if principal == nil {
+		return nil
+	}
+
+	var accesses []v1.ExternalConfigAccess
+	for _, roleName := range keyVaultAccessPolicyRoles(policy.Permissions) {
+		roleID := azure.keyVaultRole(access, roleName, "KeyVaultAccessPolicy", nil)
+		accesses = append(accesses, keyVaultConfigAccess(vaultID, principal, roleID))
+	}
+	return accesses
+}
+
+// keyVaultAccessPolicyRoles names the roles granted by the permissions of an access policy.
+// Permissions are sorted so that identical policies map to the same role across vaults.
+func keyVaultAccessPolicyRoles(permissions *armkeyvault.Permissions) []string {
+	if permissions == nil {
+		return nil
+	}
+
+	categories := []struct {
+		name        string
+		permissions []string
+	}{
+		{"Keys", enumStrings(permissions.Keys)},
+		{"Secrets", enumStrings(permissions.Secrets)},
+		{"Certificates", enumStrings(permissions.Certificates)},
+		{"Storage", enumStrings(permissions.Storage)},
+	}
+
+	var roles []string
+	for _, category := range categories {
+		if len(category.permissions) == 0 {
+			continue
+		}
+		slices.Sort(category.permissions)
+		roles = append(roles, fmt.Sprintf("Key Vault %s: %s", category.name, strings.Join(category.permissions, ", ")))
+	}
+	return roles
+}
+
+func enumStrings[T ~string](values []*T) []string {
+	var out []string
+	for _, v := range values {
+		if v != nil && *v != "" {
+			out = append(out, strings.ToLower(string(*v)))
+		}
+	}
+	return lo.Uniq(out)
+}
+
+// keyVaultRoleAssignmentAccess converts the RBAC role assignments that apply to a
+// vault, including the ones inherited from the resource group and subscription,
+// into config access entries.
+func (azure Scraper) keyVaultRoleAssignmentAccess(access *keyVaultAccess, scope, vaultID string) ([]v1.ExternalConfigAccess, error) {
+	client, err := armauthorization.NewRoleAssignmentsClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiate role assignments client: %w", err)
+	}
+
+	definitions, err := armauthorization.NewRoleDefinitionsClient(azure.cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiate role definitions client: %w", err)
+	}
+
+	var accesses []v1.ExternalConfigAccess
+	pager := client.NewListForScopePager(scope, &armauthorization.RoleAssignmentsClientListForScopeOptions{Filter: to.Ptr("atScope()")})
+	for pager.More() {
+		respPage, err := pager.NextPage(azure.ctx)
+		if err != nil {
+			return accesses, fmt.Errorf("failed to read role assignments next page: %w", err)
+		}
+
+		for _, assignment := range respPage.Value {
+			if assignment.Properties == nil {
+				continue
+			}
+
+			definitionID := strings.ToLower(deref(assignment.Properties.RoleDefinitionID))
+			roleName, ok := access.roleNames[definitionID]
+			if !ok {
+				definition, err := definitions.GetByID(azure.ctx, definitionID, nil)
+				if err != nil {
+					azure.ctx.Logger.V(3).Infof("failed to get role definition %s: %v", definitionID, err)
+				} else if definition.Properties != nil {
+					roleName = deref(definition.Properties.RoleName)
+				}
+				access.roleNames[definitionID] = roleName
+			}
+			if roleName == "" {
+				continue
+			}
+
+			principal := azure.resolvePrincipal(access, deref(assignment.Properties.PrincipalID), string(lo.FromPtr(assignment.Properties.PrincipalType)))
+			if principal == nil {
+				continue
+			}
+
+			roleID := azure.keyVaultRole(access, roleName, "AzureRBAC", []string{definitionID})
+			configAccess := keyVaultConfigAccess(vaultID, principal, roleID)
+			configAccess.CreatedAt = lo.FromPtr(assignment.Properties.CreatedOn)
+			accesses = append(accesses, configAccess)
+		}
+	}
+
+	return accesses, nil
+}
+
+// keyVaultRole returns the id of the named role, registering it on first use.
+func (azure Scraper) keyVaultRole(access *keyVaultAccess, roleName, roleType string, aliases []string) uuid.UUID {
+	if role, ok := access.roles[roleName]; ok {
+		return role.ID
+	}
+
+	role := models.ExternalRole{
+		ID:        RoleID(azure.ctx.ScraperID(), roleName),
+		Name:      roleName,
+		RoleType:  roleType,
+		Tenant:    azure.config.TenantID,
+		ScraperID: azure.ctx.ScrapeConfig().GetPersistedID(),
+		Aliases:   aliases,
+	}
+	access.roles[roleName] = role
+	return role.ID
+}
+
+// resolvePrincipal looks up the type and name of an Entra ID object, registering
+// it as an external user or group. Service principals are recorded as users.
+// When the lookup fails the principal type reported by Azure (if any) is used.
+func (azure Scraper) resolvePrincipal(access *keyVaultAccess, objectID, principalType string) *azurePrincipal {
+	if principal, ok := access.principals[objectID]; ok {
+		return principal
+	}
+
+	id, err := uuid.Parse(objectID)
+	if err != nil {
+		access.principals[objectID] = nil
+		return nil
+	}
+
+	principal := &azurePrincipal{ID: id, Type: principalType, Name: objectID}
+	if object, err := azure.graphClient.DirectoryObjects().ByDirectoryObjectId(objectID).Get(azure.ctx, nil); err != nil {
+		azure.ctx.Logger.V(3).Infof("failed to look up principal %s: %v", objectID, err)
+	} else {
+		principal.Type = principalTypeFromODataType(lo.FromPtr(object.GetOdataType()), principalType)
+		if named, ok := object.(interface{ GetDisplayName() *string }); ok {
+			principal.Name = lo.CoalesceOrEmpty(deref(named.GetDisplayName()), objectID)
+		}
+	}
+
+	switch principal.Type {
+	case principalTypeUser, principalTypeServicePrincipal:
+		access.users = append(access.users, models.ExternalUser{
+			ID:        id,
+			Name:      principal.Name,
+			Tenant:    azure.config.TenantID,
+			ScraperID: lo.FromPtr(azure.ctx.ScrapeConfig().GetPersistedID()),
+			UserType:  principal.Type,
+		})
+	case principalTypeGroup:
+		access.groups = append(access.groups, models.ExternalGroup{
+			ID:        id,
+			Name:      principal.Name,
+			Tenant:    azure.config.TenantID,
+			ScraperID: lo.FromPtr(azure.ctx.ScrapeConfig().GetPersistedID()),
+			GroupType: "security",
+		})
+	default:
+		azure.ctx.Logger.V(3).Infof("skipping principal %s of unknown type %q", objectID, principal.Type)
+		principal = nil
+	}
+
+	access.principals[objectID] = principal
+	return principal
+}
+
+func principalTypeFromODataType(odataType, fallback string) string {
+	switch odataType {
+	case "#microsoft.graph.user":
+		return principalTypeUser
+	case "#microsoft.graph.group":
+		return principalTypeGroup
+	case "#microsoft.graph.servicePrincipal":
+		return principalTypeServicePrincipal
+	}
+	return fallback
+}
+
+func keyVaultConfigAccess(vaultID string, principal *azurePrincipal, roleID uuid.UUID) v1.ExternalConfigAccess {
+	configAccess := v1.ExternalConfigAccess{
+		ConfigExternalID: v1.ExternalID{ConfigType: getARMType(to.Ptr(keyVaultType)), ExternalID: vaultID},
+		ExternalRoleID:   &roleID,
+	}
+
+	principalID := principal.ID
+	if principal.Type == principalTypeGroup {
+		configAccess.ExternalGroupID = &principalID
+	} else {
+		configAccess.ExternalUserID = &principalID
+	}
+	return configAccess
+}
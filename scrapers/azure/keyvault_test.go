@@ -0,0 +1,92 @@
+package azure
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/keyvault/armkeyvault"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("keyVaultAccessPolicyRoles", func() {
+	It("returns nothing without permissions", func() {
+		Expect(keyVaultAccessPolicyRoles(nil)).To(BeEmpty())
+		Expect(keyVaultAccessPolicyRoles(&armkeyvault.Permissions{})).To(BeEmpty())
+	})
+
+	It("names one role per category with sorted, lowercased permissions", func() {
+		roles := keyVaultAccessPolicyRoles(&armkeyvault.Permissions{
+			Secrets: []*armkeyvault.SecretPermissions{
+				to.Ptr(armkeyvault.SecretPermissionsList),
+				to.Ptr(armkeyvault.SecretPermissionsGet),
+				to.Ptr(armkeyvault.SecretPermissionsGet),
+			},
+			Keys: []*armkeyvault.KeyPermissions{
+				to.Ptr(armkeyvault.KeyPermissionsUnwrapKey),
+				to.Ptr(armkeyvault.KeyPermissionsWrapKey),
+			},
+		})
+		Expect(roles).To(Equal([]string{
+			"Key Vault Keys: unwrapkey, wrapkey",
+			"Key Vault Secrets: get, list",
+		}))
+	})
+})
+
+var _ = Describe("sanitizeKeyVaultSecret", func() {
+	It("clears the secret value without mutating the original", func() {
+		secret := &armkeyvault.Secret{
+			Name: to.Ptr("db-password"),
+			Properties: &armkeyvault.SecretProperties{
+				ContentType: to.Ptr("text/plain"),
+				Value:       to.Ptr("hunter2"),
+			},
+		}
+
+		sanitized := sanitizeKeyVaultSecret(secret)
+		Expect(sanitized.Properties.Value).To(BeNil())
+		Expect(*sanitized.Properties.ContentType).To(Equal("text/plain"))
+		Expect(*sanitized.Name).To(Equal("db-password"))
+		Expect(*secret.Properties.Value).To(Equal("hunter2"))
+	})
+
+	It("handles secrets without properties", func() {
+		Expect(sanitizeKeyVaultSecret(nil)).To(BeNil())
+		Expect(sanitizeKeyVaultSecret(&armkeyvault.Secret{}).Properties).To(BeNil())
+	})
+})
+
+var _ = Describe("principalTypeFromODataType", func() {
+	DescribeTable("maps graph object types to principal types",
+		func(odataType, fallback, expected string) {
+			Expect(principalTypeFromODataType(odataType, fallback)).To(Equal(expected))
+		},
+		Entry("user", "#microsoft.graph.user", "", principalTypeUser),
+		Entry("group", "#microsoft.graph.group", "", principalTypeGroup),
+		Entry("service principal", "#microsoft.graph.servicePrincipal", "", principalTypeServicePrincipal),
+		Entry("unknown falls back", "#microsoft.graph.device", principalTypeGroup, principalTypeGroup),
+	)
+})
+
+var _ = Describe("keyVaultConfigAccess", func() {
+	vaultID := "/subscriptions/123/resourcegroups/rg/providers/microsoft.keyvault/vaults/kv"
+	roleID := uuid.New()
+
+	It("grants groups access through the group id", func() {
+		principal := &azurePrincipal{ID: uuid.New(), Type: principalTypeGroup}
+		access := keyVaultConfigAccess(vaultID, principal, roleID)
+		Expect(access.ExternalGroupID).To(Equal(&principal.ID))
+		Expect(access.ExternalUserID).To(BeNil())
+		Expect(access.ExternalRoleID).To(Equal(&roleID))
+		Expect(access.ConfigExternalID.ExternalID).To(Equal(vaultID))
+	})
+
+	It("grants users and service principals access through the user id", func() {
+		for _, principalType := range []string{principalTypeUser, principalTypeServicePrincipal} {
+			principal := &azurePrincipal{ID: uuid.New(), Type: principalType}
+			access := keyVaultConfigAccess(vaultID, principal, roleID)
+			Expect(access.ExternalUserID).To(Equal(&principal.ID))
+			Expect(access.ExternalGroupID).To(BeNil())
+		}
+	})
+})
@@ -0,0 +1,206 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resourcegraph/armresourcegraph"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// resourceChangesQuery lists the changes recorded by Resource Graph since a point in time, oldest first.
+// https://learn.microsoft.com/en-us/azure/governance/resource-graph/how-to/get-resource-changes
+const resourceChangesQuery = `resourcechanges
+| extend changeTime = todatetime(properties.changeAttributes.timestamp)
+| where changeTime >= datetime(%s)
+| project id, changeTime,
+	targetResourceId = tostring(properties.targetResourceId),
+	targetResourceType = tostring(properties.targetResourceType),
+	changeType = tostring(properties.changeType),
+	changedBy = tostring(properties.changeAttributes.changedBy),
+	clientType = tostring(properties.changeAttributes.clientType),
+	operation = tostring(properties.changeAttributes.operation),
+	correlationId = tostring(properties.changeAttributes.correlationId),
+	changes = properties.changes
+| order by changeTime asc`
+
+// resourceGraphChange is a row of resourceChangesQuery.
+type resourceGraphChange struct {
+	ID                 string                                 `json:"id"`
+	ChangeTime         time.Time                              `json:"changeTime"`
+	TargetResourceID   string                                 `json:"targetResourceId"`
+	TargetResourceType string                                 `json:"targetResourceType"`
+	ChangeType         string                                 `json:"changeType"`
+	ChangedBy          string                                 `json:"changedBy"`
+	ClientType         string                                 `json:"clientType"`
+	Operation          string                                 `json:"operation"`
+	CorrelationID      string                                 `json:"correlationId"`
+	Changes            map[string]resourceGraphPropertyChange `json:"changes"`
+}
+
+type resourceGraphPropertyChange struct {
+	PreviousValue      any    `json:"previousValue,omitempty"`
+	NewValue           any    `json:"newValue,omitempty"`
+	PropertyChangeType string `json:"propertyChangeType"`
+	ChangeCategory     string `json:"changeCategory,omitempty"`
+}
+
+// resourceChangesStateKey is the key of the resume cursor in the scrape summary state.
+func resourceChangesStateKey(subscriptionID string) string {
+	return "azure/resourceChanges/" + subscriptionID
+}
+
+// fetchResourceChanges gets the property level changes of the resources in a subscription
+// from the Resource Graph change history.
+// It resumes from the last change seen in the previous run, and falls back to
+// the configured max age when there is no previous run.
+func (azure Scraper) fetchResourceChanges() v1.ScrapeResults {
+	if !azure.config.Includes(IncludeResourceChanges) {
+		return nil
+	}
+
+	azure.ctx.Logger.V(3).Infof("fetching resource changes for subscription %s", azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	client, err := armresourcegraph.NewClient(azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate resource graph client: %w", err)})
+	}
+
+	stateKey := resourceChangesStateKey(azure.config.SubscriptionID)
+	since := time.Now().Add(-azure.config.ResourceChanges.GetMaxAge())
+	if cursor, ok := azure.ctx.LastScrapeSummary().State[stateKey].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, cursor); err == nil {
+			since = t
+		}
+	}
+
+	request := armresourcegraph.QueryRequest{
+		Query:         to.Ptr(fmt.Sprintf(resourceChangesQuery, since.UTC().Format(time.RFC3339Nano))),
+		Subscriptions: []*string{to.Ptr(azure.config.SubscriptionID)},
+		Options: &armresourcegraph.QueryRequestOptions{
+			ResultFormat: to.Ptr(armresourcegraph.ResultFormatObjectArray),
+		},
+	}
+
+	var changes []v1.ChangeResult
+	for {
+		resp, err := client.Resources(azure.ctx, request, nil)
+		if err != nil {
+			results = append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to query resource changes: %w", err)})
+			break
+		}
+
+		var rows []resourceGraphChange
+		if raw, err := json.Marshal(resp.Data); err != nil {
+			results = append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to marshal resource changes: %w", err)})
+			break
+		} else if err := json.Unmarshal(raw, &rows); err != nil {
+			results = append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to unmarshal resource changes: %w", err)})
+			break
+		}
+
+		for _, row := range rows {
+			changes = append(changes, resourceGraphChangeResult(row))
+			if row.ChangeTime.After(since) {
+				since = row.ChangeTime
+			}
+		}
+
+		if lo.FromPtr(resp.SkipToken) == "" {
+			break
+		}
+		request.Options.SkipToken = resp.SkipToken
+	}
+
+	return append(results, v1.ScrapeResult{
+		Changes: changes,
+		State:   map[string]any{stateKey: since.UTC().Format(time.RFC3339Nano)},
+	})
+}
+
+// resourceGraphChangeResult converts a Resource Graph change into a change result.
+// The property changes become a merge patch of the new values, keyed by the
+// property paths in the ARM representation of the resource.
+func resourceGraphChangeResult(change resourceGraphChange) v1.ChangeResult {
+	result := v1.ChangeResult{
+		ChangeType:       change.ChangeType,
+		CreatedAt:        lo.ToPtr(change.ChangeTime),
+		ExternalChangeID: strings.ToLower(change.ID),
+		ExternalID:       getARMID(&change.TargetResourceID),
+		ConfigType:       getARMType(&change.TargetResourceType),
+		Source:           ConfigTypePrefix + "ResourceChanges",
+		Details: map[string]any{
+			"changedBy":     change.ChangedBy,
+			"clientType":    change.ClientType,
+			"operation":     change.Operation,
+			"correlationId": change.CorrelationID,
+			"changes":       change.Changes,
+		},
+	}
+	if change.ChangedBy != "" {
+		result.CreatedBy = lo.ToPtr(change.ChangedBy)
+	}
+
+	if change.ChangeType != "Update" || len(change.Changes) == 0 {
+		result.Summary = change.Operation
+		return result
+	}
+
+	result.ChangeType = v1.ChangeTypeDiff
+	if patch, err := json.Marshal(resourceChangesPatch(change.Changes)); err == nil {
+		result.Patches = string(patch)
+	}
+	diff := resourceChangesDiff(change.Changes)
+	result.Diff = &diff
+	return result
+}
+
+// resourceChangesPatch builds a merge patch out of property changes.
+// Removed properties are set to null.
+func resourceChangesPatch(changes map[string]resourceGraphPropertyChange) map[string]any {
+	patch := map[string]any{}
+	for path, change := range changes {
+		var value any
+		if change.PropertyChangeType != "Remove" {
+			value = change.NewValue
+		}
+
+		node := patch
+		segments := strings.Split(path, ".")
+		for _, segment := range segments[:len(segments)-1] {
+			child, ok := node[segment].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[segment] = child
+			}
+			node = child
+		}
+		node[segments[len(segments)-1]] = value
+	}
+	return patch
+}
+
+// resourceChangesDiff renders property changes as a diff, one line per removed and added value.
+func resourceChangesDiff(changes map[string]resourceGraphPropertyChange) string {
+	paths := lo.Keys(changes)
+	slices.Sort(paths)
+
+	var lines []string
+	for _, path := range paths {
+		change := changes[path]
+		if change.PropertyChangeType != "Insert" {
+			lines = append(lines, fmt.Sprintf("- %s: %v", path, change.PreviousValue))
+		}
+		if change.PropertyChangeType != "Remove" {
+			lines = append(lines, fmt.Sprintf("+ %s: %v", path, change.NewValue))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,72 @@
+package azure
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+var _ = Describe("resourceGraphChangeResult", func() {
+	changeTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	resourceID := "/subscriptions/123/resourceGroups/RG/providers/Microsoft.Network/networkSecurityGroups/NSG"
+
+	It("converts property changes into a merge patch", func() {
+		result := resourceGraphChangeResult(resourceGraphChange{
+			ID:                 "/subscriptions/123/providers/Microsoft.Resources/changes/ABC",
+			ChangeTime:         changeTime,
+			TargetResourceID:   resourceID,
+			TargetResourceType: "microsoft.network/networksecuritygroups",
+			ChangeType:         "Update",
+			ChangedBy:          "user@example.com",
+			Changes: map[string]resourceGraphPropertyChange{
+				"tags.env":                         {PreviousValue: "dev", NewValue: "prod", PropertyChangeType: "Update"},
+				"properties.securityRules[0].name": {NewValue: "allow-https", PropertyChangeType: "Insert"},
+				"tags.owner":                       {PreviousValue: "alice", PropertyChangeType: "Remove"},
+			},
+		})
+
+		Expect(result.ChangeType).To(Equal(v1.ChangeTypeDiff))
+		Expect(result.ExternalID).To(Equal("/subscriptions/123/resourcegroups/rg/providers/microsoft.network/networksecuritygroups/nsg"))
+		Expect(result.ConfigType).To(Equal("Azure::microsoft.network/networksecuritygroups"))
+		Expect(result.ExternalChangeID).To(Equal("/subscriptions/123/providers/microsoft.resources/changes/abc"))
+		Expect(*result.CreatedAt).To(Equal(changeTime))
+		Expect(*result.CreatedBy).To(Equal("user@example.com"))
+
+		var patch map[string]any
+		Expect(json.Unmarshal([]byte(result.Patches), &patch)).To(Succeed())
+		Expect(patch).To(Equal(map[string]any{
+			"tags": map[string]any{"env": "prod", "owner": nil},
+			"properties": map[string]any{
+				"securityRules[0]": map[string]any{"name": "allow-https"},
+			},
+		}))
+
+		Expect(*result.Diff).To(Equal(
+			"+ properties.securityRules[0].name: allow-https\n" +
+				"- tags.env: dev\n" +
+				"+ tags.env: prod\n" +
+				"- tags.owner: alice"))
+	})
+
+	DescribeTable("keeps the change type of creates and deletes",
+		func(changeType string) {
+			result := resourceGraphChangeResult(resourceGraphChange{
+				ChangeTime:       changeTime,
+				TargetResourceID: resourceID,
+				ChangeType:       changeType,
+				Operation:        "Microsoft.Network/networkSecurityGroups/write",
+			})
+			Expect(result.ChangeType).To(Equal(changeType))
+			Expect(result.Patches).To(BeEmpty())
+			Expect(result.Diff).To(BeNil())
+			Expect(result.CreatedBy).To(BeNil())
+			Expect(result.Summary).To(Equal("Microsoft.Network/networkSecurityGroups/write"))
+		},
+		Entry("create", "Create"),
+		Entry("delete", "Delete"),
+	)
+})
@@ -0,0 +1,84 @@
+package changes
+
+import (
+	"sort"
+	"time"
+
+	"github.com/flanksource/commons/hash"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// AggregateChanges rolls up the changes of aggregate change mappings into a single change
+// per config, group key and window. The rolled up change takes the fields of the most recent
+// change of the group and carries the group, in place of the individual changes.
+func AggregateChanges(changes []v1.ChangeResult) []v1.ChangeResult {
+	var aggregated []v1.ChangeResult
+	output := make([]v1.ChangeResult, 0, len(changes))
+	for _, change := range changes {
+		if change.Action == v1.Aggregate && change.Aggregate != nil {
+			aggregated = append(aggregated, change)
+		} else {
+			output = append(output, change)
+		}
+	}
+	if len(aggregated) == 0 {
+		return changes
+	}
+
+	now := time.Now()
+	sort.SliceStable(aggregated, func(i, j int) bool {
+		return lo.FromPtrOr(aggregated[i].CreatedAt, now).Before(lo.FromPtrOr(aggregated[j].CreatedAt, now))
+	})
+
+	var rolledUp []v1.ChangeResult
+	// index of the latest rolled up change of every group
+	groups := map[string]int{}
+	for _, change := range aggregated {
+		key := aggregateGroupKey(change)
+		createdAt := lo.FromPtrOr(change.CreatedAt, now)
+
+		if i, ok := groups[key]; ok && rolledUp[i].Aggregate.InWindow(createdAt) {
+			group := rolledUp[i].Aggregate
+			if group.Add(change) {
+				rolledUp[i] = rollUp(change, group)
+			}
+			continue
+		}
+
+		group := &v1.ChangeAggregate{
+			Key:     change.Aggregate.Key,
+			Window:  change.Aggregate.Window,
+			Samples: change.Aggregate.Samples,
+		}
+		group.Add(change)
+		groups[key] = len(rolledUp)
+		rolledUp = append(rolledUp, rollUp(change, group))
+	}
+
+	return append(output, rolledUp...)
+}
+
+// rollUp returns the change that represents the group
+func rollUp(change v1.ChangeResult, group *v1.ChangeAggregate) v1.ChangeResult {
+	change.Aggregate = group
+	// The external ids of the aggregated changes are kept on the group
+	change.ExternalChangeID = aggregateExternalChangeID(change, group)
+	change.FlushMap()
+	return change
+}
+
+// aggregateExternalChangeID identifies the rolled up change by its group and the start of its window,
+// so that the group scraped again is saved over the same change.
+func aggregateExternalChangeID(change v1.ChangeResult, group *v1.ChangeAggregate) string {
+	id, err := hash.DeterministicUUID([]string{aggregateGroupKey(change), group.First.UTC().Format(time.RFC3339Nano)})
+	if err != nil {
+		return ""
+	}
+	return "aggregate/" + id.String()
+}
+
+func aggregateGroupKey(change v1.ChangeResult) string {
+	return change.ConfigID + "/" + change.ConfigType + "/" + change.ExternalID + "/" + change.Aggregate.Key
+}
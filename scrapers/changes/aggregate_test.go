@@ -0,0 +1,96 @@
+package changes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AggregateChanges", func() {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	scaled := func(id string, minutes int, replicas int) v1.ChangeResult {
+		return v1.ChangeResult{
+			ExternalID:       "hpa",
+			ConfigType:       "Kubernetes::HorizontalPodAutoscaler",
+			ExternalChangeID: id,
+			ChangeType:       "SuccessfulRescale",
+			Summary:          fmt.Sprintf("New size: %d", replicas),
+			CreatedAt:        lo.ToPtr(start.Add(time.Duration(minutes) * time.Minute)),
+			Details:          map[string]any{"replicas": replicas},
+		}
+	}
+
+	It("should roll up changes from mappings with an aggregate action", func() {
+		result := v1.ScrapeResult{
+			Changes: []v1.ChangeResult{
+				scaled("1", 0, 2), scaled("2", 5, 3), scaled("3", 10, 4),
+				{ExternalID: "hpa", ChangeType: "Deleted"},
+			},
+		}
+
+		err := ProcessRules(api.NewScrapeContext(DefaultContext), &result, nil, v1.ChangeMapping{
+			Filter:  `change_type == "SuccessfulRescale"`,
+			Action:  v1.Aggregate,
+			GroupBy: `external_id + "/" + change_type`,
+			Window:  "30m",
+			Samples: 2,
+		})
+		Expect(err).To(BeNil())
+
+		changes := AggregateChanges(result.Changes)
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[0].ChangeType).To(Equal("Deleted"))
+
+		rolledUp := changes[1]
+		Expect(rolledUp.ExternalChangeID).To(HavePrefix("aggregate/"))
+		Expect(rolledUp.Details).To(Equal(map[string]any{"replicas": 4}))
+		Expect(rolledUp.Aggregate.Key).To(Equal("hpa/SuccessfulRescale"))
+		Expect(rolledUp.Aggregate.Count).To(Equal(3))
+		Expect(rolledUp.Aggregate.First).To(Equal(start))
+		Expect(rolledUp.Aggregate.Last).To(Equal(start.Add(10 * time.Minute)))
+		Expect(rolledUp.Aggregate.ExternalChangeIDs).To(Equal([]string{"1", "2", "3"}))
+		Expect(rolledUp.Aggregate.SampleDetails).To(HaveLen(2))
+		Expect(rolledUp.Aggregate.SampleDetails[1]["details"]).To(Equal(map[string]any{"replicas": 4}))
+	})
+
+	It("should start a new change once the window has passed", func() {
+		var changes []v1.ChangeResult
+		for i, minutes := range []int{0, 20, 45, 50} {
+			change := scaled(fmt.Sprint(i), minutes, i)
+			change.Action = v1.Aggregate
+			change.Aggregate = &v1.ChangeAggregate{Key: "rescale", Window: 30 * time.Minute}
+			changes = append(changes, change)
+		}
+
+		rolledUp := AggregateChanges(changes)
+		Expect(rolledUp).To(HaveLen(2))
+		Expect(rolledUp[0].Aggregate.Count).To(Equal(2))
+		Expect(rolledUp[1].Aggregate.Count).To(Equal(2))
+		Expect(rolledUp[1].Aggregate.First).To(Equal(start.Add(45 * time.Minute)))
+		Expect(rolledUp[0].ExternalChangeID).ToNot(Equal(rolledUp[1].ExternalChangeID))
+
+		// The same window scraped again is rolled up into a change with the same external id
+		Expect(AggregateChanges(changes[:1])[0].ExternalChangeID).To(Equal(rolledUp[0].ExternalChangeID))
+	})
+
+	It("should not count the same change twice when merging groups", func() {
+		group := v1.ChangeAggregate{Key: "rescale", Window: time.Hour}
+		group.Add(scaled("1", 0, 1))
+		group.Add(scaled("2", 5, 2))
+
+		relisted := v1.ChangeAggregate{Key: "rescale", Window: time.Hour}
+		relisted.Add(scaled("2", 5, 2))
+		relisted.Add(scaled("3", 15, 3))
+
+		group.Merge(relisted)
+		Expect(group.Count).To(Equal(3))
+		Expect(group.Last).To(Equal(start.Add(15 * time.Minute)))
+		Expect(group.SampleDetails).To(HaveLen(3))
+	})
+})
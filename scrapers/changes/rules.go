@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/flanksource/clicky"
+	"github.com/flanksource/commons/duration"
 	"github.com/flanksource/commons/logger"
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
@@ -33,6 +34,9 @@ type changeRule struct {
 	ScraperID    string                             `json:"scraper_id"`    // Scraper ID for target config ("all" for cross-scraper).
 	AncestorType string                             `json:"ancestor_type"` // Config type of ancestor to target for move-up/copy-up.
 	Target       *duty.RelationshipSelectorTemplate `json:"target"`        // Config selector for copy/move actions.
+	GroupBy      string                             `json:"group_by"`      // CEL expression for the group key of aggregate actions.
+	Window       time.Duration                      `json:"window"`        // Window in which aggregate actions roll up changes.
+	Samples      int                                `json:"samples"`       // Number of details of aggregated changes to keep.
 }
 
 // matches the rule with a config using the filter
@@ -135,6 +139,22 @@ func (t *changeRule) process(ctx api.ScrapeContext, change *v1.ChangeResult, con
 	if t.Target != nil {
 		change.Target = t.Target
 	}
+
+	if t.Action == v1.Aggregate {
+		key := change.ChangeType
+		if t.GroupBy != "" {
+			key, err = ctx.RunTemplate(gomplate.Template{
+				Expression: t.GroupBy,
+				CacheKey:   "changes.rule.group_by:" + t.GroupBy,
+				CacheTime:  utils.RandomDurationBetween(24*time.Hour, 36*time.Hour),
+			}, env)
+			if err != nil {
+				return true, fmt.Errorf("failed to evaluate group_by expression %s: %w", t.GroupBy, err)
+			}
+		}
+		change.Aggregate = &v1.ChangeAggregate{Key: key, Window: t.Window, Samples: t.Samples}
+	}
+
 	if ctx.PropertyOn(false, "log.transforms") {
 		ctx.Tracef("%s --> %s", change.Pretty().ANSI(), clicky.MustFormat(configEnv))
 	}
@@ -194,6 +214,17 @@ func ProcessRules(ctx api.ScrapeContext, result *v1.ScrapeResult, ci *models.Con
 			errors = append(errors, fmt.Errorf("change mapping: target is mutually exclusive with move-up/copy-up/ancestor_type"))
 			continue
 		}
+
+		window := time.Hour
+		if r.Window != "" {
+			parsed, err := duration.ParseDuration(r.Window)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("change mapping: invalid window %s: %w", r.Window, err))
+				continue
+			}
+			window = time.Duration(parsed)
+		}
+
 		allRules = append(allRules, changeRule{
 			Action:       r.Action,
 			Rule:         r.Filter,
@@ -205,6 +236,9 @@ func ProcessRules(ctx api.ScrapeContext, result *v1.ScrapeResult, ci *models.Con
 			ScraperID:    r.ScraperID,
 			AncestorType: r.AncestorType,
 			Target:       r.Target,
+			GroupBy:      r.GroupBy,
+			Window:       window,
+			Samples:      lo.Ternary(r.Samples > 0, r.Samples, 5),
 		})
 	}
 	configEnv := configItemEnv(ci, result)
@@ -538,6 +538,7 @@ func scheduleScraperJob(sc api.ScrapeContext) error {
 
 		// always watch for event objects
 		config.Watch = v1.AddEventResourceToWatch(config.Watch)
+		config.Watch = v1.AddRolloutResourcesToWatch(config.Watch)
 
 		queue, err := kubernetes.WatchResources(sc, config)
 		if err != nil {
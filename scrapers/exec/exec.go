@@ -23,7 +23,12 @@ func (e ExecScraper) CanScrape(configs v1.ScraperSpec) bool {
 func (e ExecScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 	results := v1.ScrapeResults{}
 
-	for _, config := range ctx.ScrapeConfig().Spec.Exec {
+	for i, config := range ctx.ScrapeConfig().Spec.Exec {
+		if stdout, ok := ctx.ExecStub(i); ok {
+			results = append(results, ParseOutput(config.BaseScraper, stdout)...)
+			continue
+		}
+
 		execConfig := shell.Exec{
 			Script:    config.Script,
 			Checkout:  config.Checkout,
@@ -3,6 +3,7 @@ package file
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"net/url"
 	"os"
 	"path"
@@ -13,7 +14,9 @@ import (
 	"github.com/flanksource/commons/logger"
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/scrapers/processors"
 	"github.com/flanksource/config-db/utils"
+	"github.com/go-git/go-git/v5"
 	"github.com/gobwas/glob"
 	"github.com/hashicorp/go-getter"
 	"sigs.k8s.io/yaml"
@@ -97,11 +100,22 @@ func (file FileScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 			globMatches = findFiles(ctx, "", config.Paths)
 		}
 
+		var repo *git.Repository
+		var repoRoot string
+		if config.History != nil && url != "" {
+			var err error
+			if repo, repoRoot, err = openGitRepo(tempDir); err != nil {
+				results.Errorf(err, "history is only available for git repositories: %s", strippedURL)
+			}
+		}
+
 		for _, match := range globMatches {
 			file := strings.Replace(match, tempDir+"/", "", 1)
 			var result = v1.NewScrapeResult(config.BaseScraper)
 			if config.Format != "" {
 				result.Format = config.Format
+			} else {
+				result.Format = formatFromExtension(match)
 			}
 			if config.Icon != "" {
 				result.Icon = config.Icon
@@ -133,6 +147,18 @@ func (file FileScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 				jsonContent = string(contentByte)
 			}
 
+			if repo != nil {
+				if err := addGitHistory(ctx, result, repo, repoRoot, url, file, match, config.History.GetMaxCommits()); err != nil {
+					results = append(results, result.Errorf("failed to read the git history of %s: %v", file, err))
+					continue
+				}
+			}
+
+			if config.Format == "" && result.Format != "" {
+				results = append(results, result.Success(parseInferredFormat(result, file, jsonContent)))
+				continue
+			}
+
 			results = append(results, result.Success(jsonContent))
 		}
 	}
@@ -170,6 +196,41 @@ func findFiles(ctx api.ScrapeContext, dir string, paths []string) []string {
 	return matches
 }
 
+// formatFromExtension returns the format of files that are parsed into structured config
+// based on their extension, yaml and json files are handled as json.
+// Extensions used by more than one format, e.g. .cfg or .config, are left to the format of the scraper.
+func formatFromExtension(filename string) string {
+	base := strings.ToLower(filepath.Base(filename))
+	if base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env") {
+		return "env"
+	}
+
+	switch filepath.Ext(base) {
+	case ".toml":
+		return "toml"
+	case ".ini":
+		return "ini"
+	case ".hcl", ".tfvars":
+		return "hcl"
+	case ".xml", ".csproj":
+		return "xml"
+	case ".csv":
+		return "csv"
+	}
+	return ""
+}
+
+// parseInferredFormat parses a file in the format inferred from its extension.
+// A file that doesn't parse is kept as raw content, with a warning, instead of failing the scrape.
+func parseInferredFormat(result *v1.ScrapeResult, file, content string) any {
+	parsed, err := processors.ParseFormat(result.Format, content)
+	if err != nil {
+		result.Warnings = append(result.Warnings, v1.Warning{Error: fmt.Sprintf("failed to parse %s as %s: %v", file, result.Format, err)})
+		return map[string]any{"format": result.Format, "content": content}
+	}
+	return parsed
+}
+
 func isYaml(filename string) bool {
 	return filepath.Ext(filename) == ".yaml" || filepath.Ext(filename) == ".yml"
 }
@@ -3,6 +3,7 @@ package file
 import (
 	"testing"
 
+	v1 "github.com/flanksource/config-db/api/v1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -36,3 +37,38 @@ var _ = Describe("convertToLocalPath", func() {
 		Entry("plain path", "foo", "foo-2c26b46b"),
 	)
 })
+
+var _ = Describe("formatFromExtension", func() {
+	DescribeTable("should infer the format of structured files",
+		func(input, expected string) {
+			Expect(formatFromExtension(input)).To(Equal(expected))
+		},
+		Entry("toml", "config/app.toml", "toml"),
+		Entry("ini", "php.ini", "ini"),
+		Entry("terraform variables", "prod.tfvars", "hcl"),
+		Entry("maven", "pom.xml", "xml"),
+		Entry("csv", "hosts.CSV", "csv"),
+		Entry("dotenv", ".env", "env"),
+		Entry("dotenv with environment", ".env.production", "env"),
+		Entry("yaml", "values.yaml", ""),
+		Entry("json", "config.json", ""),
+		Entry("ambiguous cfg", "setup.cfg", ""),
+		Entry("ambiguous config", "web.config", ""),
+		Entry("terraform", "main.tf", ""),
+	)
+})
+
+var _ = Describe("parseInferredFormat", func() {
+	It("parses the file in the inferred format", func() {
+		result := v1.ScrapeResult{Format: "toml"}
+		Expect(parseInferredFormat(&result, "app.toml", "replicas = 2\n")).To(Equal(map[string]any{"replicas": float64(2)}))
+		Expect(result.Warnings).To(BeEmpty())
+	})
+
+	It("keeps the raw content of a file that doesn't parse with a warning", func() {
+		result := v1.ScrapeResult{Format: "toml"}
+		Expect(parseInferredFormat(&result, "app.toml", "replicas =")).To(Equal(map[string]any{"format": "toml", "content": "replicas ="}))
+		Expect(result.Warnings).To(HaveLen(1))
+		Expect(result.Warnings[0].Error).To(ContainSubstring("failed to parse app.toml as toml"))
+	})
+})
@@ -0,0 +1,169 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/samber/lo"
+)
+
+const (
+	ChangeTypeCommit = "Commit"
+
+	gitChangeSource = "git"
+)
+
+// gitHistoryStateKey is the key of the last commit seen for a file in the scrape summary state.
+func gitHistoryStateKey(url, file string) string {
+	return fmt.Sprintf("file/git/%s/%s", stripSecrets(url), file)
+}
+
+// addGitHistory adds a change for every commit that touched the file since the last scrape
+// and records the last commit in the scrape state for the next scrape to resume from.
+func addGitHistory(ctx api.ScrapeContext, result *v1.ScrapeResult, repo *git.Repository, root, url, file, match string, maxCommits int) error {
+	path, err := filepath.Rel(root, match)
+	if err != nil {
+		return err
+	}
+
+	stateKey := gitHistoryStateKey(url, file)
+	since, _ := ctx.LastScrapeSummary().State[stateKey].(string)
+
+	changes, last, truncated, err := gitFileChanges(repo, filepath.ToSlash(path), since, maxCommits)
+	if err != nil {
+		return err
+	}
+	if truncated {
+		warning := fmt.Sprintf("%s has more than %d commits since %s, the older commits are skipped", file, maxCommits, since)
+		ctx.Logger.Warnf("%s", warning)
+		result.Warnings = append(result.Warnings, v1.Warning{Error: warning})
+	}
+
+	ctx.Logger.V(3).Infof("found %d commits for %s since %s", len(changes), file, lo.CoalesceOrEmpty(since, "the beginning"))
+	result.Changes = append(result.Changes, changes...)
+	if last != "" {
+		result.State = lo.Assign(result.State, map[string]any{stateKey: last})
+	}
+	return nil
+}
+
+// openGitRepo opens the repository that the downloaded files are checked out from.
+// The files must be the root of the clone, a repository in a parent directory is not used.
+func openGitRepo(dir string) (*git.Repository, string, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: false})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, "", fmt.Errorf("%s is not the root of a git repository", dir)
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, "", err
+	}
+	return repo, worktree.Filesystem.Root(), nil
+}
+
+// gitFileChanges returns a change for every commit that touched the file after
+// the since commit, oldest first, along with the last commit that touched the file.
+// At most maxCommits commits are read, an empty since reads the most recent ones.
+// truncated is set when the limit is reached before the since commit.
+func gitFileChanges(repo *git.Repository, file, since string, maxCommits int) (changes []v1.ChangeResult, last string, truncated bool, err error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, since, false, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	commits, err := repo.Log(&git.LogOptions{From: head.Hash(), FileName: &file})
+	if err != nil {
+		return nil, since, false, fmt.Errorf("failed to read git log: %w", err)
+	}
+	defer commits.Close()
+
+	last = since
+	for {
+		commit, err := commits.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, since, false, fmt.Errorf("failed to read git log: %w", err)
+		}
+		if commit.Hash.String() == since {
+			break
+		}
+		if len(changes) == maxCommits {
+			// There are more commits after the since commit than can be read
+			truncated = since != ""
+			break
+		}
+		if len(changes) == 0 {
+			last = commit.Hash.String()
+		}
+
+		change, err := gitCommitChange(commit, file)
+		if err != nil {
+			return nil, since, false, fmt.Errorf("failed to diff %s at %s: %w", file, commit.Hash, err)
+		}
+		changes = append(changes, change)
+	}
+
+	return lo.Reverse(changes), last, truncated, nil
+}
+
+func gitCommitChange(commit *object.Commit, file string) (v1.ChangeResult, error) {
+	after, err := gitFileContents(commit, file)
+	if err != nil {
+		return v1.ChangeResult{}, err
+	}
+
+	var before string
+	if parent, err := commit.Parent(0); err == nil {
+		if before, err = gitFileContents(parent, file); err != nil {
+			return v1.ChangeResult{}, err
+		}
+	} else if !errors.Is(err, object.ErrParentNotFound) {
+		return v1.ChangeResult{}, err
+	}
+
+	summary, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+	change := v1.ChangeResult{
+		ChangeType:       ChangeTypeCommit,
+		ExternalChangeID: commit.Hash.String(),
+		Source:           gitChangeSource,
+		Summary:          summary,
+		CreatedBy:        lo.ToPtr(commit.Author.Email),
+		CreatedAt:        lo.ToPtr(commit.Author.When),
+		Details: map[string]any{
+			"commit":  commit.Hash.String(),
+			"author":  commit.Author.Name,
+			"message": strings.TrimSpace(commit.Message),
+			"file":    file,
+		},
+	}
+
+	if edits := myers.ComputeEdits("", before, after); len(edits) > 0 {
+		diff := fmt.Sprint(gotextdiff.ToUnified("a/"+file, "b/"+file, before, edits))
+		change.Diff = &diff
+	}
+	return change, nil
+}
+
+// gitFileContents returns the contents of a file at a commit, or an empty string if it didn't exist.
+func gitFileContents(commit *object.Commit, file string) (string, error) {
+	f, err := commit.File(file)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return f.Contents()
+}
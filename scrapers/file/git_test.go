@@ -0,0 +1,123 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("git history", func() {
+	var (
+		dir     string
+		repo    *git.Repository
+		commits []string
+	)
+
+	commit := func(file, content, author string, when time.Time, message string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, file), []byte(content), 0644)).To(Succeed())
+
+		worktree, err := repo.Worktree()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = worktree.Add(file)
+		Expect(err).ToNot(HaveOccurred())
+
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: author, Email: author + "@example.com", When: when},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		commits = append(commits, hash.String())
+	}
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		commits = nil
+
+		var err error
+		repo, err = git.PlainInit(dir, false)
+		Expect(err).ToNot(HaveOccurred())
+
+		commit("config/app.yaml", "replicas: 1\n", "alice", start, "Add app config")
+		commit("README.md", "# app\n", "alice", start.Add(time.Hour), "Add readme")
+		commit("config/app.yaml", "replicas: 2\n", "bob", start.Add(2*time.Hour), "Scale app\n\nMore traffic")
+	})
+
+	It("opens the repository at the root of the clone", func() {
+		_, root, err := openGitRepo(dir)
+		Expect(err).ToNot(HaveOccurred())
+
+		rel, err := filepath.Rel(root, filepath.Join(dir, "config", "app.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rel).To(Equal("config/app.yaml"))
+	})
+
+	It("does not open the repository of a parent directory", func() {
+		_, _, err := openGitRepo(filepath.Join(dir, "config"))
+		Expect(err).To(MatchError(ContainSubstring("is not the root of a git repository")))
+	})
+
+	It("creates a change for every commit that touched the file, oldest first", func() {
+		changes, last, _, err := gitFileChanges(repo, "config/app.yaml", "", 100)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(last).To(Equal(commits[2]))
+		Expect(changes).To(HaveLen(2))
+
+		Expect(changes[0].ChangeType).To(Equal(ChangeTypeCommit))
+		Expect(changes[0].ExternalChangeID).To(Equal(commits[0]))
+		Expect(*changes[0].CreatedBy).To(Equal("alice@example.com"))
+		Expect(*changes[0].Diff).To(ContainSubstring("+replicas: 1"))
+
+		Expect(changes[1].ExternalChangeID).To(Equal(commits[2]))
+		Expect(changes[1].Summary).To(Equal("Scale app"))
+		Expect(*changes[1].CreatedBy).To(Equal("bob@example.com"))
+		Expect(*changes[1].CreatedAt).To(BeTemporally("==", start.Add(2*time.Hour)))
+		Expect(*changes[1].Diff).To(ContainSubstring("--- a/config/app.yaml"))
+		Expect(*changes[1].Diff).To(ContainSubstring("-replicas: 1"))
+		Expect(*changes[1].Diff).To(ContainSubstring("+replicas: 2"))
+	})
+
+	It("only reads the commits since the last scrape", func() {
+		changes, last, truncated, err := gitFileChanges(repo, "config/app.yaml", commits[2], 100)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(truncated).To(BeFalse())
+		Expect(changes).To(BeEmpty())
+		Expect(last).To(Equal(commits[2]))
+
+		commit("config/app.yaml", "replicas: 3\n", "carol", start.Add(3*time.Hour), "Scale app again")
+		changes, last, truncated, err = gitFileChanges(repo, "config/app.yaml", commits[2], 100)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].ExternalChangeID).To(Equal(commits[3]))
+		Expect(last).To(Equal(commits[3]))
+	})
+
+	It("limits the number of commits", func() {
+		changes, last, truncated, err := gitFileChanges(repo, "config/app.yaml", "", 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(truncated).To(BeFalse())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].ExternalChangeID).To(Equal(commits[2]))
+		Expect(last).To(Equal(commits[2]))
+	})
+
+	It("reports when the limit is reached before the last scraped commit", func() {
+		commit("config/app.yaml", "replicas: 3\n", "carol", start.Add(3*time.Hour), "Scale app again")
+
+		changes, last, truncated, err := gitFileChanges(repo, "config/app.yaml", commits[0], 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(truncated).To(BeTrue())
+		Expect(changes).To(HaveLen(1))
+		Expect(last).To(Equal(commits[3]))
+
+		_, _, truncated, err = gitFileChanges(repo, "config/app.yaml", commits[2], 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(truncated).To(BeFalse())
+	})
+})
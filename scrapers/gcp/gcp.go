@@ -138,6 +138,11 @@ func parseResourceData(asset *assetpb.Asset) ResourceData {
 		aliases = append(aliases, data.Fields["email"].GetStringValue())
 	}
 
+	// Push subscriptions reference a Cloud Run service by its URL.
+	if asset.AssetType == cloudRunServiceAssetType {
+		aliases = append(aliases, cloudRunServiceURLs(data)...)
+	}
+
 	return ResourceData{
 		ID:        id,
 		Name:      getName(asset),
@@ -156,6 +161,11 @@ func getName(asset *assetpb.Asset) string {
 	if name != "" {
 		return name
 	}
+	// Knative shaped Cloud Run services have no top level name,
+	// the asset name carries the resource name instead.
+	if asset.AssetType == cloudRunServiceAssetType {
+		return strings.TrimPrefix(asset.Name, "//run.googleapis.com/")
+	}
 	if asset.AssetType == "servicenetworking.googleapis.com/Connection" {
 		network := asset.Resource.Data.Fields["network"].GetStringValue()
 		peering := asset.Resource.Data.Fields["peering"].GetStringValue()
@@ -522,6 +532,12 @@ func RelationshipResolver(assetType string, rd ResourceData) relationshipResults
 		return resolveGCPSubnetRelationships(rd)
 	case v1.GCPGKECluster:
 		return resolveGCPGKEClusterRelationships(rd)
+	case v1.GCPCloudRunService:
+		return resolveGCPCloudRunServiceRelationships(rd)
+	case v1.GCPCloudFunction, v1.GCPFunction:
+		return resolveGCPCloudFunctionRelationships(assetType, rd)
+	case v1.GCPPubSubSubscription:
+		return resolveGCPPubSubSubscriptionRelationships(rd)
 	}
 	return relationshipResults{}
 }
@@ -0,0 +1,246 @@
+package gcp
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/samber/lo"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const cloudRunServiceAssetType = "run.googleapis.com/Service"
+
+// Cloud Run services are exported by the asset inventory either in the Cloud Run
+// Admin API v2 shape (template.*) or in the Knative serving v1 shape (spec.template.*),
+// so every lookup below checks both.
+
+// cloudRunServiceURLs returns the hostnames a Cloud Run service is reachable at.
+// They are used as aliases so that push subscriptions can be linked to the service.
+func cloudRunServiceURLs(data *structpb.Struct) []string {
+	p := rawData(data)
+
+	urls := []string{
+		gabsString(p, "uri"),
+		gabsString(p, "status", "url"),
+		gabsString(p, "status", "address", "url"),
+	}
+	for _, u := range p.Search("urls").Children() {
+		if s, ok := u.Data().(string); ok {
+			urls = append(urls, s)
+		}
+	}
+
+	var hosts []string
+	for _, u := range urls {
+		if host := urlHost(u); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return lo.Uniq(hosts)
+}
+
+func resolveGCPCloudRunServiceRelationships(rd ResourceData) (r relationshipResults) {
+	selfExternalID := v1.ExternalID{ExternalID: lo.CoalesceOrEmpty(rd.ID, rd.Name), ConfigType: v1.GCPCloudRunService}
+	if selfExternalID.ExternalID == "" {
+		return r
+	}
+
+	p := rawData(rd.Raw)
+	project, location := cloudRunServiceScope(rd.Name, p)
+
+	serviceAccount := lo.CoalesceOrEmpty(
+		gabsString(p, "template", "serviceAccount"),
+		gabsString(p, "spec", "template", "spec", "serviceAccountName"),
+	)
+	if serviceAccount != "" {
+		r.Relationships = append(r.Relationships, v1.RelationshipResult{
+			ConfigExternalID:  selfExternalID,
+			RelatedExternalID: v1.ExternalID{ExternalID: serviceAccount, ConfigType: v1.IAMServiceAccount},
+			Relationship:      "CloudRunServiceServiceAccount",
+		})
+	}
+
+	connector := lo.CoalesceOrEmpty(
+		gabsString(p, "template", "vpcAccess", "connector"),
+		gabsString(p, "spec", "template", "metadata", "annotations", "run.googleapis.com/vpc-access-connector"),
+	)
+	if connector != "" {
+		r.Relationships = append(r.Relationships, v1.RelationshipResult{
+			ConfigExternalID:  selfExternalID,
+			RelatedExternalID: v1.ExternalID{ExternalID: qualifyConnector(connector, project, location), ConfigType: v1.GCPVPCAccessConnector},
+			Relationship:      "CloudRunServiceConnector",
+		})
+	}
+
+	var secrets []string
+	containers := append(p.Search("template", "containers").Children(), p.Search("spec", "template", "spec", "containers").Children()...)
+	for _, container := range containers {
+		for _, env := range container.Search("env").Children() {
+			secrets = append(secrets,
+				gabsString(env, "valueSource", "secretKeyRef", "secret"),
+				gabsString(env, "valueFrom", "secretKeyRef", "name"),
+			)
+		}
+	}
+	volumes := append(p.Search("template", "volumes").Children(), p.Search("spec", "template", "spec", "volumes").Children()...)
+	for _, volume := range volumes {
+		secrets = append(secrets,
+			gabsString(volume, "secret", "secret"),
+			gabsString(volume, "secret", "secretName"),
+		)
+	}
+
+	for _, secret := range lo.Uniq(lo.Compact(secrets)) {
+		r.Relationships = append(r.Relationships, v1.RelationshipResult{
+			ConfigExternalID:  selfExternalID,
+			RelatedExternalID: v1.ExternalID{ExternalID: qualifySecret(secret, project), ConfigType: v1.GCPSecret},
+			Relationship:      "CloudRunServiceSecret",
+		})
+	}
+
+	return r
+}
+
+// resolveGCPCloudFunctionRelationships links 1st gen (CloudFunction) and
+// 2nd gen (Function) functions to their service account and event trigger.
+func resolveGCPCloudFunctionRelationships(configType string, rd ResourceData) (r relationshipResults) {
+	selfExternalID := v1.ExternalID{ExternalID: lo.CoalesceOrEmpty(rd.ID, rd.Name), ConfigType: configType}
+	if selfExternalID.ExternalID == "" {
+		return r
+	}
+
+	p := rawData(rd.Raw)
+
+	serviceAccount := lo.CoalesceOrEmpty(
+		gabsString(p, "serviceAccountEmail"),
+		gabsString(p, "serviceConfig", "serviceAccountEmail"),
+	)
+	if serviceAccount != "" {
+		r.Relationships = append(r.Relationships, v1.RelationshipResult{
+			ConfigExternalID:  selfExternalID,
+			RelatedExternalID: v1.ExternalID{ExternalID: serviceAccount, ConfigType: v1.IAMServiceAccount},
+			Relationship:      "FunctionServiceAccount",
+		})
+	}
+
+	topic := gabsString(p, "eventTrigger", "pubsubTopic")
+	var bucket string
+	for _, filter := range p.Search("eventTrigger", "eventFilters").Children() {
+		if gabsString(filter, "attribute") == "bucket" {
+			bucket = gabsString(filter, "value")
+		}
+	}
+
+	// 1st gen triggers reference the resource directly, e.g.
+	// projects/<project>/topics/<topic> or projects/_/buckets/<bucket>
+	eventType := gabsString(p, "eventTrigger", "eventType")
+	resource := gabsString(p, "eventTrigger", "resource")
+	switch {
+	case topic == "" && strings.Contains(eventType, "pubsub"):
+		topic = resource
+	case bucket == "" && strings.Contains(eventType, "storage"):
+		if _, name, ok := strings.Cut(resource, "/buckets/"); ok {
+			bucket = name
+		} else {
+			bucket = resource
+		}
+	}
+
+	if topic != "" {
+		r.Relationships = append(r.Relationships, v1.RelationshipResult{
+			ConfigExternalID:  selfExternalID,
+			RelatedExternalID: v1.ExternalID{ExternalID: topic, ConfigType: v1.GCPPubSubTopic},
+			Relationship:      "FunctionTopic",
+		})
+	}
+	if bucket != "" {
+		r.Relationships = append(r.Relationships, v1.RelationshipResult{
+			ConfigExternalID:  selfExternalID,
+			RelatedExternalID: v1.ExternalID{ExternalID: bucket, ConfigType: v1.GCSBucket},
+			Relationship:      "FunctionBucket",
+		})
+	}
+
+	return r
+}
+
+// resolveGCPPubSubSubscriptionRelationships places a subscription under its topic
+// and links push subscriptions to the Cloud Run service they deliver to.
+func resolveGCPPubSubSubscriptionRelationships(rd ResourceData) (r relationshipResults) {
+	p := rawData(rd.Raw)
+
+	if topic := gabsString(p, "topic"); topic != "" && topic != "_deleted-topic_" {
+		r.Parents = append(r.Parents, v1.ConfigExternalKey{
+			ExternalID: topic,
+			Type:       v1.GCPPubSubTopic,
+			ScraperID:  "all",
+		})
+	}
+
+	selfExternalID := v1.ExternalID{ExternalID: lo.CoalesceOrEmpty(rd.ID, rd.Name), ConfigType: v1.GCPPubSubSubscription}
+	if host := urlHost(gabsString(p, "pushConfig", "pushEndpoint")); host != "" && selfExternalID.ExternalID != "" {
+		r.Relationships = append(r.Relationships, v1.RelationshipResult{
+			ConfigExternalID:  selfExternalID,
+			RelatedExternalID: v1.ExternalID{ExternalID: host, ConfigType: v1.GCPCloudRunService},
+			Relationship:      "SubscriptionCloudRunService",
+		})
+	}
+
+	return r
+}
+
+func rawData(data *structpb.Struct) *gabs.Container {
+	b, _ := data.MarshalJSON()
+	p, _ := gabs.ParseJSON(b)
+	return p
+}
+
+func gabsString(c *gabs.Container, hierarchy ...string) string {
+	s, _ := c.Search(hierarchy...).Data().(string)
+	return s
+}
+
+func urlHost(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// cloudRunServiceScope returns the project and location of a Cloud Run service,
+// used to qualify references made by short name.
+func cloudRunServiceScope(name string, p *gabs.Container) (project, location string) {
+	// projects/<project>/locations/<location>/services/<service>
+	if parts := strings.Split(name, "/"); len(parts) >= 4 && parts[0] == "projects" && parts[2] == "locations" {
+		return parts[1], parts[3]
+	}
+	// The knative namespace is the project number
+	return gabsString(p, "metadata", "namespace"), gabsString(p, "metadata", "labels", "cloud.googleapis.com/location")
+}
+
+func qualifyConnector(connector, project, location string) string {
+	if strings.HasPrefix(connector, "projects/") || project == "" || location == "" {
+		return connector
+	}
+	return fmt.Sprintf("projects/%s/locations/%s/connectors/%s", project, location, connector)
+}
+
+// qualifySecret returns the resource name of a secret referenced either by
+// name or by resource name, optionally pinned to a version.
+func qualifySecret(secret, project string) string {
+	if before, _, ok := strings.Cut(secret, "/versions/"); ok {
+		secret = before
+	}
+	if strings.HasPrefix(secret, "projects/") || project == "" {
+		return secret
+	}
+	return fmt.Sprintf("projects/%s/secrets/%s", project, secret)
+}
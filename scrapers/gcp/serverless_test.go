@@ -0,0 +1,129 @@
+package gcp
+
+import (
+	"cloud.google.com/go/asset/apiv1/assetpb"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+var _ = Describe("serverless relationships", func() {
+	namedResourceData := func(assetName, assetType string, fields map[string]any) ResourceData {
+		data, err := structpb.NewStruct(fields)
+		Expect(err).ToNot(HaveOccurred())
+		return parseResourceData(&assetpb.Asset{Name: assetName, AssetType: assetType, Resource: &assetpb.Resource{Data: data}})
+	}
+	resourceData := func(assetType string, fields map[string]any) ResourceData {
+		return namedResourceData("", assetType, fields)
+	}
+
+	related := func(r relationshipResults) map[string]v1.ExternalID {
+		out := map[string]v1.ExternalID{}
+		for _, rel := range r.Relationships {
+			out[rel.Relationship+"/"+rel.RelatedExternalID.ExternalID] = rel.RelatedExternalID
+		}
+		return out
+	}
+
+	It("links a v2 Cloud Run service to its service account, connector and secrets", func() {
+		rd := resourceData(cloudRunServiceAssetType, map[string]any{
+			"name": "projects/proj/locations/us-central1/services/api",
+			"uri":  "https://api-abc123-uc.a.run.app",
+			"template": map[string]any{
+				"serviceAccount": "api@proj.iam.gserviceaccount.com",
+				"vpcAccess":      map[string]any{"connector": "projects/proj/locations/us-central1/connectors/vpc"},
+				"containers": []any{map[string]any{
+					"env": []any{
+						map[string]any{"name": "DB_PASSWORD", "valueSource": map[string]any{"secretKeyRef": map[string]any{"secret": "db-password", "version": "latest"}}},
+						map[string]any{"name": "PLAIN", "value": "x"},
+					},
+				}},
+				"volumes": []any{map[string]any{"secret": map[string]any{"secret": "projects/other/secrets/tls"}}},
+			},
+		})
+		Expect(rd.Aliases).To(ContainElement("api-abc123-uc.a.run.app"))
+
+		r := RelationshipResolver(v1.GCPCloudRunService, rd)
+		Expect(r.Relationships).To(HaveLen(4))
+		Expect(related(r)).To(HaveKeyWithValue("CloudRunServiceServiceAccount/api@proj.iam.gserviceaccount.com",
+			v1.ExternalID{ExternalID: "api@proj.iam.gserviceaccount.com", ConfigType: v1.IAMServiceAccount}))
+		Expect(related(r)).To(HaveKey("CloudRunServiceConnector/projects/proj/locations/us-central1/connectors/vpc"))
+		Expect(related(r)).To(HaveKey("CloudRunServiceSecret/projects/proj/secrets/db-password"))
+		Expect(related(r)).To(HaveKey("CloudRunServiceSecret/projects/other/secrets/tls"))
+	})
+
+	It("links a knative shaped Cloud Run service named after its asset", func() {
+		rd := namedResourceData("//run.googleapis.com/projects/proj/locations/europe-west1/services/worker", cloudRunServiceAssetType, map[string]any{
+			"metadata": map[string]any{
+				"namespace": "123456",
+				"labels":    map[string]any{"cloud.googleapis.com/location": "europe-west1"},
+			},
+			"spec": map[string]any{"template": map[string]any{
+				"metadata": map[string]any{"annotations": map[string]any{"run.googleapis.com/vpc-access-connector": "vpc"}},
+				"spec": map[string]any{
+					"serviceAccountName": "worker@proj.iam.gserviceaccount.com",
+					"volumes":            []any{map[string]any{"secret": map[string]any{"secretName": "creds"}}},
+				},
+			}},
+			"status": map[string]any{"url": "https://worker-xyz-ew.a.run.app"},
+		})
+		Expect(rd.Name).To(Equal("projects/proj/locations/europe-west1/services/worker"))
+		Expect(rd.Aliases).To(ContainElement("worker-xyz-ew.a.run.app"))
+
+		r := RelationshipResolver(v1.GCPCloudRunService, rd)
+		Expect(related(r)).To(HaveKey("CloudRunServiceServiceAccount/worker@proj.iam.gserviceaccount.com"))
+		Expect(related(r)).To(HaveKey("CloudRunServiceConnector/projects/proj/locations/europe-west1/connectors/vpc"))
+		Expect(related(r)).To(HaveKey("CloudRunServiceSecret/projects/proj/secrets/creds"))
+	})
+
+	DescribeTable("links functions to their trigger and service account",
+		func(configType string, fields map[string]any, expected v1.ExternalID, relationship string) {
+			fields["name"] = "projects/proj/locations/us-central1/functions/fn"
+			r := RelationshipResolver(configType, resourceData("cloudfunctions.googleapis.com/Function", fields))
+			Expect(related(r)).To(HaveKeyWithValue(relationship+"/"+expected.ExternalID, expected))
+			Expect(related(r)).To(HaveKey("FunctionServiceAccount/fn@proj.iam.gserviceaccount.com"))
+		},
+		Entry("1st gen pubsub trigger", v1.GCPCloudFunction, map[string]any{
+			"serviceAccountEmail": "fn@proj.iam.gserviceaccount.com",
+			"eventTrigger":        map[string]any{"eventType": "google.pubsub.topic.publish", "resource": "projects/proj/topics/orders"},
+		}, v1.ExternalID{ExternalID: "projects/proj/topics/orders", ConfigType: v1.GCPPubSubTopic}, "FunctionTopic"),
+		Entry("1st gen storage trigger", v1.GCPCloudFunction, map[string]any{
+			"serviceAccountEmail": "fn@proj.iam.gserviceaccount.com",
+			"eventTrigger":        map[string]any{"eventType": "google.storage.object.finalize", "resource": "projects/_/buckets/uploads"},
+		}, v1.ExternalID{ExternalID: "uploads", ConfigType: v1.GCSBucket}, "FunctionBucket"),
+		Entry("2nd gen pubsub trigger", v1.GCPFunction, map[string]any{
+			"serviceConfig": map[string]any{"serviceAccountEmail": "fn@proj.iam.gserviceaccount.com"},
+			"eventTrigger":  map[string]any{"eventType": "google.cloud.pubsub.topic.v1.messagePublished", "pubsubTopic": "projects/proj/topics/orders"},
+		}, v1.ExternalID{ExternalID: "projects/proj/topics/orders", ConfigType: v1.GCPPubSubTopic}, "FunctionTopic"),
+		Entry("2nd gen storage trigger", v1.GCPFunction, map[string]any{
+			"serviceConfig": map[string]any{"serviceAccountEmail": "fn@proj.iam.gserviceaccount.com"},
+			"eventTrigger": map[string]any{
+				"eventType":    "google.cloud.storage.object.v1.finalized",
+				"eventFilters": []any{map[string]any{"attribute": "bucket", "value": "uploads"}},
+			},
+		}, v1.ExternalID{ExternalID: "uploads", ConfigType: v1.GCSBucket}, "FunctionBucket"),
+	)
+
+	It("places a push subscription under its topic and links it to the push endpoint", func() {
+		r := RelationshipResolver(v1.GCPPubSubSubscription, resourceData("pubsub.googleapis.com/Subscription", map[string]any{
+			"name":       "projects/proj/subscriptions/orders-push",
+			"topic":      "projects/proj/topics/orders",
+			"pushConfig": map[string]any{"pushEndpoint": "https://API-abc123-uc.a.run.app/events"},
+		}))
+
+		Expect(r.Parents).To(ConsistOf(v1.ConfigExternalKey{ExternalID: "projects/proj/topics/orders", Type: v1.GCPPubSubTopic, ScraperID: "all"}))
+		Expect(related(r)).To(HaveKeyWithValue("SubscriptionCloudRunService/api-abc123-uc.a.run.app",
+			v1.ExternalID{ExternalID: "api-abc123-uc.a.run.app", ConfigType: v1.GCPCloudRunService}))
+	})
+
+	It("does not link pull subscriptions to a service", func() {
+		r := RelationshipResolver(v1.GCPPubSubSubscription, resourceData("pubsub.googleapis.com/Subscription", map[string]any{
+			"name":  "projects/proj/subscriptions/orders-pull",
+			"topic": "_deleted-topic_",
+		}))
+		Expect(r.Parents).To(BeEmpty())
+		Expect(r.Relationships).To(BeEmpty())
+	})
+})
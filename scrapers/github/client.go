@@ -57,7 +57,7 @@ func NewGitHubClient(ctx api.ScrapeContext, config v1.GitHub, owner, repo string
 		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 		tc = oauth2.NewClient(ctx, ts)
 	}
-	client := github.NewClient(tc)
+	client := github.NewClient(withHARCollector(ctx, tc))
 
 	return &GitHubClient{
 		ScrapeContext: ctx,
@@ -339,6 +339,16 @@ func (c *GitHubClient) isAuthenticatedOwner(ctx context.Context, owner string) b
 	return strings.EqualFold(user.GetLogin(), owner)
 }
 
+// withHARCollector records the requests of the client into the HAR collector of the scrape, if any,
+// or serves them from the recorded responses when replaying.
+func withHARCollector(ctx api.ScrapeContext, client *gohttp.Client) *gohttp.Client {
+	if client == nil {
+		client = &gohttp.Client{}
+	}
+	client.Transport = ctx.HTTPTransport(client.Transport)
+	return client
+}
+
 type GitHubActionsClient struct {
 	*github.Client
 	api.ScrapeContext
@@ -363,7 +373,7 @@ func NewGitHubActionsClient(ctx api.ScrapeContext, gha v1.GitHubActions) (*GitHu
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	client := github.NewClient(withHARCollector(ctx, tc))
 
 	return &GitHubActionsClient{
 		ScrapeContext: ctx,
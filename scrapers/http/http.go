@@ -8,7 +8,6 @@ import (
 	commonsHTTP "github.com/flanksource/commons/http"
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
-	"github.com/flanksource/duty/connection"
 	"github.com/flanksource/gomplate/v3"
 	"github.com/samber/lo"
 )
@@ -58,7 +57,7 @@ func scrape(ctx api.ScrapeContext, spec v1.HTTP) (v1.ScrapeResults, error) {
 
 	ctx.Logger.V(3).Infof("scraping HTTP: %s", spec.HTTPConnection)
 
-	client, err := connection.CreateHTTPClient(ctx, *conn)
+	client, err := ctx.NewHTTPClient(*conn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create http client: %w", err)
 	}
@@ -260,6 +260,8 @@ func consumeResources(ctx job.JobRuntime, scrapeConfig v1.ScrapeConfig, config v
 	}
 
 	if len(deletedResources) > 0 {
+		kubernetes.ForgetRollouts(cc, deletedResources)
+
 		deletedResourceIDs := lo.Map(deletedResources, func(item *unstructured.Unstructured, _ int) string {
 			return string(item.GetUID())
 		})
@@ -0,0 +1,205 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	gatewayAPIGroup = "gateway.networking.k8s.io"
+	istioAPIGroup   = "networking.istio.io"
+)
+
+var gatewayRouteKinds = []string{"HTTPRoute", "GRPCRoute", "TLSRoute", "TCPRoute", "UDPRoute"}
+
+// trafficRouting links Gateway API routes and Istio routing rules into
+// the path traffic takes from the edge to a Service:
+//
+//	Gateway -> HTTPRoute/GRPCRoute/... -> Service
+//	Gateway -> VirtualService -> Service <- DestinationRule
+//
+// These are relationships rather than parents and children, so routes and
+// Services stay under their namespace.
+type trafficRouting struct{}
+
+func init() {
+	relationshipLookupHooks = append(relationshipLookupHooks, trafficRouting{})
+	propertyLookupHooks = append(propertyLookupHooks, trafficRouting{})
+}
+
+func isGatewayRoute(obj *unstructured.Unstructured) bool {
+	return strings.HasPrefix(obj.GetAPIVersion(), gatewayAPIGroup+"/") && lo.Contains(gatewayRouteKinds, obj.GetKind())
+}
+
+func isIstioObject(obj *unstructured.Unstructured, kind string) bool {
+	return strings.HasPrefix(obj.GetAPIVersion(), istioAPIGroup+"/") && obj.GetKind() == kind
+}
+
+func (trafficRouting) RelationshipLookupHook(ctx *KubernetesContext, obj *unstructured.Unstructured) v1.RelationshipResults {
+	var gateways, services []v1.ExternalID
+	switch {
+	case isGatewayRoute(obj):
+		// Routes attach themselves to gateways, which have no reference to their routes
+		for _, ref := range gabs.Wrap(obj.Object).S("spec", "parentRefs").Children() {
+			group, _ := ref.S("group").Data().(string)
+			kind, _ := ref.S("kind").Data().(string)
+			name, _ := ref.S("name").Data().(string)
+			namespace, _ := ref.S("namespace").Data().(string)
+			if name == "" || lo.CoalesceOrEmpty(group, gatewayAPIGroup) != gatewayAPIGroup || lo.CoalesceOrEmpty(kind, "Gateway") != "Gateway" {
+				continue
+			}
+			gateways = append(gateways, kubernetesRef(ctx, "Gateway", lo.CoalesceOrEmpty(namespace, obj.GetNamespace()), name))
+		}
+
+		for _, rule := range gabs.Wrap(obj.Object).S("spec", "rules").Children() {
+			for _, ref := range rule.S("backendRefs").Children() {
+				group, _ := ref.S("group").Data().(string)
+				kind, _ := ref.S("kind").Data().(string)
+				name, _ := ref.S("name").Data().(string)
+				namespace, _ := ref.S("namespace").Data().(string)
+				if name == "" || group != "" || lo.CoalesceOrEmpty(kind, "Service") != "Service" {
+					continue
+				}
+				services = append(services, kubernetesRef(ctx, "Service", lo.CoalesceOrEmpty(namespace, obj.GetNamespace()), name))
+			}
+		}
+
+	case isIstioObject(obj, "VirtualService"):
+		refs, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "gateways")
+		for _, gateway := range refs {
+			// The reserved "mesh" gateway refers to the sidecars, not to a Gateway object
+			if gateway == "mesh" {
+				continue
+			}
+			namespace, name, ok := strings.Cut(gateway, "/")
+			if !ok {
+				namespace, name = obj.GetNamespace(), gateway
+			}
+			gateways = append(gateways, kubernetesRef(ctx, "Gateway", namespace, name))
+		}
+
+		for _, destination := range istioDestinations(obj) {
+			if namespace, name, ok := istioServiceHost(destination.host, obj.GetNamespace()); ok {
+				services = append(services, kubernetesRef(ctx, "Service", namespace, name))
+			}
+		}
+
+	case isIstioObject(obj, "DestinationRule"):
+		host, _, _ := unstructured.NestedString(obj.Object, "spec", "host")
+		if namespace, name, ok := istioServiceHost(host, obj.GetNamespace()); ok {
+			services = append(services, kubernetesRef(ctx, "Service", namespace, name))
+		}
+	}
+
+	byExternalID := func(id v1.ExternalID) string { return id.ExternalID }
+
+	var relationships v1.RelationshipResults
+	for _, gateway := range lo.UniqBy(gateways, byExternalID) {
+		relationships = append(relationships, v1.RelationshipResult{
+			ConfigExternalID: gateway,
+			RelatedConfigID:  string(obj.GetUID()),
+			Relationship:     "Gateway" + obj.GetKind(),
+		})
+	}
+	for _, service := range lo.UniqBy(services, byExternalID) {
+		relationships = append(relationships, v1.RelationshipResult{
+			ConfigID:          string(obj.GetUID()),
+			RelatedExternalID: service,
+			Relationship:      obj.GetKind() + "Service",
+		})
+	}
+	return relationships
+}
+
+func (trafficRouting) PropertyLookupHook(ctx *KubernetesContext, obj *unstructured.Unstructured) types.Properties {
+	var props types.Properties
+	switch {
+	case isGatewayRoute(obj):
+		if hostnames, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "hostnames"); len(hostnames) > 0 {
+			props = append(props, &types.Property{Name: "hostnames", Label: "Hostnames", Text: strings.Join(hostnames, ", ")})
+		}
+
+	case isIstioObject(obj, "VirtualService"):
+		if hosts, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "hosts"); len(hosts) > 0 {
+			props = append(props, &types.Property{Name: "hosts", Label: "Hosts", Text: strings.Join(hosts, ", ")})
+		}
+
+		var subsets []string
+		for _, destination := range istioDestinations(obj) {
+			if destination.subset != "" {
+				subsets = append(subsets, fmt.Sprintf("%s/%s", destination.host, destination.subset))
+			}
+		}
+		if subsets = lo.Uniq(subsets); len(subsets) > 0 {
+			props = append(props, &types.Property{Name: "subsets", Label: "Subsets", Text: strings.Join(subsets, ", ")})
+		}
+
+	case isIstioObject(obj, "DestinationRule"):
+		var subsets []string
+		for _, subset := range gabs.Wrap(obj.Object).S("spec", "subsets").Children() {
+			if name, ok := subset.S("name").Data().(string); ok && name != "" {
+				subsets = append(subsets, name)
+			}
+		}
+		if len(subsets) > 0 {
+			props = append(props, &types.Property{Name: "subsets", Label: "Subsets", Text: strings.Join(subsets, ", ")})
+		}
+	}
+
+	return props
+}
+
+func kubernetesRef(ctx *KubernetesContext, kind, namespace, name string) v1.ExternalID {
+	return v1.ExternalID{
+		ConfigType: ConfigTypePrefix + kind,
+		ExternalID: KubernetesAlias(ctx.ClusterName(), kind, namespace, name),
+	}
+}
+
+type istioDestination struct {
+	host, subset string
+}
+
+// istioDestinations returns the route destinations of the http, tcp and tls routes of a VirtualService
+func istioDestinations(obj *unstructured.Unstructured) []istioDestination {
+	spec := gabs.Wrap(obj.Object).S("spec")
+
+	var destinations []istioDestination
+	for _, protocol := range []string{"http", "tcp", "tls"} {
+		for _, route := range spec.S(protocol).Children() {
+			for _, target := range route.S("route").Children() {
+				host, _ := target.S("destination", "host").Data().(string)
+				subset, _ := target.S("destination", "subset").Data().(string)
+				if host != "" {
+					destinations = append(destinations, istioDestination{host: host, subset: subset})
+				}
+			}
+		}
+	}
+	return destinations
+}
+
+// istioServiceHost resolves an Istio host to a Kubernetes Service.
+// Short names are relative to the namespace of the rule, every other host
+// must be a fully qualified service name (<name>.<namespace>.svc[.<domain>]).
+// Wildcards and external hosts are not resolved.
+func istioServiceHost(host, namespace string) (string, string, bool) {
+	if host == "" || strings.Contains(host, "*") {
+		return "", "", false
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) == 1 {
+		return namespace, host, true
+	}
+	if len(parts) >= 3 && parts[2] == "svc" {
+		return parts[1], parts[0], true
+	}
+	return "", "", false
+}
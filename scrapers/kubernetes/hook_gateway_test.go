@@ -0,0 +1,153 @@
+package kubernetes
+
+import (
+	v1 "github.com/flanksource/config-db/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("trafficRouting", func() {
+	ctx := &KubernetesContext{cluster: v1.ScrapeResult{Name: "test-cluster"}}
+	hook := trafficRouting{}
+
+	ref := func(kind, namespace, name string) v1.ExternalID {
+		return v1.ExternalID{
+			ConfigType: ConfigTypePrefix + kind,
+			ExternalID: KubernetesAlias("test-cluster", kind, namespace, name),
+		}
+	}
+
+	from := func(gateway v1.ExternalID, relationship string) v1.RelationshipResult {
+		return v1.RelationshipResult{ConfigExternalID: gateway, RelatedConfigID: "uid", Relationship: relationship}
+	}
+
+	to := func(service v1.ExternalID, relationship string) v1.RelationshipResult {
+		return v1.RelationshipResult{ConfigID: "uid", RelatedExternalID: service, Relationship: relationship}
+	}
+
+	object := func(apiVersion, kind, namespace, name string, spec map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   map[string]any{"name": name, "namespace": namespace, "uid": "uid"},
+			"spec":       spec,
+		}}
+	}
+
+	Context("Gateway API routes", func() {
+		route := object("gateway.networking.k8s.io/v1", "HTTPRoute", "app", "web", map[string]any{
+			"hostnames": []any{"example.com", "www.example.com"},
+			"parentRefs": []any{
+				map[string]any{"name": "edge", "namespace": "infra"},
+				map[string]any{"name": "internal"},
+				map[string]any{"name": "mesh", "group": "", "kind": "Service"},
+			},
+			"rules": []any{
+				map[string]any{"backendRefs": []any{
+					map[string]any{"name": "web", "port": int64(80)},
+					map[string]any{"name": "web-canary", "kind": "Service", "port": int64(80)},
+				}},
+				map[string]any{"backendRefs": []any{
+					map[string]any{"name": "api", "namespace": "backend"},
+					map[string]any{"name": "bucket", "group": "storage.example.com", "kind": "Bucket"},
+				}},
+			},
+		})
+
+		It("links the route to its gateways and backend services", func() {
+			Expect(hook.RelationshipLookupHook(ctx, route)).To(Equal(v1.RelationshipResults{
+				from(ref("Gateway", "infra", "edge"), "GatewayHTTPRoute"),
+				from(ref("Gateway", "app", "internal"), "GatewayHTTPRoute"),
+				to(ref("Service", "app", "web"), "HTTPRouteService"),
+				to(ref("Service", "app", "web-canary"), "HTTPRouteService"),
+				to(ref("Service", "backend", "api"), "HTTPRouteService"),
+			}))
+		})
+
+		It("exposes the hostnames", func() {
+			props := hook.PropertyLookupHook(ctx, route)
+			Expect(props).To(HaveLen(1))
+			Expect(props[0].Name).To(Equal("hostnames"))
+			Expect(props[0].Text).To(Equal("example.com, www.example.com"))
+		})
+
+		It("ignores routes of other API groups", func() {
+			other := object("example.com/v1", "HTTPRoute", "app", "web", route.Object["spec"].(map[string]any))
+			Expect(hook.RelationshipLookupHook(ctx, other)).To(BeEmpty())
+			Expect(hook.PropertyLookupHook(ctx, other)).To(BeEmpty())
+		})
+	})
+
+	Context("Istio", func() {
+		virtualService := object("networking.istio.io/v1beta1", "VirtualService", "bookinfo", "reviews", map[string]any{
+			"hosts":    []any{"reviews"},
+			"gateways": []any{"mesh", "bookinfo-gateway", "istio-system/ingress"},
+			"http": []any{
+				map[string]any{"route": []any{
+					map[string]any{"destination": map[string]any{"host": "reviews", "subset": "v1"}, "weight": int64(90)},
+					map[string]any{"destination": map[string]any{"host": "reviews", "subset": "v2"}, "weight": int64(10)},
+				}},
+				map[string]any{"route": []any{
+					map[string]any{"destination": map[string]any{"host": "ratings.shared.svc.cluster.local"}},
+					map[string]any{"destination": map[string]any{"host": "api.example.com"}},
+				}},
+			},
+			"tcp": []any{
+				map[string]any{"route": []any{
+					map[string]any{"destination": map[string]any{"host": "mysql.db.svc"}},
+				}},
+			},
+		})
+
+		It("links the virtual service to its gateways and destination services", func() {
+			Expect(hook.RelationshipLookupHook(ctx, virtualService)).To(Equal(v1.RelationshipResults{
+				from(ref("Gateway", "bookinfo", "bookinfo-gateway"), "GatewayVirtualService"),
+				from(ref("Gateway", "istio-system", "ingress"), "GatewayVirtualService"),
+				to(ref("Service", "bookinfo", "reviews"), "VirtualServiceService"),
+				to(ref("Service", "shared", "ratings"), "VirtualServiceService"),
+				to(ref("Service", "db", "mysql"), "VirtualServiceService"),
+			}))
+		})
+
+		It("exposes the hosts and subsets", func() {
+			props := hook.PropertyLookupHook(ctx, virtualService)
+			Expect(props).To(HaveLen(2))
+			Expect(props[0].Text).To(Equal("reviews"))
+			Expect(props[1].Name).To(Equal("subsets"))
+			Expect(props[1].Text).To(Equal("reviews/v1, reviews/v2"))
+		})
+
+		It("links a destination rule to its service", func() {
+			rule := object("networking.istio.io/v1", "DestinationRule", "bookinfo", "reviews", map[string]any{
+				"host": "reviews.bookinfo.svc.cluster.local",
+				"subsets": []any{
+					map[string]any{"name": "v1", "labels": map[string]any{"version": "v1"}},
+					map[string]any{"name": "v2", "labels": map[string]any{"version": "v2"}},
+				},
+			})
+			Expect(hook.RelationshipLookupHook(ctx, rule)).To(Equal(v1.RelationshipResults{
+				to(ref("Service", "bookinfo", "reviews"), "DestinationRuleService"),
+			}))
+
+			props := hook.PropertyLookupHook(ctx, rule)
+			Expect(props).To(HaveLen(1))
+			Expect(props[0].Text).To(Equal("v1, v2"))
+		})
+	})
+
+	DescribeTable("istioServiceHost",
+		func(host, expectedNamespace, expectedName string, expectedOK bool) {
+			namespace, name, ok := istioServiceHost(host, "default")
+			Expect(ok).To(Equal(expectedOK))
+			Expect(namespace).To(Equal(expectedNamespace))
+			Expect(name).To(Equal(expectedName))
+		},
+		Entry("short name", "reviews", "default", "reviews", true),
+		Entry("service domain", "reviews.prod.svc", "prod", "reviews", true),
+		Entry("cluster domain", "reviews.prod.svc.cluster.local", "prod", "reviews", true),
+		Entry("external host", "api.example.com", "", "", false),
+		Entry("wildcard", "*.prod.svc.cluster.local", "", "", false),
+		Entry("empty", "", "", "", false),
+	)
+})
@@ -0,0 +1,331 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/collections/syncmap"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db"
+	"github.com/flanksource/duty/models"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	helmReleaseSecretType = "helm.sh/release.v1"
+	helmReleaseType       = ConfigTypePrefix + "HelmRelease"
+
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+// helm relates a Helm release, decoded from the release secrets, to the objects it rendered.
+// These are relationships rather than parents and children, so the objects stay under
+// their owners and namespaces.
+type helm struct{}
+
+func init() {
+	relationshipLookupHooks = append(relationshipLookupHooks, helm{})
+}
+
+func (helm helm) RelationshipLookupHook(ctx *KubernetesContext, obj *unstructured.Unstructured) v1.RelationshipResults {
+	// Flux manages its releases through its own HelmRelease object
+	if obj.GetLabels()["helm.toolkit.fluxcd.io/name"] != "" {
+		return nil
+	}
+
+	name := obj.GetAnnotations()[helmReleaseNameAnnotation]
+	namespace := obj.GetAnnotations()[helmReleaseNamespaceAnnotation]
+	if name == "" || namespace == "" {
+		return nil
+	}
+
+	return v1.RelationshipResults{{
+		ConfigExternalID: v1.ExternalID{
+			ConfigType: helmReleaseType,
+			ExternalID: helmReleaseExternalID(ctx.ClusterName(), namespace, name),
+		},
+		RelatedConfigID: string(obj.GetUID()),
+		Relationship:    "HelmRelease" + obj.GetKind(),
+	}}
+}
+
+// helmReleaseExternalID is distinct from the alias of Flux HelmRelease objects,
+// which usually share the name of the release they manage.
+func helmReleaseExternalID(cluster, namespace, name string) string {
+	return fmt.Sprintf("Kubernetes/%s/Helm/%s/%s", cluster, namespace, name)
+}
+
+func isHelmReleaseSecret(obj *unstructured.Unstructured) bool {
+	if obj.GetKind() != "Secret" || obj.GetAPIVersion() != "v1" {
+		return false
+	}
+	secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+	return secretType == helmReleaseSecretType
+}
+
+// helmRelease is the subset of a Helm release record that is scraped.
+// The rendered manifest is deliberately left out.
+type helmRelease struct {
+	Name      string          `json:"name"`
+	Namespace string          `json:"namespace"`
+	Version   int             `json:"version"`
+	Info      helmReleaseInfo `json:"info"`
+	Chart     struct {
+		Metadata helmChartMetadata `json:"metadata"`
+	} `json:"chart"`
+	Config map[string]any `json:"config"`
+
+	// uid of the secret the release was decoded from
+	uid string
+}
+
+type helmReleaseInfo struct {
+	FirstDeployed time.Time `json:"first_deployed"`
+	LastDeployed  time.Time `json:"last_deployed"`
+	Deleted       time.Time `json:"deleted"`
+	Description   string    `json:"description"`
+	Status        string    `json:"status"`
+}
+
+type helmChartMetadata struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	AppVersion  string `json:"appVersion,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// decodeHelmRelease decodes the release stored in a Helm release secret.
+// Helm stores the release as base64 encoded gzipped json, which is
+// base64 encoded once more as secret data.
+func decodeHelmRelease(obj *unstructured.Unstructured) (*helmRelease, error) {
+	data, _, _ := unstructured.NestedString(obj.Object, "data", "release")
+	if data == "" {
+		return nil, fmt.Errorf("secret has no release data")
+	}
+
+	encoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret data: %w", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+
+	if bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress release: %w", err)
+		}
+		defer reader.Close()
+
+		if raw, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("failed to decompress release: %w", err)
+		}
+	}
+
+	var release helmRelease
+	if err := json.Unmarshal(raw, &release); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal release: %w", err)
+	}
+	release.uid = string(obj.GetUID())
+	return &release, nil
+}
+
+type helmRevision struct {
+	version int
+	values  string
+}
+
+// helmReleaseValues holds the values of the last revision seen for every release
+// so that incremental scrapes, which only see the new revision, can diff against it.
+var helmReleaseValues syncmap.SyncMap[string, helmRevision]
+
+// cloneHelmReleaseValues returns a copy of the values of the last revisions, for dry runs.
+func cloneHelmReleaseValues(values *syncmap.SyncMap[string, helmRevision]) *syncmap.SyncMap[string, helmRevision] {
+	c := &syncmap.SyncMap[string, helmRevision]{}
+	values.Range(func(id string, revision helmRevision) bool {
+		c.Store(id, revision)
+		return true
+	})
+	return c
+}
+
+// helmReleaseResults turns Helm release secrets into one config item per release,
+// built from its latest revision, and one change per revision.
+func helmReleaseResults(ctx *KubernetesContext, secrets []*unstructured.Unstructured) (results v1.ScrapeResults, changes v1.ScrapeResults) {
+	releases := map[string][]helmRelease{}
+	for _, secret := range secrets {
+		release, err := decodeHelmRelease(secret)
+		if err != nil {
+			results.Errorf(err, "failed to decode helm release secret %s/%s", secret.GetNamespace(), secret.GetName())
+			continue
+		}
+
+		id := helmReleaseExternalID(ctx.ClusterName(), release.Namespace, release.Name)
+		releases[id] = append(releases[id], *release)
+	}
+
+	// Values often hold credentials, so revisions are only diffed when they are scraped
+	var diff func(values, prev string) (string, error)
+	if ctx.config.HelmValues {
+		diff = func(values, prev string) (string, error) {
+			return db.GenerateDiff(ctx.DutyContext(), values, prev)
+		}
+	}
+	seen := api.DryRunState(ctx.ScrapeContext, "helmReleaseValues", &helmReleaseValues, cloneHelmReleaseValues)
+
+	for id, revisions := range releases {
+		sort.Slice(revisions, func(i, j int) bool { return revisions[i].Version < revisions[j].Version })
+
+		// Helm marks the previous revision as superseded on upgrades, so an incremental
+		// scrape may only see an older revision which must not replace the latest one.
+		latest := revisions[len(revisions)-1]
+		if revision, ok := seen.Load(id); !ok || revision.version <= latest.Version {
+			result := helmReleaseResult(ctx, id, latest)
+			result.BaseScraper = ctx.config.BaseScraper
+			results = append(results, result)
+		}
+
+		releaseChanges, err := helmReleaseChanges(seen, id, revisions, diff)
+		if err != nil {
+			results.Errorf(err, "failed to generate helm release changes for %s", id)
+		}
+		if len(releaseChanges) > 0 {
+			changes = append(changes, v1.ScrapeResult{
+				BaseScraper: ctx.config.BaseScraper,
+				Changes:     releaseChanges,
+			})
+		}
+	}
+
+	return results, changes
+}
+
+func helmReleaseResult(ctx *KubernetesContext, id string, release helmRelease) v1.ScrapeResult {
+	result := v1.ScrapeResult{
+		ID:          id,
+		Name:        release.Name,
+		ConfigClass: "HelmRelease",
+		Type:        helmReleaseType,
+		Status:      release.Info.Status,
+		Description: release.Info.Description,
+		CreatedAt:   lo.ToPtr(release.Info.FirstDeployed),
+		Config:      helmReleaseConfig(release, ctx.config.HelmValues),
+		Labels: map[string]string{
+			"chart":   release.Chart.Metadata.Name,
+			"version": release.Chart.Metadata.Version,
+		},
+		Parents: []v1.ConfigExternalKey{{
+			Type:       ConfigTypePrefix + "Namespace",
+			ExternalID: KubernetesAlias(ctx.ClusterName(), "Namespace", "", release.Namespace),
+		}},
+	}
+
+	switch release.Info.Status {
+	case "deployed":
+		result.Health = models.HealthHealthy
+		result.Ready = true
+	case "failed":
+		result.Health = models.HealthUnhealthy
+	case "uninstalled":
+		result.DeletedAt = lo.ToPtr(lo.CoalesceOrEmpty(release.Info.Deleted, release.Info.LastDeployed))
+		result.DeleteReason = v1.DeletedReasonFromAttribute
+	default:
+		// pending-install, pending-upgrade, pending-rollback and uninstalling
+		result.Health = models.HealthUnknown
+	}
+
+	return result
+}
+
+func helmReleaseConfig(release helmRelease, includeValues bool) map[string]any {
+	config := map[string]any{
+		"name":          release.Name,
+		"namespace":     release.Namespace,
+		"revision":      release.Version,
+		"status":        release.Info.Status,
+		"description":   release.Info.Description,
+		"firstDeployed": release.Info.FirstDeployed,
+		"lastDeployed":  release.Info.LastDeployed,
+		"chart":         release.Chart.Metadata,
+	}
+	if includeValues {
+		config["values"] = lo.CoalesceMapOrEmpty(release.Config)
+	}
+	return config
+}
+
+// helmReleaseChanges returns one change per revision, in order, with the diff
+// of the values against the previous revision, and records the last revision in tracked.
+// Values are neither diffed nor tracked when diff is nil.
+func helmReleaseChanges(tracked *syncmap.SyncMap[string, helmRevision], id string, revisions []helmRelease, diff func(values, prev string) (string, error)) ([]v1.ChangeResult, error) {
+	var changes []v1.ChangeResult
+	seen, hasSeen := tracked.Load(id)
+	prev, hasPrev := seen, hasSeen
+	for _, release := range revisions {
+		var values []byte
+		if diff != nil {
+			var err error
+			if values, err = json.Marshal(lo.CoalesceMapOrEmpty(release.Config)); err != nil {
+				return changes, fmt.Errorf("failed to marshal values of revision %d: %w", release.Version, err)
+			}
+		}
+
+		change := v1.ChangeResult{
+			ExternalID:       id,
+			ConfigType:       helmReleaseType,
+			ExternalChangeID: release.uid,
+			ChangeType:       helmChangeType(release),
+			Summary:          fmt.Sprintf("revision %d: %s-%s %s", release.Version, release.Chart.Metadata.Name, release.Chart.Metadata.Version, release.Info.Description),
+			Source:           "helm",
+			CreatedAt:        lo.ToPtr(release.Info.LastDeployed),
+			Details: map[string]any{
+				"revision":   release.Version,
+				"status":     release.Info.Status,
+				"chart":      release.Chart.Metadata.Name,
+				"version":    release.Chart.Metadata.Version,
+				"appVersion": release.Chart.Metadata.AppVersion,
+			},
+		}
+
+		if diff != nil && hasPrev && prev.version == release.Version-1 && prev.values != "" {
+			d, err := diff(string(values), prev.values)
+			if err != nil {
+				return changes, fmt.Errorf("failed to diff values of revision %d: %w", release.Version, err)
+			}
+			if d != "" {
+				change.Diff = &d
+			}
+		}
+
+		changes = append(changes, change)
+		prev, hasPrev = helmRevision{version: release.Version, values: string(values)}, true
+	}
+
+	if hasPrev && (!hasSeen || prev.version >= seen.version) {
+		tracked.Store(id, prev)
+	}
+	return changes, nil
+}
+
+func helmChangeType(release helmRelease) string {
+	switch {
+	case release.Version == 1:
+		return "HelmInstall"
+	case strings.HasPrefix(release.Info.Description, "Rollback"):
+		return "HelmRollback"
+	default:
+		return "HelmUpgrade"
+	}
+}
@@ -0,0 +1,198 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func makeHelmReleaseSecret(release map[string]any) *unstructured.Unstructured {
+	raw, err := json.Marshal(release)
+	Expect(err).ToNot(HaveOccurred())
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(raw)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       helmReleaseSecretType,
+		"metadata": map[string]any{
+			"name":      fmt.Sprintf("sh.helm.release.v1.%s.v%d", release["name"], release["version"]),
+			"namespace": release["namespace"],
+			"uid":       fmt.Sprintf("uid-%s-%d", release["name"], release["version"]),
+		},
+		"data": map[string]any{
+			"release": base64.StdEncoding.EncodeToString([]byte(encoded)),
+		},
+	}}
+}
+
+var _ = Describe("helm", func() {
+	ctx := &KubernetesContext{cluster: v1.ScrapeResult{Name: "test-cluster"}}
+	deployed := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	release := func(name string, version int, description string, values map[string]any) helmRelease {
+		return helmRelease{
+			Name:      name,
+			Namespace: "apps",
+			Version:   version,
+			Info:      helmReleaseInfo{LastDeployed: deployed.Add(time.Duration(version) * time.Hour), Description: description, Status: "superseded"},
+			Config:    values,
+			uid:       fmt.Sprintf("uid-%s-%d", name, version),
+		}
+	}
+
+	// diff returns both values so that the test can tell which revisions were compared
+	diff := func(values, prev string) (string, error) {
+		return prev + " -> " + values, nil
+	}
+
+	It("decodes a release secret", func() {
+		secret := makeHelmReleaseSecret(map[string]any{
+			"name":      "podinfo",
+			"namespace": "apps",
+			"version":   3,
+			"manifest":  "---\nkind: Deployment",
+			"config":    map[string]any{"replicaCount": 2},
+			"info": map[string]any{
+				"first_deployed": deployed.Format(time.RFC3339),
+				"last_deployed":  deployed.Add(time.Hour).Format(time.RFC3339),
+				"status":         "deployed",
+				"description":    "Upgrade complete",
+			},
+			"chart": map[string]any{
+				"metadata": map[string]any{"name": "podinfo", "version": "6.5.0", "appVersion": "6.5.0"},
+				"values":   map[string]any{"replicaCount": 1},
+			},
+		})
+		Expect(isHelmReleaseSecret(secret)).To(BeTrue())
+
+		decoded, err := decodeHelmRelease(secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Name).To(Equal("podinfo"))
+		Expect(decoded.Version).To(Equal(3))
+		Expect(decoded.Info.Status).To(Equal("deployed"))
+		Expect(decoded.Info.FirstDeployed).To(Equal(deployed))
+		Expect(decoded.Chart.Metadata.Version).To(Equal("6.5.0"))
+		Expect(decoded.Config).To(Equal(map[string]any{"replicaCount": float64(2)}))
+		Expect(decoded.uid).To(Equal("uid-podinfo-3"))
+
+		result := helmReleaseResult(ctx, "id", *decoded)
+		Expect(result.Type).To(Equal("Kubernetes::HelmRelease"))
+		Expect(result.Health).To(Equal(models.HealthHealthy))
+		Expect(result.Config).ToNot(HaveKey("manifest"))
+		Expect(result.Config).ToNot(HaveKey("values"))
+
+		withValues := &KubernetesContext{cluster: ctx.cluster, config: v1.Kubernetes{HelmValues: true}}
+		result = helmReleaseResult(withValues, "id", *decoded)
+		Expect(result.Config).To(HaveKeyWithValue("values", decoded.Config))
+	})
+
+	It("only decodes helm release secrets", func() {
+		secret := makeHelmReleaseSecret(map[string]any{"name": "podinfo", "version": 1, "namespace": "apps"})
+		secret.Object["type"] = "Opaque"
+		Expect(isHelmReleaseSecret(secret)).To(BeFalse())
+	})
+
+	It("marks uninstalled releases as deleted", func() {
+		r := release("uninstalled", 2, "Uninstallation complete", nil)
+		r.Info.Status = "uninstalled"
+		r.Info.Deleted = deployed.Add(24 * time.Hour)
+
+		result := helmReleaseResult(ctx, "id", r)
+		Expect(result.DeletedAt).To(Equal(&r.Info.Deleted))
+		Expect(result.DeleteReason).To(Equal(v1.DeletedReasonFromAttribute))
+	})
+
+	It("creates a change per revision with the diff of the values", func() {
+		id := helmReleaseExternalID("test-cluster", "apps", "changes")
+		changes, err := helmReleaseChanges(&helmReleaseValues, id, []helmRelease{
+			release("changes", 1, "Install complete", nil),
+			release("changes", 2, "Upgrade complete", map[string]any{"replicas": 2}),
+			release("changes", 3, "Rollback to 1", nil),
+		}, diff)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(HaveLen(3))
+
+		Expect(changes[0].ChangeType).To(Equal("HelmInstall"))
+		Expect(changes[0].Diff).To(BeNil())
+		Expect(changes[1].ChangeType).To(Equal("HelmUpgrade"))
+		Expect(*changes[1].Diff).To(Equal(`{} -> {"replicas":2}`))
+		Expect(changes[2].ChangeType).To(Equal("HelmRollback"))
+		Expect(*changes[2].Diff).To(Equal(`{"replicas":2} -> {}`))
+
+		for _, change := range changes {
+			Expect(change.ExternalID).To(Equal(id))
+			Expect(change.ConfigType).To(Equal(helmReleaseType))
+		}
+		Expect(changes[1].ExternalChangeID).To(Equal("uid-changes-2"))
+	})
+
+	It("does not diff the values unless they are scraped", func() {
+		id := helmReleaseExternalID("test-cluster", "apps", "secret-values")
+		changes, err := helmReleaseChanges(&helmReleaseValues, id, []helmRelease{
+			release("secret-values", 1, "Install complete", map[string]any{"password": "a"}),
+			release("secret-values", 2, "Upgrade complete", map[string]any{"password": "b"}),
+		}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[1].Diff).To(BeNil())
+
+		seen, _ := helmReleaseValues.Load(id)
+		Expect(seen.version).To(Equal(2))
+		Expect(seen.values).To(BeEmpty())
+	})
+
+	It("diffs against the last revision seen by a previous scrape", func() {
+		id := helmReleaseExternalID("test-cluster", "apps", "incremental")
+		_, err := helmReleaseChanges(&helmReleaseValues, id, []helmRelease{release("incremental", 4, "Upgrade complete", map[string]any{"tag": "v1"})}, diff)
+		Expect(err).ToNot(HaveOccurred())
+
+		changes, err := helmReleaseChanges(&helmReleaseValues, id, []helmRelease{release("incremental", 5, "Upgrade complete", map[string]any{"tag": "v2"})}, diff)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*changes[0].Diff).To(Equal(`{"tag":"v1"} -> {"tag":"v2"}`))
+
+		// The previous revision is updated to superseded after an upgrade
+		_, err = helmReleaseChanges(&helmReleaseValues, id, []helmRelease{release("incremental", 4, "Upgrade complete", map[string]any{"tag": "v1"})}, diff)
+		Expect(err).ToNot(HaveOccurred())
+		seen, _ := helmReleaseValues.Load(id)
+		Expect(seen.version).To(Equal(5))
+	})
+
+	DescribeTable("relates rendered objects to their release",
+		func(annotations, labels map[string]string, expected v1.RelationshipResults) {
+			obj := &unstructured.Unstructured{}
+			obj.SetKind("Deployment")
+			obj.SetUID("deployment-uid")
+			obj.SetAnnotations(annotations)
+			obj.SetLabels(labels)
+			Expect(helm{}.RelationshipLookupHook(ctx, obj)).To(Equal(expected))
+		},
+		Entry("helm release",
+			map[string]string{helmReleaseNameAnnotation: "podinfo", helmReleaseNamespaceAnnotation: "apps"}, nil,
+			v1.RelationshipResults{{
+				ConfigExternalID: v1.ExternalID{ConfigType: helmReleaseType, ExternalID: "Kubernetes/test-cluster/Helm/apps/podinfo"},
+				RelatedConfigID:  "deployment-uid",
+				Relationship:     "HelmReleaseDeployment",
+			}}),
+		Entry("flux helm release",
+			map[string]string{helmReleaseNameAnnotation: "podinfo", helmReleaseNamespaceAnnotation: "apps"},
+			map[string]string{"helm.toolkit.fluxcd.io/name": "podinfo"},
+			nil),
+		Entry("not rendered by helm", nil, nil, nil),
+	)
+})
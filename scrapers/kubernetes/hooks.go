@@ -27,11 +27,16 @@ type PropertyLookupHook interface {
 	PropertyLookupHook(ctx *KubernetesContext, obj *unstructured.Unstructured) types.Properties
 }
 
+type RelationshipLookupHook interface {
+	RelationshipLookupHook(ctx *KubernetesContext, obj *unstructured.Unstructured) v1.RelationshipResults
+}
+
 var childlookupHooks []ChildLookupHook
 var parentlookupHooks []ParentLookupHook
 var aliaslookupHooks []AliasLookupHook
 var onObjectHooks []OnObject
 var propertyLookupHooks []PropertyLookupHook
+var relationshipLookupHooks []RelationshipLookupHook
 
 func OnObjectHooks(ctx *KubernetesContext, obj *unstructured.Unstructured) (bool, map[string]string, error) {
 	labels := make(map[string]string)
@@ -81,3 +86,11 @@ func PropertyLookupHooks(ctx *KubernetesContext, obj *unstructured.Unstructured)
 	}
 	return props
 }
+
+func RelationshipLookupHooks(ctx *KubernetesContext, obj *unstructured.Unstructured) v1.RelationshipResults {
+	var relationships v1.RelationshipResults
+	for _, hook := range relationshipLookupHooks {
+		relationships = append(relationships, hook.RelationshipLookupHook(ctx, obj)...)
+	}
+	return relationships
+}
@@ -152,6 +152,7 @@ func ExtractResults(ctx *KubernetesContext, objs []*unstructured.Unstructured) v
 	// Initialize RBAC extractor for config access tracking
 	var rbac *rbacExtractor
 	var roleBindings []*unstructured.Unstructured
+	var helmReleaseSecrets []*unstructured.Unstructured
 	if ctx.Properties().On(true, "kubernetes.rbac_config_access") {
 		rbacCtx := ctx.ScrapeContext
 		rbacCtx.Context = rbacCtx.WithKubernetes(ctx.config.KubernetesConnection)
@@ -170,7 +171,19 @@ func ExtractResults(ctx *KubernetesContext, objs []*unstructured.Unstructured) v
 		ResourceIDMapPerCluster.Swap(string(ctx.ScrapeConfig().GetUID()), ctx.resourceIDMap.data)
 	}
 
+	if changes := api.DryRunState(ctx.ScrapeContext, "rollouts", &rollouts, (*rolloutTracker).clone).Observe(objs, ctx.IsIncrementalScrape()); len(changes) > 0 {
+		changeResults = append(changeResults, v1.ScrapeResult{
+			BaseScraper: ctx.config.BaseScraper,
+			Changes:     changes,
+		})
+	}
+
 	for _, obj := range objs {
+		if obj.GetKind() == "ControllerRevision" && ctx.IsIncrementalScrape() {
+			// ControllerRevisions are only watched to follow the rollouts of StatefulSets and DaemonSets
+			continue
+		}
+
 		tags := lo.Assign(map[string]string{}, ctx.config.Tags.AsMap())
 
 		if ignore, err := ctx.IsIgnored(obj); err != nil {
@@ -287,6 +300,12 @@ func ExtractResults(ctx *KubernetesContext, objs []*unstructured.Unstructured) v
 			continue
 		}
 
+		if isHelmReleaseSecret(obj) {
+			// Release secrets are scraped as HelmRelease config items once all revisions are collected
+			helmReleaseSecrets = append(helmReleaseSecrets, obj)
+			continue
+		}
+
 		var (
 			relationships v1.RelationshipResults
 			labels        = make(map[string]string)
@@ -563,6 +582,7 @@ func ExtractResults(ctx *KubernetesContext, objs []*unstructured.Unstructured) v
 		children := ChildLookupHooks(ctx, obj)
 		allAliases := append(aliases, AliasLookupHooks(ctx, obj)...)
 		props := PropertyLookupHooks(ctx, obj)
+		relationships = append(relationships, RelationshipLookupHooks(ctx, obj)...)
 		results = append(results, v1.ScrapeResult{
 			BaseScraper:         ctx.config.BaseScraper,
 			Name:                obj.GetName(),
@@ -594,6 +614,10 @@ func ExtractResults(ctx *KubernetesContext, objs []*unstructured.Unstructured) v
 	}
 	results = append(results, rbac.results(ctx.config.BaseScraper))
 
+	helmReleases, helmChanges := helmReleaseResults(ctx, helmReleaseSecrets)
+	results = append(results, helmReleases...)
+	changeResults = append(changeResults, helmChanges...)
+
 	results = append(results, changeResults...)
 	results = append([]v1.ScrapeResult{ctx.cluster}, results...)
 
@@ -0,0 +1,378 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/collections/syncmap"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	ChangeTypeRollout = "Rollout"
+
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+	rolloutSource                = "kubernetes/rollout"
+)
+
+// rollout is the current revision of a Deployment, StatefulSet or DaemonSet.
+type rollout struct {
+	revision int64
+	// name of the ReplicaSet or ControllerRevision of the revision
+	name     string
+	podSpec  map[string]any
+	started  time.Time
+	complete bool
+
+	// change is the Rollout change emitted when the revision became current,
+	// it is nil for revisions that were current when first seen.
+	change *v1.ChangeResult
+}
+
+// rolloutTracker follows the revisions of workloads across scrapes to
+// emit a Rollout change whenever a new ReplicaSet or ControllerRevision becomes current.
+type rolloutTracker struct {
+	rollouts syncmap.SyncMap[string, rollout] // owner uid -> current revision
+}
+
+var rollouts rolloutTracker
+
+// clone returns a tracker with the current revisions of t, for dry runs.
+func (t *rolloutTracker) clone() *rolloutTracker {
+	c := &rolloutTracker{}
+	t.rollouts.Range(func(uid string, current rollout) bool {
+		c.rollouts.Store(uid, current)
+		return true
+	})
+	return c
+}
+
+// Forget drops the revisions of the given deleted owners.
+func (t *rolloutTracker) Forget(objs []*unstructured.Unstructured) {
+	for _, obj := range objs {
+		t.rollouts.Delete(string(obj.GetUID()))
+	}
+}
+
+// ForgetRollouts stops following the rollouts of deleted workloads.
+func ForgetRollouts(ctx api.ScrapeContext, deleted []*unstructured.Unstructured) {
+	api.DryRunState(ctx, "rollouts", &rollouts, (*rolloutTracker).clone).Forget(deleted)
+}
+
+type rolloutImageChange struct {
+	Container string `json:"container"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+}
+
+// Observe returns the Rollout changes of the given objects.
+//
+// New revisions only result in a change when emit is set, i.e. when the objects
+// come from the informer stream. A full scrape only records the current revisions,
+// as it can't tell when they became current.
+//
+// Once the owner reports that all its replicas run the new revision, the change
+// is emitted again with the duration of the rollout.
+func (t *rolloutTracker) Observe(objs []*unstructured.Unstructured, emit bool) []v1.ChangeResult {
+	var changes []v1.ChangeResult
+
+	// Revisions are processed before their owners so that a rollout that
+	// starts and completes within the same batch is reported as complete.
+	for _, obj := range objs {
+		owner, revision, podSpec, ok := rolloutRevisionOf(obj)
+		if !ok {
+			continue
+		}
+
+		current, found := t.rollouts.Load(string(owner.UID))
+		if found && revision <= current.revision {
+			continue
+		}
+
+		next := rollout{
+			revision: revision,
+			name:     obj.GetName(),
+			podSpec:  podSpec,
+			started:  obj.GetCreationTimestamp().Time,
+			complete: true,
+		}
+
+		if found && emit {
+			// A rollback makes an existing ReplicaSet current again
+			if next.started.IsZero() || next.started.Before(current.started) {
+				next.started = time.Now()
+			}
+			next.complete = false
+			next.change = lo.ToPtr(rolloutChange(owner, obj, current, next))
+			changes = append(changes, *next.change)
+		}
+
+		t.rollouts.Store(string(owner.UID), next)
+	}
+
+	for _, obj := range objs {
+		current, found := t.rollouts.Load(string(obj.GetUID()))
+		if !found || current.complete || current.change == nil || !isRolloutComplete(obj, current) {
+			continue
+		}
+
+		current.complete = true
+		t.rollouts.Store(string(obj.GetUID()), current)
+
+		// Emitted with the same external change id, so it replaces the change of the started rollout
+		change := *current.change
+		change.Details = lo.Assign(change.Details, map[string]any{
+			"duration": time.Since(current.started).Round(time.Second).String(),
+		})
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// rolloutRevisionOf returns the controller, the revision and the pod spec of
+// a ReplicaSet owned by a Deployment or of a ControllerRevision owned by a StatefulSet or DaemonSet.
+func rolloutRevisionOf(obj *unstructured.Unstructured) (metav1.OwnerReference, int64, map[string]any, bool) {
+	var (
+		owner    *metav1.OwnerReference
+		revision int64
+		podSpec  map[string]any
+	)
+
+	for _, ref := range obj.GetOwnerReferences() {
+		if lo.FromPtr(ref.Controller) {
+			owner = &ref
+			break
+		}
+	}
+	if owner == nil {
+		return metav1.OwnerReference{}, 0, nil, false
+	}
+
+	switch {
+	case obj.GetKind() == KindReplicaSet && owner.Kind == KindDeployment:
+		r, err := strconv.ParseInt(obj.GetAnnotations()[deploymentRevisionAnnotation], 10, 64)
+		if err != nil {
+			return *owner, 0, nil, false
+		}
+		revision = r
+		podSpec, _, _ = unstructured.NestedMap(obj.Object, "spec", "template", "spec")
+
+	case obj.GetKind() == "ControllerRevision" && (owner.Kind == KindStatefulSet || owner.Kind == KindDaemonSet):
+		revision, _, _ = unstructured.NestedInt64(obj.Object, "revision")
+		podSpec, _, _ = unstructured.NestedMap(obj.Object, "data", "spec", "template", "spec")
+
+	default:
+		return *owner, 0, nil, false
+	}
+
+	return *owner, revision, podSpec, revision > 0
+}
+
+// isRolloutComplete reports whether all the replicas of a workload run its current revision.
+func isRolloutComplete(obj *unstructured.Unstructured, current rollout) bool {
+	status := func(field string) int64 {
+		v, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+		return v
+	}
+	if status("observedGeneration") < obj.GetGeneration() {
+		return false
+	}
+
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+
+	switch obj.GetKind() {
+	case KindDeployment:
+		// status.replicas includes the pods of the old ReplicaSets
+		return status("updatedReplicas") == replicas && status("availableReplicas") >= replicas && status("replicas") == replicas
+
+	case KindStatefulSet:
+		updateRevision, _, _ := unstructured.NestedString(obj.Object, "status", "updateRevision")
+		currentRevision, _, _ := unstructured.NestedString(obj.Object, "status", "currentRevision")
+		return updateRevision == current.name && currentRevision == updateRevision &&
+			status("updatedReplicas") == replicas && status("readyReplicas") >= replicas
+
+	case KindDaemonSet:
+		desired := status("desiredNumberScheduled")
+		return status("updatedNumberScheduled") == desired && status("numberAvailable") >= desired
+	}
+
+	return false
+}
+
+func rolloutChange(owner metav1.OwnerReference, obj *unstructured.Unstructured, previous, next rollout) v1.ChangeResult {
+	images, env, config := podSpecDelta(previous.podSpec, next.podSpec)
+
+	summary := fmt.Sprintf("revision %d", next.revision)
+	if len(images) > 0 {
+		summary = strings.Join(lo.Map(images, func(c rolloutImageChange, _ int) string {
+			return fmt.Sprintf("%s: %s -> %s", c.Container, lo.CoalesceOrEmpty(c.From, "(none)"), lo.CoalesceOrEmpty(c.To, "(none)"))
+		}), ", ")
+	} else if len(env) > 0 {
+		summary = "env: " + strings.Join(env, ", ")
+	}
+
+	details := map[string]any{
+		"revision":                  next.revision,
+		"previousRevision":          previous.revision,
+		lo.CamelCase(obj.GetKind()): obj.GetName(),
+	}
+	if len(images) > 0 {
+		details["images"] = images
+	}
+	if len(env) > 0 {
+		details["env"] = env
+	}
+	if len(config) > 0 {
+		details["config"] = config
+	}
+
+	// Rollbacks make an existing ReplicaSet current again under a new revision,
+	// so the revision is part of the change id.
+	return v1.ChangeResult{
+		ChangeType:       ChangeTypeRollout,
+		ExternalChangeID: fmt.Sprintf("%s/%d", obj.GetUID(), next.revision),
+		ExternalID:       string(owner.UID),
+		ConfigID:         string(owner.UID),
+		ConfigType:       ConfigTypePrefix + owner.Kind,
+		Source:           rolloutSource,
+		Summary:          summary,
+		CreatedAt:        lo.ToPtr(next.started),
+		Details:          details,
+	}
+}
+
+type rolloutContainer struct {
+	image string
+	env   map[string]string
+}
+
+// podSpecDelta returns the image changes per container, the names of the environment
+// variables that changed and the ConfigMaps and Secrets that are no longer or newly referenced.
+func podSpecDelta(previous, next map[string]any) ([]rolloutImageChange, []string, []string) {
+	before, after := rolloutContainers(previous), rolloutContainers(next)
+
+	var images []rolloutImageChange
+	var env []string
+	for _, name := range lo.Uniq(append(lo.Keys(before), lo.Keys(after)...)) {
+		b, a := before[name], after[name]
+		if b.image != a.image {
+			images = append(images, rolloutImageChange{Container: name, From: b.image, To: a.image})
+		}
+		for _, key := range lo.Uniq(append(lo.Keys(b.env), lo.Keys(a.env)...)) {
+			bv, bok := b.env[key]
+			av, aok := a.env[key]
+			if bok != aok || bv != av {
+				env = append(env, key)
+			}
+		}
+	}
+	slices.SortFunc(images, func(a, b rolloutImageChange) int { return strings.Compare(a.Container, b.Container) })
+
+	beforeRefs, afterRefs := podSpecConfigRefs(previous), podSpecConfigRefs(next)
+	removed, added := lo.Difference(beforeRefs, afterRefs)
+	config := append(removed, added...)
+
+	env = lo.Uniq(env)
+	slices.Sort(env)
+	config = lo.Uniq(config)
+	slices.Sort(config)
+	return images, env, config
+}
+
+func rolloutContainers(podSpec map[string]any) map[string]rolloutContainer {
+	containers := map[string]rolloutContainer{}
+	for _, field := range []string{"initContainers", "containers"} {
+		list, _, _ := unstructured.NestedSlice(podSpec, field)
+		for _, item := range list {
+			c, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(c, "name")
+			image, _, _ := unstructured.NestedString(c, "image")
+
+			env := map[string]string{}
+			vars, _, _ := unstructured.NestedSlice(c, "env")
+			for _, v := range vars {
+				envVar, ok := v.(map[string]any)
+				if !ok {
+					continue
+				}
+				key, _, _ := unstructured.NestedString(envVar, "name")
+				value, _ := json.Marshal(lo.OmitByKeys(envVar, []string{"name"}))
+				env[key] = string(value)
+			}
+
+			containers[name] = rolloutContainer{image: image, env: env}
+		}
+	}
+	return containers
+}
+
+// podSpecConfigRefs returns the ConfigMaps and Secrets referenced by a pod spec as <Kind>/<name>.
+func podSpecConfigRefs(podSpec map[string]any) []string {
+	var refs []string
+	add := func(kind string, obj map[string]any, fields ...string) {
+		if name, _, _ := unstructured.NestedString(obj, fields...); name != "" {
+			refs = append(refs, kind+"/"+name)
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		list, _, _ := unstructured.NestedSlice(podSpec, field)
+		for _, item := range list {
+			c, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			envFrom, _, _ := unstructured.NestedSlice(c, "envFrom")
+			for _, e := range envFrom {
+				if source, ok := e.(map[string]any); ok {
+					add("ConfigMap", source, "configMapRef", "name")
+					add("Secret", source, "secretRef", "name")
+				}
+			}
+
+			vars, _, _ := unstructured.NestedSlice(c, "env")
+			for _, v := range vars {
+				if envVar, ok := v.(map[string]any); ok {
+					add("ConfigMap", envVar, "valueFrom", "configMapKeyRef", "name")
+					add("Secret", envVar, "valueFrom", "secretKeyRef", "name")
+				}
+			}
+		}
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(podSpec, "volumes")
+	for _, v := range volumes {
+		volume, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		add("ConfigMap", volume, "configMap", "name")
+		add("Secret", volume, "secret", "secretName")
+
+		sources, _, _ := unstructured.NestedSlice(volume, "projected", "sources")
+		for _, s := range sources {
+			if source, ok := s.(map[string]any); ok {
+				add("ConfigMap", source, "configMap", "name")
+				add("Secret", source, "secret", "name")
+			}
+		}
+	}
+
+	return lo.Uniq(refs)
+}
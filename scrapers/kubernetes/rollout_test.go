@@ -0,0 +1,178 @@
+package kubernetes
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func makeReplicaSet(uid, deploymentUID string, revision string, created time.Time, containers ...any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "ReplicaSet",
+		"spec": map[string]any{
+			"template": map[string]any{"spec": map[string]any{"containers": containers}},
+		},
+	}}
+	obj.SetName("web-" + uid)
+	obj.SetNamespace("default")
+	obj.SetUID(types.UID(uid))
+	obj.SetCreationTimestamp(metav1.NewTime(created))
+	obj.SetAnnotations(map[string]string{deploymentRevisionAnnotation: revision})
+	obj.SetOwnerReferences([]metav1.OwnerReference{{Kind: KindDeployment, Name: "web", UID: types.UID(deploymentUID), Controller: lo.ToPtr(true)}})
+	return obj
+}
+
+func makeDeployment(uid string, replicas, updated, available, total int64) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"spec":       map[string]any{"replicas": replicas},
+		"status": map[string]any{
+			"observedGeneration": int64(2),
+			"replicas":           total,
+			"updatedReplicas":    updated,
+			"availableReplicas":  available,
+		},
+	}}
+	obj.SetName("web")
+	obj.SetUID(types.UID(uid))
+	obj.SetGeneration(2)
+	return obj
+}
+
+func container(name, image string, env ...any) map[string]any {
+	return map[string]any{"name": name, "image": image, "env": env}
+}
+
+var _ = Describe("rolloutTracker", func() {
+	// creation timestamps have a precision of a second
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	It("records the current revision on a full scrape without emitting a change", func() {
+		var tracker rolloutTracker
+		changes := tracker.Observe([]*unstructured.Unstructured{
+			makeReplicaSet("rs-2", "deploy", "2", created, container("app", "nginx:1.2")),
+			makeReplicaSet("rs-1", "deploy", "1", created.Add(-time.Hour), container("app", "nginx:1.1")),
+		}, false)
+		Expect(changes).To(BeEmpty())
+
+		current, ok := tracker.rollouts.Load("deploy")
+		Expect(ok).To(BeTrue())
+		Expect(current.revision).To(Equal(int64(2)))
+		Expect(current.complete).To(BeTrue())
+	})
+
+	It("forgets the revisions of deleted owners", func() {
+		var tracker rolloutTracker
+		tracker.Observe([]*unstructured.Unstructured{makeReplicaSet("rs-1", "deploy", "1", created, container("app", "nginx:1.1"))}, false)
+		tracker.Forget([]*unstructured.Unstructured{makeDeployment("deploy", 1, 1, 1, 1)})
+
+		_, ok := tracker.rollouts.Load("deploy")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("emits a rollout when a new ReplicaSet becomes current and again once it completes", func() {
+		var tracker rolloutTracker
+		tracker.Observe([]*unstructured.Unstructured{
+			makeReplicaSet("rs-1", "deploy", "1", created,
+				container("app", "nginx:1.1",
+					map[string]any{"name": "LOG_LEVEL", "value": "info"},
+					map[string]any{"name": "DB_URL", "valueFrom": map[string]any{"secretKeyRef": map[string]any{"name": "db", "key": "url"}}},
+				),
+				container("sidecar", "envoy:1.0"),
+			),
+		}, false)
+
+		changes := tracker.Observe([]*unstructured.Unstructured{
+			makeDeployment("deploy", 2, 1, 2, 3),
+			makeReplicaSet("rs-2", "deploy", "2", created.Add(30*time.Minute),
+				container("app", "nginx:1.2",
+					map[string]any{"name": "LOG_LEVEL", "value": "debug"},
+					map[string]any{"name": "DB_URL", "valueFrom": map[string]any{"secretKeyRef": map[string]any{"name": "db-v2", "key": "url"}}},
+				),
+				container("sidecar", "envoy:1.0"),
+			),
+		}, true)
+		Expect(changes).To(HaveLen(1))
+
+		change := changes[0]
+		Expect(change.ChangeType).To(Equal(ChangeTypeRollout))
+		Expect(change.ConfigID).To(Equal("deploy"))
+		Expect(change.ConfigType).To(Equal("Kubernetes::Deployment"))
+		Expect(change.ExternalChangeID).To(Equal("rs-2/2"))
+		Expect(change.Summary).To(Equal("app: nginx:1.1 -> nginx:1.2"))
+		Expect(*change.CreatedAt).To(BeTemporally("==", created.Add(30*time.Minute)))
+		Expect(change.Details).To(HaveKeyWithValue("revision", int64(2)))
+		Expect(change.Details).To(HaveKeyWithValue("previousRevision", int64(1)))
+		Expect(change.Details).To(HaveKeyWithValue("replicaSet", "web-rs-2"))
+		Expect(change.Details).To(HaveKeyWithValue("images", []rolloutImageChange{{Container: "app", From: "nginx:1.1", To: "nginx:1.2"}}))
+		Expect(change.Details).To(HaveKeyWithValue("env", []string{"DB_URL", "LOG_LEVEL"}))
+		Expect(change.Details).To(HaveKeyWithValue("config", []string{"Secret/db", "Secret/db-v2"}))
+		Expect(change.Details).ToNot(HaveKey("duration"))
+
+		completed := tracker.Observe([]*unstructured.Unstructured{makeDeployment("deploy", 2, 2, 2, 2)}, true)
+		Expect(completed).To(HaveLen(1))
+		Expect(completed[0].ExternalChangeID).To(Equal("rs-2/2"))
+		duration, err := time.ParseDuration(completed[0].Details["duration"].(string))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(duration).To(BeNumerically("~", 30*time.Minute, 2*time.Second))
+		Expect(change.Details).ToNot(HaveKey("duration"))
+
+		// The completion is only reported once
+		Expect(tracker.Observe([]*unstructured.Unstructured{makeDeployment("deploy", 2, 2, 2, 2)}, true)).To(BeEmpty())
+	})
+
+	It("ignores revisions older than the current one", func() {
+		var tracker rolloutTracker
+		tracker.Observe([]*unstructured.Unstructured{makeReplicaSet("rs-2", "deploy", "2", created, container("app", "nginx:1.2"))}, false)
+		Expect(tracker.Observe([]*unstructured.Unstructured{makeReplicaSet("rs-1", "deploy", "1", created, container("app", "nginx:1.1"))}, true)).To(BeEmpty())
+	})
+
+	It("uses the ControllerRevisions of StatefulSets", func() {
+		revision := func(uid, name string, revision int64, image string) *unstructured.Unstructured {
+			obj := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "apps/v1",
+				"kind":       "ControllerRevision",
+				"revision":   revision,
+				"data": map[string]any{"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+					"containers": []any{container("db", image)},
+				}}}},
+			}}
+			obj.SetName(name)
+			obj.SetUID(types.UID(uid))
+			obj.SetCreationTimestamp(metav1.NewTime(created))
+			obj.SetOwnerReferences([]metav1.OwnerReference{{Kind: KindStatefulSet, Name: "db", UID: "sts", Controller: lo.ToPtr(true)}})
+			return obj
+		}
+
+		var tracker rolloutTracker
+		tracker.Observe([]*unstructured.Unstructured{revision("cr-1", "db-abc", 1, "postgres:15")}, false)
+
+		statefulSet := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "apps/v1",
+			"kind":       "StatefulSet",
+			"spec":       map[string]any{"replicas": int64(1)},
+			"status": map[string]any{
+				"updateRevision":  "db-def",
+				"currentRevision": "db-def",
+				"updatedReplicas": int64(1),
+				"readyReplicas":   int64(1),
+			},
+		}}
+		statefulSet.SetUID("sts")
+
+		// Started and completed within the same batch
+		changes := tracker.Observe([]*unstructured.Unstructured{statefulSet, revision("cr-2", "db-def", 2, "postgres:16")}, true)
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[0].Summary).To(Equal("db: postgres:15 -> postgres:16"))
+		Expect(changes[0].Details).To(HaveKeyWithValue("controllerRevision", "db-def"))
+		Expect(changes[1].ExternalChangeID).To(Equal(changes[0].ExternalChangeID))
+		Expect(changes[1].Details).To(HaveKey("duration"))
+	})
+})
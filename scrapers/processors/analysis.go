@@ -0,0 +1,85 @@
+package processors
+
+import (
+	"strings"
+	"time"
+
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/gomplate/v3"
+	"github.com/samber/lo"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/scrapers/analysis"
+	"github.com/flanksource/config-db/utils"
+)
+
+const analysisRuleSource = "AnalysisRule"
+
+// analysisRuleResults returns an analysis for every rule that matches the config item.
+//
+// Analyses of rules that no longer match aren't reported anymore,
+// so they are resolved by the stale analysis retention.
+func analysisRuleResults(ctx api.ScrapeContext, result v1.ScrapeResult, rules []v1.AnalysisRule) []v1.ScrapeResult {
+	if result.ID == "" || result.Config == nil {
+		return nil
+	}
+
+	var results []v1.ScrapeResult
+	for _, rule := range rules {
+		if rule.Type != "" && !rule.Type.Match(result.Type) {
+			continue
+		}
+
+		env := result.AsMap()
+		ok, err := ctx.RunTemplateBool(gomplate.Template{
+			Expression: string(rule.Expr),
+			CacheKey:   "processors.analysis.expr:" + string(rule.Expr),
+			CacheTime:  utils.RandomDurationBetween(24*time.Hour, 36*time.Hour),
+		}, env)
+		if err != nil {
+			// NOTE: An expression accessing a field that doesn't exist returns an error,
+			// such errors are treated as a non-match.
+			ctx.Logger.V(4).Infof("analysis rule %s (%s) failed for %s: %v", rule.Analyzer, rule.Expr, result.ID, err)
+			continue
+		} else if !ok {
+			continue
+		}
+
+		analysisResult := &v1.AnalysisResult{
+			Analyzer:     rule.Analyzer,
+			ConfigType:   result.Type,
+			ExternalID:   result.ID,
+			Severity:     rule.Severity,
+			AnalysisType: rule.Category,
+			Source:       analysisRuleSource,
+			Summary:      rule.Analyzer,
+			Analysis:     map[string]any{"expr": string(rule.Expr)},
+		}
+
+		if builtin, ok := analysis.Rules[rule.Analyzer]; ok {
+			analysisResult.Severity = lo.CoalesceOrEmpty(analysisResult.Severity, models.Severity(builtin.Severity))
+			analysisResult.AnalysisType = lo.CoalesceOrEmpty(analysisResult.AnalysisType, models.AnalysisType(builtin.Category))
+		}
+		analysisResult.Severity = lo.CoalesceOrEmpty(analysisResult.Severity, models.SeverityInfo)
+		analysisResult.AnalysisType = lo.CoalesceOrEmpty(analysisResult.AnalysisType, models.AnalysisTypeOther)
+
+		if rule.Message != "" {
+			message := rule.Message
+			if strings.Contains(message, "{{") {
+				if message, err = ctx.RunTemplate(gomplate.Template{Template: rule.Message}, env); err != nil {
+					ctx.Logger.V(3).Infof("failed to template the message of analysis rule %s: %v", rule.Analyzer, err)
+					message = rule.Message
+				}
+			}
+			analysisResult.Message(message)
+		}
+
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:    result.BaseScraper,
+			AnalysisResult: analysisResult,
+		})
+	}
+
+	return results
+}
@@ -0,0 +1,64 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/flanksource/duty/context"
+	"github.com/flanksource/duty/models"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func TestAnalysisRuleResults(t *testing.T) {
+	ctx := api.NewScrapeContext(context.New())
+
+	bucket := v1.ScrapeResult{
+		ID:   "arn:aws:s3:::logs",
+		Name: "logs",
+		Type: "AWS::S3::Bucket",
+		Config: map[string]any{
+			"versioning": map[string]any{"status": "Suspended"},
+			"public":     true,
+		},
+	}
+
+	rules := []v1.AnalysisRule{
+		{Type: "AWS::S3::Bucket", Expr: "config.public", Analyzer: "s3-public-bucket", Severity: models.SeverityCritical, Category: models.AnalysisTypeSecurity, Message: "{{.name}} is public"},
+		{Type: "AWS::S3::Bucket", Expr: `config.versioning.status != "Enabled"`, Analyzer: "s3-versioning"},
+		{Type: "AWS::EC2::Instance", Expr: "true", Analyzer: "ec2"},
+		{Expr: "config.missing.field", Analyzer: "missing"},
+		// Falls back to the severity and category of the built-in rules
+		{Expr: "true", Analyzer: "cloudtrail-enabled"},
+	}
+
+	results := analysisRuleResults(ctx, bucket, rules)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 analyses, got %d", len(results))
+	}
+
+	public := results[0].AnalysisResult
+	if public.Analyzer != "s3-public-bucket" || public.ExternalID != bucket.ID || public.ConfigType != bucket.Type {
+		t.Errorf("unexpected analysis %+v", public)
+	}
+	if public.Severity != models.SeverityCritical || public.AnalysisType != models.AnalysisTypeSecurity {
+		t.Errorf("unexpected severity=%s category=%s", public.Severity, public.AnalysisType)
+	}
+	if len(public.Messages) != 1 || public.Messages[0] != "logs is public" {
+		t.Errorf("unexpected messages %v", public.Messages)
+	}
+
+	versioning := results[1].AnalysisResult
+	if versioning.Severity != models.SeverityInfo || versioning.AnalysisType != models.AnalysisTypeOther {
+		t.Errorf("expected default severity and category, got severity=%s category=%s", versioning.Severity, versioning.AnalysisType)
+	}
+
+	builtin := results[2].AnalysisResult
+	if builtin.Severity != models.SeverityCritical || builtin.AnalysisType != models.AnalysisTypeSecurity {
+		t.Errorf("expected the built-in severity and category, got severity=%s category=%s", builtin.Severity, builtin.AnalysisType)
+	}
+
+	if results := analysisRuleResults(ctx, v1.ScrapeResult{Type: "AWS::S3::Bucket"}, rules); len(results) != 0 {
+		t.Errorf("expected no analysis for results without a config, got %d", len(results))
+	}
+}
@@ -0,0 +1,293 @@
+package processors
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/hairyhenderson/toml"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// formatParsers turn the content of config items in formats other than json and yaml into structured config.
+var formatParsers = map[string]func(content string) (any, error){
+	"csv":    parseCSV,
+	"dotenv": parseDotEnv,
+	"env":    parseDotEnv,
+	"hcl":    parseHCL,
+	"ini":    parseINI,
+	"toml":   parseTOML,
+	"xml":    parseXML,
+}
+
+// ParseFormat parses the content of a config item in one of the formats other than json and yaml.
+func ParseFormat(format, content string) (any, error) {
+	parse, ok := formatParsers[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+	return parse(content)
+}
+
+func parseTOML(content string) (any, error) {
+	var out map[string]any
+	if _, err := toml.Decode(content, &out); err != nil {
+		return nil, err
+	}
+
+	// Round trip through json so that dates and times are strings
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]any
+	return normalized, json.Unmarshal(b, &normalized)
+}
+
+// parseINI parses ini files into a map of sections.
+// Keys outside of a section are at the top level, repeated sections are merged.
+func parseINI(content string) (any, error) {
+	out := map[string]any{}
+	section := out
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section %s", i+1, line)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if existing, ok := out[name].(map[string]any); ok {
+				section = existing
+			} else {
+				section = map[string]any{}
+				out[name] = section
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			key, value, _ = strings.Cut(line, ":")
+		}
+		section[strings.TrimSpace(key)] = unquote(strings.TrimSpace(value))
+	}
+	return out, nil
+}
+
+// parseDotEnv parses KEY=VALUE lines, as used by .env files.
+func parseDotEnv(content string) (any, error) {
+	out := map[string]any{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", i+1)
+		}
+
+		value = strings.TrimSpace(value)
+		if !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "'") {
+			if before, _, found := strings.Cut(value, " #"); found {
+				value = strings.TrimSpace(before)
+			}
+		}
+		out[strings.TrimSpace(key)] = unquote(value)
+	}
+	return out, nil
+}
+
+// unquote removes the quotes around a value, double quoted values have their escape sequences expanded.
+func unquote(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	switch {
+	case value[0] == '"' && value[len(value)-1] == '"':
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+		return value[1 : len(value)-1]
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// parseHCL parses HCL into a map of attributes and blocks.
+// Blocks are nested under their type and labels, e.g.
+//
+//	resource "aws_instance" "web" { ... }  =>  {"resource": {"aws_instance": {"web": [{...}]}}}
+//
+// Expressions that cannot be evaluated without context (variables, functions) are kept as source text.
+func parseHCL(content string) (any, error) {
+	src := []byte(content)
+	file, diags := hclsyntax.ParseConfig(src, "config.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unexpected hcl body %T", file.Body)
+	}
+	return hclBody(body, src), nil
+}
+
+func hclBody(body *hclsyntax.Body, src []byte) map[string]any {
+	out := map[string]any{}
+	for name, attr := range body.Attributes {
+		out[name] = hclExpression(attr.Expr, src)
+	}
+
+	for _, block := range body.Blocks {
+		parent := out
+		for _, key := range append([]string{block.Type}, block.Labels...)[:len(block.Labels)] {
+			child, ok := parent[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				parent[key] = child
+			}
+			parent = child
+		}
+
+		key := block.Type
+		if len(block.Labels) > 0 {
+			key = block.Labels[len(block.Labels)-1]
+		}
+		blocks, _ := parent[key].([]any)
+		parent[key] = append(blocks, hclBody(block.Body, src))
+	}
+	return out
+}
+
+func hclExpression(expr hclsyntax.Expression, src []byte) any {
+	source := string(expr.Range().SliceBytes(src))
+
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return source
+	}
+
+	b, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return source
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return source
+	}
+	return out
+}
+
+// parseXML parses xml into nested maps.
+// Attributes are prefixed with "@", the text of elements with attributes or
+// children is stored under "#text" and repeated elements become lists.
+func parseXML(content string) (any, error) {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	// The content is already a string, ignore the declared encoding
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no root element")
+		} else if err != nil {
+			return nil, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			root, err := xmlElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{start.Name.Local: root}, nil
+		}
+	}
+}
+
+func xmlElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	node := map[string]any{}
+	for _, attr := range start.Attr {
+		node["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := xmlElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+
+			switch existing := node[t.Name.Local].(type) {
+			case nil:
+				node[t.Name.Local] = child
+			case []any:
+				node[t.Name.Local] = append(existing, child)
+			default:
+				node[t.Name.Local] = []any{existing, child}
+			}
+
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(node) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				node["#text"] = s
+			}
+			return node, nil
+		}
+	}
+}
+
+// parseCSV parses csv with a header row into a list of rows keyed by column name.
+func parseCSV(content string) (any, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\ufeff")))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []any{}
+	if len(records) == 0 {
+		return rows, nil
+	}
+
+	header := records[0]
+	for _, record := range records[1:] {
+		row := map[string]any{}
+		for i, value := range record {
+			if i < len(header) {
+				row[header[i]] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
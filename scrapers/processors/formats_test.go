@@ -0,0 +1,129 @@
+package processors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatParsers(t *testing.T) {
+	tests := []struct {
+		format  string
+		content string
+		want    any
+	}{
+		{
+			format: "toml",
+			content: `title = "app"
+[database]
+ports = [5432, 5433]
+enabled = true`,
+			want: map[string]any{
+				"title":    "app",
+				"database": map[string]any{"ports": []any{float64(5432), float64(5433)}, "enabled": true},
+			},
+		},
+		{
+			format: "ini",
+			content: `; global
+debug = false
+[server]
+host = "localhost"
+port: 8080
+# comment
+[server]
+timeout = 30`,
+			want: map[string]any{
+				"debug":  "false",
+				"server": map[string]any{"host": "localhost", "port": "8080", "timeout": "30"},
+			},
+		},
+		{
+			format: "env",
+			content: `# database
+export DB_HOST=localhost
+DB_PASSWORD="p@ss#word\n"
+GREETING='hello world'
+LOG_LEVEL=info # inline comment`,
+			want: map[string]any{
+				"DB_HOST":     "localhost",
+				"DB_PASSWORD": "p@ss#word\n",
+				"GREETING":    "hello world",
+				"LOG_LEVEL":   "info",
+			},
+		},
+		{
+			format: "hcl",
+			content: `region = "eu-west-1"
+replicas = 3
+resource "aws_instance" "web" {
+  ami = var.ami
+  tags = { Name = "web" }
+}
+backend {
+  bucket = "state"
+}`,
+			want: map[string]any{
+				"region":   "eu-west-1",
+				"replicas": float64(3),
+				"resource": map[string]any{"aws_instance": map[string]any{"web": []any{
+					map[string]any{"ami": "var.ami", "tags": map[string]any{"Name": "web"}},
+				}}},
+				"backend": []any{map[string]any{"bucket": "state"}},
+			},
+		},
+		{
+			format: "xml",
+			content: `<?xml version="1.0" encoding="ISO-8859-1"?>
+<project version="4.0">
+  <groupId>com.example</groupId>
+  <dependency scope="test">junit</dependency>
+  <dependency>slf4j</dependency>
+</project>`,
+			want: map[string]any{"project": map[string]any{
+				"@version": "4.0",
+				"groupId":  "com.example",
+				"dependency": []any{
+					map[string]any{"@scope": "test", "#text": "junit"},
+					"slf4j",
+				},
+			}},
+		},
+		{
+			format: "csv",
+			content: "\ufeffhostname,ip,role\n" +
+				"web-1, 10.0.0.1,frontend\n" +
+				"db-1,10.0.0.2\n",
+			want: []any{
+				map[string]any{"hostname": "web-1", "ip": "10.0.0.1", "role": "frontend"},
+				map[string]any{"hostname": "db-1", "ip": "10.0.0.2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := formatParsers[tt.format](tt.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatParsers_Invalid(t *testing.T) {
+	for format, content := range map[string]string{
+		"toml": "title = ",
+		"ini":  "[server",
+		"env":  "NOT A VARIABLE",
+		"hcl":  "resource {",
+		"xml":  "<project><name>",
+		"csv":  "a,\"b",
+	} {
+		if _, err := formatParsers[format](content); err == nil {
+			t.Errorf("%s: expected an error", format)
+		}
+	}
+}
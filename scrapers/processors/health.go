@@ -0,0 +1,74 @@
+package processors
+
+import (
+	"time"
+
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/gomplate/v3"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/utils"
+)
+
+// applyHealthRules applies the first health rule that matches the config item.
+// Rules are evaluated after the scraper, and the health jsonpath, have set the health
+// so that they can refer to it.
+func applyHealthRules(ctx api.ScrapeContext, result v1.ScrapeResult, rules []v1.HealthRule) v1.ScrapeResult {
+	for _, rule := range rules {
+		if rule.Type != "" && !rule.Type.Match(result.Type) {
+			continue
+		}
+
+		ok, err := ctx.RunTemplateBool(gomplate.Template{
+			Expression: string(rule.Expr),
+			CacheKey:   "processors.health.expr:" + string(rule.Expr),
+			CacheTime:  utils.RandomDurationBetween(24*time.Hour, 36*time.Hour),
+		}, result.AsMap())
+		if err != nil {
+			// NOTE: An expression accessing a field that doesn't exist returns an error,
+			// such errors are treated as a non-match.
+			ctx.Logger.V(4).Infof("health rule (%s) failed for %s: %v", rule.Expr, result.ID, err)
+			continue
+		} else if !ok {
+			continue
+		}
+
+		return applyHealthRule(result, rule)
+	}
+
+	return result
+}
+
+func applyHealthRule(result v1.ScrapeResult, rule v1.HealthRule) v1.ScrapeResult {
+	if rule.Mode == v1.HealthRuleAugment && healthSeverity(rule.Health) <= healthSeverity(result.Health) {
+		return result
+	}
+
+	if rule.Health != "" {
+		result.Health = rule.Health
+	}
+	if rule.Status != "" {
+		result.Status = rule.Status
+	}
+	if rule.Message != "" {
+		result.Description = rule.Message
+	}
+
+	result.FlushMap()
+	return result
+}
+
+// healthSeverity orders health from unset to unhealthy
+func healthSeverity(health models.Health) int {
+	switch health {
+	case models.HealthHealthy:
+		return 1
+	case models.HealthWarning:
+		return 2
+	case models.HealthUnhealthy:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,73 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/flanksource/duty/context"
+	"github.com/flanksource/duty/models"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func TestApplyHealthRules(t *testing.T) {
+	ctx := api.NewScrapeContext(context.New())
+
+	deployment := func(replicas, ready int) v1.ScrapeResult {
+		return v1.ScrapeResult{
+			ID:     "deployment",
+			Type:   "Kubernetes::Deployment",
+			Health: models.HealthHealthy,
+			Status: "Running",
+			Config: map[string]any{
+				"spec":   map[string]any{"replicas": replicas},
+				"status": map[string]any{"readyReplicas": ready},
+			},
+		}
+	}
+
+	rules := []v1.HealthRule{
+		{Type: "Kubernetes::StatefulSet", Expr: "true", Health: models.HealthUnhealthy},
+		{Type: "Kubernetes::Deployment", Expr: "config.spec.replicas != config.status.readyReplicas", Health: models.HealthWarning, Status: "Degraded", Message: "not all replicas are ready"},
+		{Type: "Kubernetes::*", Expr: "config.missing.field == 1", Health: models.HealthUnhealthy},
+		{Expr: "true", Status: "Matched"},
+	}
+
+	got := applyHealthRules(ctx, deployment(3, 1), rules)
+	if got.Health != models.HealthWarning || got.Status != "Degraded" || got.Description != "not all replicas are ready" {
+		t.Errorf("expected the deployment rule to apply, got health=%s status=%s description=%s", got.Health, got.Status, got.Description)
+	}
+	if got.AsMap()["health"] != models.HealthWarning {
+		t.Errorf("expected the cached map to be refreshed, got %v", got.AsMap()["health"])
+	}
+
+	// The rule with the missing field is skipped, the catch-all rule only sets the status
+	got = applyHealthRules(ctx, deployment(3, 3), rules)
+	if got.Health != models.HealthHealthy || got.Status != "Matched" {
+		t.Errorf("expected the catch-all rule to apply, got health=%s status=%s", got.Health, got.Status)
+	}
+}
+
+func TestApplyHealthRule_Augment(t *testing.T) {
+	tests := []struct {
+		current, rule, want models.Health
+	}{
+		{current: "", rule: models.HealthHealthy, want: models.HealthHealthy},
+		{current: models.HealthUnknown, rule: models.HealthWarning, want: models.HealthWarning},
+		{current: models.HealthHealthy, rule: models.HealthWarning, want: models.HealthWarning},
+		{current: models.HealthUnhealthy, rule: models.HealthWarning, want: models.HealthUnhealthy},
+		{current: models.HealthWarning, rule: models.HealthHealthy, want: models.HealthWarning},
+	}
+
+	for _, tt := range tests {
+		got := applyHealthRule(v1.ScrapeResult{Health: tt.current}, v1.HealthRule{Health: tt.rule, Mode: v1.HealthRuleAugment})
+		if got.Health != tt.want {
+			t.Errorf("%s augmented with %s: got %s, want %s", tt.current, tt.rule, got.Health, tt.want)
+		}
+
+		got = applyHealthRule(v1.ScrapeResult{Health: tt.current}, v1.HealthRule{Health: tt.rule})
+		if got.Health != tt.rule {
+			t.Errorf("%s overridden with %s: got %s", tt.current, tt.rule, got.Health)
+		}
+	}
+}
@@ -59,6 +59,9 @@ type Transform struct {
 	Relationship []v1.RelationshipConfig
 	Locations    []v1.LocationOrAlias
 	Aliases      []v1.LocationOrAlias
+	Health       []v1.HealthRule
+	Analysis     []v1.AnalysisRule
+	Rego         []v1.RegoPolicy
 }
 
 func (t *Transform) String() string {
@@ -218,6 +221,9 @@ func NewExtractor(config v1.BaseScraper) (Extract, error) {
 	extract.Transform.Relationship = config.Transform.Relationship
 	extract.Transform.Locations = config.Transform.Locations
 	extract.Transform.Aliases = config.Transform.Aliases
+	extract.Transform.Health = config.Transform.Health
+	extract.Transform.Analysis = config.Transform.Analysis
+	extract.Transform.Rego = config.Transform.Rego
 
 	for _, mask := range config.Transform.Masks {
 		if mask.Selector == "" {
@@ -417,6 +423,12 @@ func (e Extract) Extract(ctx api.ScrapeContext, inputs ...v1.ScrapeResult) ([]v1
 					return results, errors.Wrapf(err, "Failed parse yaml %s", input)
 				}
 				input.Config = string(contentByte)
+			} else if parse, ok := formatParsers[strings.ToLower(input.Format)]; ok {
+				parsed, err := parse(v)
+				if err != nil {
+					return results, errors.Wrapf(err, "Failed parse %s %s", input.Format, input)
+				}
+				input.Config = parsed
 			} else {
 				input.Config = map[string]any{
 					"format":  input.Format,
@@ -463,6 +475,16 @@ func (e Extract) Extract(ctx api.ScrapeContext, inputs ...v1.ScrapeResult) ([]v1
 				results = append(results, extracted...)
 				continue
 			}
+		} else if rows, ok := parsedConfig.([]any); ok && strings.EqualFold(input.Format, "csv") {
+			// Every row of a csv file is a config item, use `id` to select the column it is keyed by
+			for _, row := range rows {
+				extracted, err := e.Extract(ctx, input.Clone(row))
+				if err != nil {
+					return results, fmt.Errorf("failed to extract csv row: %v", err)
+				}
+				results = append(results, extracted...)
+			}
+			continue
 		}
 
 		input.Config = parsedConfig
@@ -527,6 +549,7 @@ func (e Extract) Extract(ctx api.ScrapeContext, inputs ...v1.ScrapeResult) ([]v1
 			}
 
 			extracted = extracted.SetHealthIfEmpty()
+			extracted = applyHealthRules(ctx, extracted, e.Transform.Health)
 
 			// Form new relationships based on the transform configs
 			if newRelationships, err := getRelationshipsFromRelationshipConfigs(ctx, extracted, e.Transform.Relationship); err != nil {
@@ -535,7 +558,10 @@ func (e Extract) Extract(ctx api.ScrapeContext, inputs ...v1.ScrapeResult) ([]v1
 				extracted.RelationshipSelectors = append(extracted.RelationshipSelectors, newRelationships...)
 			}
 
+			regoResults := regoAnalysisResults(ctx, &extracted, e.Transform.Rego)
 			results = append(results, extracted)
+			results = append(results, analysisRuleResults(ctx, extracted, e.Transform.Analysis)...)
+			results = append(results, regoResults...)
 		}
 
 		if !input.BaseScraper.Transform.Masks.IsEmpty() {
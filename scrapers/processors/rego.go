@@ -0,0 +1,184 @@
+package processors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flanksource/commons/hash"
+	"github.com/flanksource/duty/connection"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flanksource/config-db/analyzers"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// regoAnalyzers caches the compiled policies, and the policies that failed to load, so that
+// git checkouts and ConfigMaps are only read once per expiry and not for every config item.
+var (
+	regoAnalyzers = cache.New(15*time.Minute, 30*time.Minute)
+	regoLock      sync.Mutex
+)
+
+type regoAnalyzer struct {
+	analyzer *analyzers.RegoAnalyzer
+	err      error
+}
+
+// regoAnalysisResults returns the analyses reported by the rego policies that match the config item.
+// Policies that fail to load are reported as warnings of the config item.
+func regoAnalysisResults(ctx api.ScrapeContext, result *v1.ScrapeResult, policies []v1.RegoPolicy) []v1.ScrapeResult {
+	if result.ID == "" || result.Config == nil {
+		return nil
+	}
+
+	var results []v1.ScrapeResult
+	for _, policy := range policies {
+		if policy.Type != "" && !policy.Type.Match(result.Type) {
+			continue
+		}
+
+		analyzer, err := getRegoAnalyzer(ctx, policy)
+		if err != nil {
+			result.Warnings = append(result.Warnings, v1.Warning{Error: fmt.Sprintf("failed to load rego policies: %v", err)})
+			continue
+		}
+
+		analyses, err := analyzer.Analyze(ctx, *result)
+		if err != nil {
+			ctx.Logger.V(3).Infof("rego policies failed for %s: %v", result.ID, err)
+			continue
+		}
+
+		for i := range analyses {
+			results = append(results, v1.ScrapeResult{
+				BaseScraper:    result.BaseScraper,
+				AnalysisResult: &analyses[i],
+			})
+		}
+	}
+
+	return results
+}
+
+func getRegoAnalyzer(ctx api.ScrapeContext, policy v1.RegoPolicy) (*analyzers.RegoAnalyzer, error) {
+	spec, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	key := hash.Sha256Hex(ctx.Namespace() + string(spec))
+
+	regoLock.Lock()
+	defer regoLock.Unlock()
+
+	if cached, ok := regoAnalyzers.Get(key); ok {
+		entry := cached.(regoAnalyzer)
+		return entry.analyzer, entry.err
+	}
+
+	analyzer, err := newRegoAnalyzer(ctx, policy)
+	if err != nil {
+		ctx.Logger.Errorf("failed to load rego policies: %v", err)
+		ctx.JobHistory().AddError(fmt.Sprintf("failed to load rego policies: %v", err))
+	}
+
+	regoAnalyzers.SetDefault(key, regoAnalyzer{analyzer: analyzer, err: err})
+	return analyzer, err
+}
+
+func newRegoAnalyzer(ctx api.ScrapeContext, policy v1.RegoPolicy) (*analyzers.RegoAnalyzer, error) {
+	modules, err := regoModules(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+	return analyzers.NewRegoAnalyzer(ctx, policy, modules)
+}
+
+// regoModules returns the inline modules, along with the ones in the ConfigMap and under the path.
+func regoModules(ctx api.ScrapeContext, policy v1.RegoPolicy) (map[string]string, error) {
+	modules := maps.Clone(policy.Modules)
+	if modules == nil {
+		modules = map[string]string{}
+	}
+
+	if policy.ConfigMap != nil {
+		k8s, err := ctx.Kubernetes()
+		if err != nil {
+			return nil, fmt.Errorf("error creating kubernetes client: %w", err)
+		}
+
+		namespace := lo.CoalesceOrEmpty(policy.ConfigMap.Namespace, ctx.Namespace())
+		configMap, err := k8s.CoreV1().ConfigMaps(namespace).Get(ctx, policy.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configmap %s/%s: %w", namespace, policy.ConfigMap.Name, err)
+		}
+
+		for key, value := range configMap.Data {
+			if strings.HasSuffix(key, ".rego") {
+				modules[fmt.Sprintf("configmap/%s/%s/%s", namespace, policy.ConfigMap.Name, key)] = value
+			}
+		}
+	}
+
+	path := policy.Path
+	if policy.Checkout != nil {
+		dir, err := regoCheckout(ctx, policy.Checkout)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, policy.Path)
+	}
+
+	if path != "" {
+		err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(file) != ".rego" || strings.HasSuffix(file, "_test.rego") {
+				return nil
+			}
+
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			modules[file] = string(content)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rego modules from %s: %w", path, err)
+		}
+	}
+
+	return modules, nil
+}
+
+// regoCheckout clones, or updates, the git repository of the policies and returns its directory.
+func regoCheckout(ctx api.ScrapeContext, conn *connection.GitConnection) (string, error) {
+	// Hydrating mutates the connection, don't leak the secrets into the scraper spec
+	checkout := conn.DeepCopy()
+	if err := checkout.HydrateConnection(ctx.DutyContext()); err != nil {
+		return "", fmt.Errorf("error hydrating git connection: %w", err)
+	}
+
+	client, err := connection.CreateGitConfig(ctx.DutyContext(), checkout)
+	if err != nil {
+		return "", err
+	}
+
+	// The checkout of a repository and branch is reused by the following loads
+	dir := filepath.Join(os.TempDir(), "config-db", "rego", hash.Sha256Hex(checkout.URL+"#"+checkout.Branch))
+	if _, err := client.Clone(ctx.DutyContext(), dir); err != nil {
+		return "", fmt.Errorf("failed to checkout %s: %w", client.RedactedURL(), err)
+	}
+	return dir, nil
+}
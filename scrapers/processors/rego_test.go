@@ -0,0 +1,143 @@
+package processors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flanksource/duty/context"
+	"github.com/flanksource/duty/models"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const privilegedPolicy = `package kubernetes.privileged
+
+deny contains msg if {
+	some container in input.spec.template.spec.containers
+	container.securityContext.privileged
+	msg := sprintf("container %s is privileged", [container.name])
+}
+
+warn contains {"msg": "replicas should be greater than 1"} if {
+	input.spec.replicas < 2
+}
+`
+
+const latestTagPolicy = `package kubernetes.images
+
+warn contains msg if {
+	some container in input.spec.template.spec.containers
+	endswith(container.image, ":latest")
+	msg := sprintf("container %s uses the latest tag", [container.name])
+}
+`
+
+func TestRegoAnalysisResults(t *testing.T) {
+	ctx := api.NewScrapeContext(context.New())
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "images.rego"), []byte(latestTagPolicy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Tests of the policies are not loaded
+	if err := os.WriteFile(filepath.Join(dir, "images_test.rego"), []byte("package kubernetes.images_test\n\ninvalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment := v1.ScrapeResult{
+		ID:   "deployment-id",
+		Name: "web",
+		Type: "Kubernetes::Deployment",
+		Config: map[string]any{
+			"spec": map[string]any{
+				"replicas": 1,
+				"template": map[string]any{"spec": map[string]any{"containers": []any{
+					map[string]any{"name": "web", "image": "nginx:latest", "securityContext": map[string]any{"privileged": true}},
+				}}},
+			},
+		},
+	}
+
+	policies := []v1.RegoPolicy{
+		{Type: "Kubernetes::Deployment", Modules: map[string]string{"privileged.rego": privilegedPolicy}, Path: dir},
+		{Type: "Kubernetes::Pod", Modules: map[string]string{"privileged.rego": privilegedPolicy}},
+	}
+
+	results := regoAnalysisResults(ctx, &deployment, policies)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 analyses, got %d", len(results))
+	}
+
+	images := results[0].AnalysisResult
+	if images.Analyzer != "kubernetes.images" || images.ExternalID != deployment.ID || images.ConfigType != deployment.Type {
+		t.Errorf("unexpected analysis %+v", images)
+	}
+	if images.Severity != models.SeverityLow || images.AnalysisType != models.AnalysisTypeSecurity || images.Source != "Rego" {
+		t.Errorf("unexpected severity=%s category=%s source=%s", images.Severity, images.AnalysisType, images.Source)
+	}
+	if len(images.Messages) != 1 || images.Messages[0] != "container web uses the latest tag" {
+		t.Errorf("unexpected messages %v", images.Messages)
+	}
+
+	privileged := results[1].AnalysisResult
+	if privileged.Analyzer != "kubernetes.privileged" || privileged.Severity != models.SeverityHigh {
+		t.Errorf("unexpected analyzer=%s severity=%s", privileged.Analyzer, privileged.Severity)
+	}
+	want := []string{"container web is privileged", "replicas should be greater than 1"}
+	if len(privileged.Messages) != len(want) || privileged.Messages[0] != want[0] || privileged.Messages[1] != want[1] {
+		t.Errorf("got messages %v, want %v", privileged.Messages, want)
+	}
+}
+
+func TestRegoAnalysisResults_InvalidPolicy(t *testing.T) {
+	ctx := api.NewScrapeContext(context.New())
+
+	result := v1.ScrapeResult{ID: "id", Type: "Kubernetes::Pod", Config: map[string]any{}}
+	policies := []v1.RegoPolicy{{Modules: map[string]string{"invalid.rego": "package invalid\n\ndeny["}}}
+
+	if results := regoAnalysisResults(ctx, &result, policies); len(results) != 0 {
+		t.Errorf("expected no analyses for an invalid policy, got %d", len(results))
+	}
+	if len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0].Error, "failed to load rego policies") {
+		t.Errorf("expected a warning for the invalid policy, got %v", result.Warnings)
+	}
+
+	// The failure is cached until it expires, along with the compiled policies
+	if _, err := getRegoAnalyzer(ctx, policies[0]); err == nil {
+		t.Error("expected the cached failure of the invalid policy")
+	}
+	if regoAnalyzers.ItemCount() == 0 {
+		t.Error("expected the failure of the invalid policy to be cached")
+	}
+}
+
+func TestRegoAnalysisResults_RegoV0(t *testing.T) {
+	ctx := api.NewScrapeContext(context.New())
+
+	const conftestPolicy = `package main
+
+deny[msg] {
+	input.kind == "Deployment"
+	not input.spec.template.spec.securityContext.runAsNonRoot
+	msg := "containers must not run as root"
+}
+`
+	result := v1.ScrapeResult{ID: "id", Type: "Kubernetes::Deployment", Config: map[string]any{"kind": "Deployment"}}
+	policies := []v1.RegoPolicy{{RegoVersion: "v0", Modules: map[string]string{"policy.rego": conftestPolicy}}}
+
+	results := regoAnalysisResults(ctx, &result, policies)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 analysis, got %d: %v", len(results), result.Warnings)
+	}
+	if messages := results[0].AnalysisResult.Messages; len(messages) != 1 || messages[0] != "containers must not run as root" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+
+	// The same policy does not parse as rego v1
+	if _, err := getRegoAnalyzer(ctx, v1.RegoPolicy{Modules: policies[0].Modules}); err == nil {
+		t.Error("expected the v0 policy to fail to parse as v1")
+	}
+}
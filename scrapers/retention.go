@@ -82,6 +82,95 @@ func ProcessChangeRetention(ctx context.Context, scraperID uuid.UUID, spec v1.Ch
 	return nil
 }
 
+// ProcessAnalysisRetention deletes the analyses of the scraper that match the spec
+// and haven't been observed within its age. It returns the number of deleted analyses.
+func ProcessAnalysisRetention(ctx context.Context, scraperID uuid.UUID, spec v1.AnalysisRetentionSpec) (int64, error) {
+	if spec.Age == "" {
+		return 0, fmt.Errorf("age cannot be empty")
+	}
+
+	age, err := duration.ParseDuration(spec.Age)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing age %s as duration: %w", spec.Age, err)
+	}
+
+	const query = `
+		DELETE FROM config_analysis
+		WHERE id IN (
+			SELECT id
+			FROM config_analysis
+			WHERE (scraper_id = @scraperID OR (scraper_id IS NULL AND config_id IN (SELECT id FROM config_items WHERE scraper_id = @scraperID)))
+				AND (@analyzer = '' OR analyzer = @analyzer)
+				AND (@severity = '' OR severity::text = @severity)
+				AND (@status = '' OR status::text = @status)
+				AND COALESCE(last_observed, first_observed) < now() - interval '1 minute' * @ageMinutes
+			LIMIT @batchSize
+		)
+	`
+	deleted, err := deleteInBatches(ctx, query,
+		sql.Named("scraperID", scraperID),
+		sql.Named("analyzer", spec.Analyzer),
+		sql.Named("severity", spec.Severity),
+		sql.Named("status", spec.Status),
+		sql.Named("ageMinutes", int(age.Minutes())),
+		sql.Named("batchSize", properties.Int(1000, "analysis_retention.delete_batch_size")),
+	)
+	if err != nil {
+		return deleted, fmt.Errorf("error retaining config analyses by age: %w", err)
+	}
+
+	if deleted > 0 {
+		logger.Infof("Deleted %d config_analysis for Scraper[%s] as per AnalysisRetentionSpec[%s]", deleted, scraperID, spec.Analyzer)
+	}
+	return deleted, nil
+}
+
+// ProcessRelationshipRetention deletes the relationships of the scraper that were deleted,
+// or whose config items were deleted, before the age. It returns the number of deleted relationships.
+func ProcessRelationshipRetention(ctx context.Context, scraperID uuid.UUID, spec v1.RelationshipRetentionSpec) (int64, error) {
+	if spec.DeletedAge == "" {
+		return 0, fmt.Errorf("deletedAge cannot be empty")
+	}
+
+	age, err := duration.ParseDuration(spec.DeletedAge)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing deletedAge %s as duration: %w", spec.DeletedAge, err)
+	}
+
+	// Rows are matched by ctid as selector_id is nullable
+	const query = `
+		DELETE FROM config_relationships
+		WHERE ctid IN (
+			SELECT cr.ctid
+			FROM config_relationships cr
+			JOIN config_items parent ON cr.config_id = parent.id
+			JOIN config_items child ON cr.related_id = child.id
+			WHERE (cr.scraper_id = @scraperID OR parent.scraper_id = @scraperID OR child.scraper_id = @scraperID)
+				AND (@relation = '' OR cr.relation = @relation)
+				AND (
+					cr.deleted_at < now() - interval '1 minute' * @ageMinutes
+					OR parent.deleted_at < now() - interval '1 minute' * @ageMinutes
+					OR child.deleted_at < now() - interval '1 minute' * @ageMinutes
+				)
+			LIMIT @batchSize
+		)
+	`
+	deleted, err := deleteInBatches(ctx, query,
+		sql.Named("scraperID", scraperID),
+		sql.Named("relation", spec.Relation),
+		sql.Named("ageMinutes", int(age.Minutes())),
+		sql.Named("batchSize", properties.Int(1000, "relationship_retention.delete_batch_size")),
+	)
+	if err != nil {
+		return deleted, fmt.Errorf("error retaining config relationships by deleted age: %w", err)
+	}
+
+	if deleted > 0 {
+		logger.Infof("Deleted %d config_relationships for Scraper[%s] as per RelationshipRetentionSpec[%s]", deleted, scraperID, spec.Relation)
+	}
+	return deleted, nil
+}
+
 func deleteInBatches(ctx context.Context, query string, args ...any) (int64, error) {
 	var total int64
 	for {
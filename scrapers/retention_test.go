@@ -0,0 +1,105 @@
+package scrapers
+
+import (
+	"time"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db/models"
+	dutymodels "github.com/flanksource/duty/models"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+)
+
+var _ = Describe("Retention", Ordered, func() {
+	var (
+		scraper       dutymodels.ConfigScraper
+		configItemID  string
+		deletedItemID string
+	)
+
+	BeforeAll(func() {
+		scraper = dutymodels.ConfigScraper{
+			Name:   "retention-test",
+			Spec:   `{"foo":"bar"}`,
+			Source: dutymodels.SourceConfigFile,
+		}
+		Expect(ctx.DB().Create(&scraper).Error).To(BeNil())
+
+		configItemID = uuid.New().String()
+		deletedItemID = uuid.New().String()
+		for _, ci := range []models.ConfigItem{
+			{ID: configItemID, ConfigClass: "Test", Type: "Test", ScraperID: &scraper.ID},
+			{ID: deletedItemID, ConfigClass: "Test", Type: "Test", ScraperID: &scraper.ID, DeletedAt: lo.ToPtr(time.Now().Add(-10 * 24 * time.Hour))},
+		} {
+			Expect(ctx.DB().Create(&ci).Error).To(BeNil())
+		}
+	})
+
+	It("should delete analyses as per the spec", func() {
+		tenDaysAgo := time.Now().Add(-10 * 24 * time.Hour)
+		analyses := []dutymodels.ConfigAnalysis{
+			{Analyzer: "trivy", Severity: dutymodels.SeverityLow, Status: dutymodels.AnalysisStatusResolved, LastObserved: &tenDaysAgo},
+			{Analyzer: "trivy", Severity: dutymodels.SeverityHigh, Status: dutymodels.AnalysisStatusResolved, LastObserved: &tenDaysAgo},
+			{Analyzer: "trivy", Severity: dutymodels.SeverityLow, Status: dutymodels.AnalysisStatusOpen, LastObserved: &tenDaysAgo},
+			{Analyzer: "trivy", Severity: dutymodels.SeverityLow, Status: dutymodels.AnalysisStatusResolved, LastObserved: lo.ToPtr(time.Now())},
+			{Analyzer: "kube-score", Severity: dutymodels.SeverityLow, Status: dutymodels.AnalysisStatusResolved, LastObserved: &tenDaysAgo},
+		}
+		for i := range analyses {
+			analyses[i].ID = uuid.New()
+			analyses[i].ConfigID = uuid.MustParse(configItemID)
+			analyses[i].ScraperID = &scraper.ID
+			analyses[i].AnalysisType = dutymodels.AnalysisTypeSecurity
+			Expect(ctx.DB().Create(&analyses[i]).Error).To(BeNil())
+		}
+
+		deleted, err := ProcessAnalysisRetention(ctx.Context, scraper.ID, v1.AnalysisRetentionSpec{
+			Analyzer: "trivy",
+			Severity: "low",
+			Status:   "resolved",
+			Age:      "7d",
+		})
+		Expect(err).To(BeNil())
+		Expect(deleted).To(Equal(int64(1)))
+
+		var remaining []string
+		Expect(ctx.DB().Model(&dutymodels.ConfigAnalysis{}).Where("config_id = ?", configItemID).Pluck("id", &remaining).Error).To(BeNil())
+		Expect(remaining).To(HaveLen(4))
+		Expect(remaining).ToNot(ContainElement(analyses[0].ID.String()))
+
+		_, err = ProcessAnalysisRetention(ctx.Context, scraper.ID, v1.AnalysisRetentionSpec{Analyzer: "trivy"})
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("should delete relationships of deleted config items as per the spec", func() {
+		otherID := uuid.New().String()
+		Expect(ctx.DB().Create(&models.ConfigItem{ID: otherID, ConfigClass: "Test", Type: "Test", ScraperID: &scraper.ID}).Error).To(BeNil())
+
+		relationships := []dutymodels.ConfigRelationship{
+			{ConfigID: configItemID, RelatedID: deletedItemID, Relation: "TestDeleted"},
+			{ConfigID: configItemID, RelatedID: otherID, Relation: "TestDeleted"},
+			{ConfigID: configItemID, RelatedID: otherID, Relation: "TestRecent", SelectorID: "recent", DeletedAt: lo.ToPtr(time.Now())},
+		}
+		for _, r := range relationships {
+			Expect(ctx.DB().Create(&r).Error).To(BeNil())
+		}
+		Expect(ctx.DB().Exec(`INSERT INTO config_relationships (config_id, related_id, relation, selector_id) VALUES (?, ?, ?, NULL)`,
+			deletedItemID, configItemID, "TestDeletedNullSelector").Error).To(BeNil())
+
+		deleted, err := ProcessRelationshipRetention(ctx.Context, scraper.ID, v1.RelationshipRetentionSpec{DeletedAge: "7d"})
+		Expect(err).To(BeNil())
+		Expect(deleted).To(Equal(int64(2)))
+
+		var nullSelector int64
+		Expect(ctx.DB().Model(&dutymodels.ConfigRelationship{}).Where("config_id = ?", deletedItemID).Count(&nullSelector).Error).To(BeNil())
+		Expect(nullSelector).To(BeZero())
+
+		var remaining int64
+		Expect(ctx.DB().Model(&dutymodels.ConfigRelationship{}).Where("config_id = ?", configItemID).Count(&remaining).Error).To(BeNil())
+		Expect(remaining).To(Equal(int64(2)))
+
+		_, err = ProcessRelationshipRetention(ctx.Context, scraper.ID, v1.RelationshipRetentionSpec{})
+		Expect(err).ToNot(BeNil())
+	})
+})
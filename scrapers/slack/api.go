@@ -90,7 +90,7 @@ func NewSlackAPI(ctx api.ScrapeContext, token string) (*SlackAPI, error) {
 	conn := connection.HTTPConnection{
 		Bearer: types.EnvVar{ValueStatic: token},
 	}
-	client, err := connection.CreateHTTPClient(ctx, conn, types.WithFeature("slack"))
+	client, err := ctx.NewHTTPClient(conn, types.WithFeature("slack"))
 	if err != nil {
 		return nil, err
 	}
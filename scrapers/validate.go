@@ -0,0 +1,370 @@
+package scrapers
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template/parse"
+
+	"github.com/flanksource/commons/duration"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/utils"
+	"github.com/flanksource/duty"
+	"github.com/google/cel-go/cel"
+	"github.com/ohler55/ojg/jp"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var changeActions = []v1.ChangeAction{v1.Delete, v1.Ignore, v1.MoveUp, v1.CopyUp, v1.Copy, v1.Move, v1.Aggregate}
+
+// gcpAssetType matches Cloud Asset Inventory types, e.g. storage.googleapis.com/Bucket
+var gcpAssetType = regexp.MustCompile(`^[a-z0-9.-]+\.googleapis\.com/[A-Za-z0-9]+$`)
+
+// celEnv parses expressions without checking them, as the variables and functions
+// available to an expression depend on where it is evaluated.
+var celEnv, _ = cel.NewEnv(cel.OptionalTypes())
+
+// ValidateScrapeConfig compiles the expressions, JSONPaths and templates of the scrape config
+// and checks its schedule, durations and include values, without running it.
+func ValidateScrapeConfig(sc v1.ScrapeConfig) field.ErrorList {
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+
+	if sc.Spec.Schedule != "" && sc.Spec.Schedule != "@never" {
+		if _, err := cron.ParseStandard(sc.Spec.Schedule); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("schedule"), sc.Spec.Schedule, err.Error()))
+		}
+	}
+	errs = append(errs, validateDuration(spec.Child("timeout"), sc.Spec.Timeout)...)
+	errs = append(errs, validateRetention(spec.Child("retention"), sc.Spec.Retention)...)
+	errs = append(errs, validateCorrelations(spec.Child("correlation"), sc.Spec.Correlation)...)
+	for i, limit := range sc.Spec.ChangeRateLimits {
+		errs = append(errs, validateDuration(spec.Child("changeRateLimits").Index(i).Child("window"), limit.Window)...)
+	}
+	errs = append(errs, validateDuration(spec.Child("attribution", "window"), sc.Spec.Attribution.Window)...)
+
+	for i, aws := range sc.Spec.AWS {
+		path := spec.Child("aws").Index(i).Child("include")
+		for j, include := range aws.Include {
+			if !slices.ContainsFunc(v1.AllAWSIncludes, func(s string) bool { return strings.EqualFold(s, include) }) {
+				errs = append(errs, field.NotSupported(path.Index(j), include, v1.AllAWSIncludes))
+			}
+		}
+	}
+	for i, gcp := range sc.Spec.GCP {
+		path := spec.Child("gcp").Index(i).Child("include")
+		for j, include := range gcp.Include {
+			if !slices.Contains(v1.AllIncludes, include) && !gcpAssetType.MatchString(include) {
+				errs = append(errs, field.NotSupported(path.Index(j), include, append(slices.Clone(v1.AllIncludes), "<service>.googleapis.com/<AssetType>")))
+			}
+		}
+	}
+
+	for _, scraper := range scraperBases(sc.Spec, spec) {
+		errs = append(errs, validateBaseScraper(scraper.path, scraper.base)...)
+	}
+	return errs
+}
+
+// ValidateScrapePlugin compiles the expressions and templates of the scrape plugin and checks its durations.
+func ValidateScrapePlugin(plugin v1.ScrapePlugin) field.ErrorList {
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+
+	errs = append(errs, validateChanges(spec.Child("changes"), plugin.Spec.Change)...)
+	if plugin.Spec.Retention != nil {
+		errs = append(errs, validateRetention(spec.Child("retention"), *plugin.Spec.Retention)...)
+	}
+	errs = append(errs, validateCorrelations(spec.Child("correlation"), plugin.Spec.Correlation)...)
+	errs = append(errs, validateRelationships(spec.Child("relationship"), plugin.Spec.Relationship)...)
+	errs = append(errs, validateProperties(spec.Child("properties"), plugin.Spec.Properties)...)
+	errs = append(errs, validateLocations(spec.Child("locations"), plugin.Spec.Locations)...)
+	errs = append(errs, validateLocations(spec.Child("aliases"), plugin.Spec.Aliases)...)
+	errs = append(errs, validateHealth(spec.Child("health"), plugin.Spec.Health)...)
+	errs = append(errs, validateAnalysis(spec.Child("analysis"), plugin.Spec.Analysis)...)
+	return errs
+}
+
+type scraperBase struct {
+	path *field.Path
+	base v1.BaseScraper
+}
+
+func scraperBases(spec v1.ScraperSpec, path *field.Path) []scraperBase {
+	var bases []scraperBase
+	add := func(name string, i int, base v1.BaseScraper) {
+		bases = append(bases, scraperBase{path: path.Child(name).Index(i), base: base})
+	}
+
+	for i, s := range spec.GCP {
+		add("gcp", i, s.BaseScraper)
+	}
+	for i, s := range spec.AWS {
+		add("aws", i, s.BaseScraper)
+	}
+	for i, s := range spec.File {
+		add("file", i, s.BaseScraper)
+	}
+	for i, s := range spec.Kubernetes {
+		add("kubernetes", i, s.BaseScraper)
+	}
+	for i, s := range spec.KubernetesFile {
+		add("kubernetesFile", i, s.BaseScraper)
+	}
+	for i, s := range spec.AzureDevops {
+		add("azureDevops", i, s.BaseScraper)
+	}
+	for i, s := range spec.GitHub {
+		add("github", i, s.BaseScraper)
+	}
+	for i, s := range spec.GithubActions {
+		add("githubActions", i, s.BaseScraper)
+	}
+	for i, s := range spec.Azure {
+		add("azure", i, s.BaseScraper)
+	}
+	for i, s := range spec.Postgres {
+		add("postgres", i, s.BaseScraper)
+	}
+	for i, s := range spec.SQL {
+		add("sql", i, s.BaseScraper)
+	}
+	for i, s := range spec.Slack {
+		add("slack", i, s.BaseScraper)
+	}
+	for i, s := range spec.Trivy {
+		add("trivy", i, s.BaseScraper)
+	}
+	for i, s := range spec.Terraform {
+		add("terraform", i, s.BaseScraper)
+	}
+	for i, s := range spec.HTTP {
+		add("http", i, s.BaseScraper)
+	}
+	for i, s := range spec.Clickhouse {
+		add("clickhouse", i, s.BaseScraper)
+	}
+	for i, s := range spec.Logs {
+		add("logs", i, s.BaseScraper)
+	}
+	for i, s := range spec.PubSub {
+		add("pubsub", i, s.BaseScraper)
+	}
+	for i, s := range spec.Exec {
+		add("exec", i, s.BaseScraper)
+	}
+	for i, s := range spec.Playwright {
+		add("playwright", i, s.BaseScraper)
+	}
+	return bases
+}
+
+func validateBaseScraper(path *field.Path, base v1.BaseScraper) field.ErrorList {
+	var errs field.ErrorList
+
+	// Except for items, these are JSONPaths only when they look like one, and static values otherwise
+	fields := map[string]string{
+		"id":          base.ID,
+		"name":        base.Name,
+		"description": base.Description,
+		"type":        base.Type,
+		"class":       base.Class,
+		"status":      base.Status,
+		"health":      base.Health,
+	}
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if utils.IsJSONPath(fields[name]) {
+			errs = append(errs, validateJSONPath(path.Child(name), fields[name])...)
+		}
+	}
+	errs = append(errs, validateJSONPath(path.Child("items"), base.Items)...)
+	for i, createField := range base.CreateFields {
+		if utils.IsJSONPath(createField) {
+			errs = append(errs, validateJSONPath(path.Child("createFields").Index(i), createField)...)
+		}
+	}
+	for i, deleteField := range base.DeleteFields {
+		if utils.IsJSONPath(deleteField) {
+			errs = append(errs, validateJSONPath(path.Child("deleteFields").Index(i), deleteField)...)
+		}
+	}
+	errs = append(errs, validateProperties(path.Child("properties"), base.Properties)...)
+
+	transform := path.Child("transform")
+	errs = append(errs, validateTemplate(transform.Child("gotemplate"), base.Transform.GoTemplate)...)
+	errs = append(errs, validateJSONPath(transform.Child("jsonpath"), base.Transform.JSONPath)...)
+	errs = append(errs, validateCEL(transform.Child("expr"), base.Transform.Expression)...)
+	for i, exclude := range base.Transform.Exclude {
+		errs = append(errs, validateJSONPath(transform.Child("exclude").Index(i).Child("jsonpath"), exclude.JSONPath)...)
+	}
+	for i, mask := range base.Transform.Masks {
+		errs = append(errs, validateCEL(transform.Child("mask").Index(i).Child("selector"), mask.Selector)...)
+		errs = append(errs, validateJSONPath(transform.Child("mask").Index(i).Child("jsonpath"), mask.JSONPath)...)
+	}
+	errs = append(errs, validateRelationships(transform.Child("relationship"), base.Transform.Relationship)...)
+	errs = append(errs, validateChanges(transform.Child("changes"), base.Transform.Change)...)
+	errs = append(errs, validateLocations(transform.Child("locations"), base.Transform.Locations)...)
+	errs = append(errs, validateLocations(transform.Child("aliases"), base.Transform.Aliases)...)
+	errs = append(errs, validateHealth(transform.Child("health"), base.Transform.Health)...)
+	errs = append(errs, validateAnalysis(transform.Child("analysis"), base.Transform.Analysis)...)
+	return errs
+}
+
+func validateChanges(path *field.Path, changes v1.TransformChange) field.ErrorList {
+	var errs field.ErrorList
+	for i, exclude := range changes.Exclude {
+		errs = append(errs, validateCEL(path.Child("exclude").Index(i), exclude)...)
+	}
+
+	for i, mapping := range changes.Mapping {
+		mappingPath := path.Child("mapping").Index(i)
+		errs = append(errs, validateCEL(mappingPath.Child("filter"), mapping.Filter)...)
+		errs = append(errs, validateCEL(mappingPath.Child("config_id"), mapping.ConfigID)...)
+		errs = append(errs, validateCEL(mappingPath.Child("group_by"), mapping.GroupBy)...)
+		errs = append(errs, validateTemplate(mappingPath.Child("summary"), mapping.Summary)...)
+		errs = append(errs, validateDuration(mappingPath.Child("window"), mapping.Window)...)
+
+		if mapping.Action != "" && !slices.Contains(changeActions, mapping.Action) {
+			errs = append(errs, field.NotSupported(mappingPath.Child("action"), mapping.Action, changeActions))
+		}
+		if mapping.Target != nil && !mapping.Target.IsEmpty() {
+			if mapping.Action == v1.MoveUp || mapping.Action == v1.CopyUp || mapping.AncestorType != "" {
+				errs = append(errs, field.Forbidden(mappingPath.Child("target"), "target is mutually exclusive with move-up/copy-up/ancestor_type"))
+			}
+			errs = append(errs, validateSelector(mappingPath.Child("target"), *mapping.Target)...)
+		}
+	}
+	return errs
+}
+
+func validateRelationships(path *field.Path, relationships []v1.RelationshipConfig) field.ErrorList {
+	var errs field.ErrorList
+	for i, relationship := range relationships {
+		errs = append(errs, validateCEL(path.Index(i).Child("expr"), relationship.Expr)...)
+		errs = append(errs, validateCEL(path.Index(i).Child("filter"), relationship.Filter)...)
+		errs = append(errs, validateSelector(path.Index(i), relationship.RelationshipSelectorTemplate)...)
+	}
+	return errs
+}
+
+func validateSelector(path *field.Path, selector duty.RelationshipSelectorTemplate) field.ErrorList {
+	var errs field.ErrorList
+	lookups := map[string]duty.Lookup{
+		"id":          selector.ID,
+		"external_id": selector.ExternalID,
+		"name":        selector.Name,
+		"namespace":   selector.Namespace,
+		"type":        selector.Type,
+		"agent":       selector.Agent,
+		"scope":       selector.Scope,
+	}
+	for _, name := range slices.Sorted(maps.Keys(lookups)) {
+		errs = append(errs, validateCEL(path.Child(name, "expr"), lookups[name].Expr)...)
+	}
+	return errs
+}
+
+func validateProperties(path *field.Path, properties []v1.ConfigProperties) field.ErrorList {
+	var errs field.ErrorList
+	for i, property := range properties {
+		errs = append(errs, validateCEL(path.Index(i).Child("filter"), property.Filter)...)
+	}
+	return errs
+}
+
+func validateLocations(path *field.Path, locations []v1.LocationOrAlias) field.ErrorList {
+	var errs field.ErrorList
+	for i, location := range locations {
+		errs = append(errs, validateCEL(path.Index(i).Child("filter"), string(location.Filter))...)
+	}
+	return errs
+}
+
+func validateHealth(path *field.Path, rules []v1.HealthRule) field.ErrorList {
+	var errs field.ErrorList
+	for i, rule := range rules {
+		errs = append(errs, validateCEL(path.Index(i).Child("expr"), string(rule.Expr))...)
+	}
+	return errs
+}
+
+func validateAnalysis(path *field.Path, rules []v1.AnalysisRule) field.ErrorList {
+	var errs field.ErrorList
+	for i, rule := range rules {
+		errs = append(errs, validateCEL(path.Index(i).Child("expr"), string(rule.Expr))...)
+	}
+	return errs
+}
+
+func validateRetention(path *field.Path, retention v1.RetentionSpec) field.ErrorList {
+	var errs field.ErrorList
+	for i, changes := range retention.Changes {
+		errs = append(errs, validateDuration(path.Child("changes").Index(i).Child("age"), changes.Age)...)
+	}
+	for i, types := range retention.Types {
+		errs = append(errs, validateDuration(path.Child("types").Index(i).Child("createdAge"), types.CreatedAge)...)
+		errs = append(errs, validateDuration(path.Child("types").Index(i).Child("updatedAge"), types.UpdatedAge)...)
+		errs = append(errs, validateDuration(path.Child("types").Index(i).Child("deletedAge"), types.DeletedAge)...)
+	}
+	for i, analysis := range retention.Analysis {
+		errs = append(errs, validateDuration(path.Child("analysis").Index(i).Child("age"), analysis.Age)...)
+	}
+	errs = append(errs, validateDuration(path.Child("staleItemAge"), retention.StaleItemAge)...)
+	errs = append(errs, validateDuration(path.Child("staleAnalysisAge"), retention.StaleAnalysisAge)...)
+	return errs
+}
+
+func validateCorrelations(path *field.Path, correlations []v1.ChangeCorrelation) field.ErrorList {
+	var errs field.ErrorList
+	for i, correlation := range correlations {
+		errs = append(errs, validateDuration(path.Index(i).Child("window"), correlation.Window)...)
+	}
+	return errs
+}
+
+// validateCEL only checks the syntax of a CEL expression. Unknown variables, functions and type errors are not reported.
+func validateCEL(path *field.Path, expression string) field.ErrorList {
+	if expression == "" {
+		return nil
+	}
+	if _, issues := celEnv.Parse(expression); issues != nil && issues.Err() != nil {
+		return field.ErrorList{field.Invalid(path, expression, fmt.Sprintf("invalid CEL expression: %v", issues.Err()))}
+	}
+	return nil
+}
+
+func validateJSONPath(path *field.Path, jsonPath string) field.ErrorList {
+	if jsonPath == "" {
+		return nil
+	}
+	if _, err := jp.ParseString(jsonPath); err != nil {
+		return field.ErrorList{field.Invalid(path, jsonPath, fmt.Sprintf("invalid JSONPath: %v", err))}
+	}
+	return nil
+}
+
+// validateTemplate parses a Go template. Functions are not checked, as they are provided by gomplate at run time.
+func validateTemplate(path *field.Path, template string) field.ErrorList {
+	if template == "" {
+		return nil
+	}
+	tree := parse.New(path.String())
+	tree.Mode = parse.SkipFuncCheck | parse.ParseComments
+	if _, err := tree.Parse(template, "", "", map[string]*parse.Tree{}); err != nil {
+		return field.ErrorList{field.Invalid(path, template, fmt.Sprintf("invalid template: %v", err))}
+	}
+	return nil
+}
+
+func validateDuration(path *field.Path, value string) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	if _, err := duration.ParseDuration(value); err != nil {
+		return field.ErrorList{field.Invalid(path, value, fmt.Sprintf("invalid duration: %v", err))}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package scrapers
+
+import (
+	v1 "github.com/flanksource/config-db/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("ValidateScrapeConfig", func() {
+	fields := func(errs field.ErrorList) []string {
+		var paths []string
+		for _, err := range errs {
+			paths = append(paths, err.Field)
+		}
+		return paths
+	}
+
+	It("accepts a valid scrape config", func() {
+		sc := v1.ScrapeConfig{Spec: v1.ScraperSpec{
+			Schedule: "@every 30m",
+			Timeout:  "1d",
+			AWS:      []v1.AWS{{Include: []string{"ec2instance", "RDSEvents"}}},
+			GCP:      []v1.GCP{{Include: []string{v1.IncludeIAMPolicy, "storage.googleapis.com/Bucket"}}},
+			File: []v1.File{{BaseScraper: v1.BaseScraper{
+				CustomScraperBase: v1.CustomScraperBase{ID: "$.id", Name: "static name", Items: "$.items[*]"},
+				Transform: v1.Transform{
+					Script: v1.Script{GoTemplate: `{{ .config | toJSON }}`},
+					Change: v1.TransformChange{Mapping: []v1.ChangeMapping{{
+						Filter:  `change.change_type == "diff" && jq('.a', patch) != ""`,
+						Action:  v1.Aggregate,
+						Window:  "2h",
+						Summary: `{{ .summary | upper }}`,
+					}}},
+				},
+			}}},
+		}}
+
+		Expect(ValidateScrapeConfig(sc)).To(BeEmpty())
+	})
+
+	It("reports the field path of each error", func() {
+		sc := v1.ScrapeConfig{Spec: v1.ScraperSpec{
+			Schedule: "61 * * * *",
+			Timeout:  "forever",
+			AWS:      []v1.AWS{{Include: []string{"EC2instance", "Mainframe"}}},
+			GCP:      []v1.GCP{{Include: []string{"Everything"}}},
+			HTTP: []v1.HTTP{{BaseScraper: v1.BaseScraper{
+				CustomScraperBase: v1.CustomScraperBase{ID: "$.id[", Items: "$.items["},
+				Transform: v1.Transform{
+					Script: v1.Script{GoTemplate: `{{ .config `},
+					Change: v1.TransformChange{
+						Exclude: []string{`change_type ==`},
+						Mapping: []v1.ChangeMapping{{Filter: `true`, Action: "explode", Window: "soon"}},
+					},
+				},
+			}}},
+		}}
+
+		Expect(fields(ValidateScrapeConfig(sc))).To(ConsistOf(
+			"spec.schedule",
+			"spec.timeout",
+			"spec.aws[0].include[1]",
+			"spec.gcp[0].include[0]",
+			"spec.http[0].id",
+			"spec.http[0].items",
+			"spec.http[0].transform.gotemplate",
+			"spec.http[0].transform.changes.exclude[0]",
+			"spec.http[0].transform.changes.mapping[0].action",
+			"spec.http[0].transform.changes.mapping[0].window",
+		))
+	})
+})
+
+var _ = Describe("ValidateScrapePlugin", func() {
+	It("reports invalid expressions and durations", func() {
+		plugin := v1.ScrapePlugin{Spec: v1.ScrapePluginSpec{
+			Change:    v1.TransformChange{Mapping: []v1.ChangeMapping{{Filter: `change_type == "diff"`, ConfigID: `config.id +`}}},
+			Retention: &v1.RetentionSpec{StaleItemAge: "a while"},
+			Health:    []v1.HealthRule{{Expr: `config.status == "ok"`}},
+		}}
+
+		errs := ValidateScrapePlugin(plugin)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs.ToAggregate().Error()).To(And(
+			ContainSubstring("spec.changes.mapping[0].config_id"),
+			ContainSubstring("spec.retention.staleItemAge"),
+		))
+	})
+})
@@ -0,0 +1,295 @@
+// Package replay records outbound HTTP exchanges into HAR files and serves requests
+// from them, so that scrapers can run against recorded responses with no network.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/flanksource/commons/har"
+	"github.com/flanksource/commons/logger"
+)
+
+// ReplayedHeader is set on the responses served from a cassette, to tell them apart from responses of the network.
+const ReplayedHeader = "X-Config-Db-Replayed"
+
+// Cassette holds the recorded HTTP exchanges that are served in place of the network.
+type Cassette struct {
+	mu      sync.Mutex
+	entries []har.Entry
+	served  []bool
+	misses  []string
+}
+
+// RecordingConfig captures full bodies of every content type, so that the recorded responses can be replayed.
+// Credentials are still redacted unless http.har.sensitive is set, and are ignored when matching recordings.
+func RecordingConfig() har.HARConfig {
+	cfg := har.DefaultConfig()
+	cfg.MaxBodySize = 0
+	cfg.CaptureContentTypes = []string{""}
+	return cfg
+}
+
+func NewCassette(entries []har.Entry) *Cassette {
+	return &Cassette{entries: entries, served: make([]bool, len(entries))}
+}
+
+// Load reads a HAR file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HAR file %s: %w", path, err)
+	}
+
+	var file har.File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file %s: %w", path, err)
+	}
+	return NewCassette(file.Log.Entries), nil
+}
+
+// Misses returns the requests that had no recorded response.
+func (c *Cassette) Misses() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.misses...)
+}
+
+// Match returns the recorded response for the request.
+//
+// Requests match on the method, host, path and query, ignoring the values of sensitive
+// query parameters as they are redacted in recordings. When several recordings match,
+// the one with the same body is preferred, and they are served in the order they were
+// recorded, so that pagination and retries replay the same sequence of responses.
+// Once all of them are served, the last one is served again.
+func (c *Cassette) Match(req *http.Request, body []byte) (*har.Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var candidates []int
+	for i, entry := range c.entries {
+		if matchRequest(entry.Request, req) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		c.misses = append(c.misses, fmt.Sprintf("%s %s", req.Method, req.URL))
+		return nil, false
+	}
+
+	sameBody := func(i int) bool {
+		return c.entries[i].Request.PostData == nil || equalBody(c.entries[i].Request.PostData.Text, body)
+	}
+
+	found := -1
+	for _, i := range candidates {
+		if !c.served[i] && sameBody(i) {
+			found = i
+			break
+		}
+	}
+	if found < 0 {
+		for _, i := range candidates {
+			if !c.served[i] {
+				found = i
+				break
+			}
+		}
+	}
+	if found < 0 {
+		found = candidates[len(candidates)-1]
+	}
+
+	c.served[found] = true
+	return &c.entries[found], true
+}
+
+// ServeHTTP serves the recorded response of the request, or a 501 when there is none.
+func (c *Cassette) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	w.Header().Set(ReplayedHeader, "true")
+	entry, ok := c.Match(req, body)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no recorded response for %s %s", req.Method, req.URL), http.StatusNotImplemented)
+		return
+	}
+
+	for _, header := range entry.Response.Headers {
+		switch strings.ToLower(header.Name) {
+		case "content-length", "content-encoding", "transfer-encoding", "connection":
+			// the body is recorded decoded, and is written in full
+			continue
+		}
+		w.Header().Add(header.Name, header.Value)
+	}
+	if entry.Response.Content.MimeType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", entry.Response.Content.MimeType)
+	}
+	w.WriteHeader(entry.Response.Status)
+	_, _ = io.WriteString(w, entry.Response.Content.Text)
+}
+
+// RoundTrip serves the recorded response of the request, or a 501 when there is none, so that
+// clients using the cassette as their transport never reach the network.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	c.ServeHTTP(recorder, req)
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// Install serves every request made through http.DefaultTransport, or a transport cloned from it,
+// from the cassette until the returned function is called.
+//
+// Clients that build their own transport instead of cloning http.DefaultTransport keep using the network,
+// they are served from the cassette by using it as their transport, and are otherwise reported by Unreplayed.
+func (c *Cassette) Install() (restore func()) {
+	transport := http.DefaultTransport.(*http.Transport)
+	dial, dialTLS, proxy := transport.DialContext, transport.DialTLSContext, transport.Proxy
+
+	plain, secure := newPipeListener(), newPipeListener()
+	servers := []*http.Server{{Handler: withScheme("http", c)}, {Handler: withScheme("https", c)}}
+	go servers[0].Serve(plain)  //nolint:errcheck
+	go servers[1].Serve(secure) //nolint:errcheck
+
+	// TLS connections are terminated here, the transport uses the plain connection as is
+	transport.DialContext = plain.DialContext
+	transport.DialTLSContext = secure.DialContext
+	transport.Proxy = nil
+
+	return func() {
+		transport.DialContext, transport.DialTLSContext, transport.Proxy = dial, dialTLS, proxy
+		transport.CloseIdleConnections()
+		for _, server := range servers {
+			_ = server.Close()
+		}
+	}
+}
+
+// Unreplayed returns the requests of the recorded exchanges whose response was not served from a cassette,
+// i.e. the requests that reached the network while replaying.
+func Unreplayed(entries []har.Entry) []string {
+	var requests []string
+	for _, entry := range entries {
+		replayed := false
+		for _, header := range entry.Response.Headers {
+			if strings.EqualFold(header.Name, ReplayedHeader) {
+				replayed = true
+				break
+			}
+		}
+		if !replayed {
+			requests = append(requests, fmt.Sprintf("%s %s", entry.Request.Method, entry.Request.URL))
+		}
+	}
+	return requests
+}
+
+// withScheme restores the target of requests received from a dialer, which only carry the path.
+func withScheme(scheme string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.URL.Scheme = scheme
+		req.URL.Host = req.Host
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func matchRequest(recorded har.Request, req *http.Request) bool {
+	if !strings.EqualFold(recorded.Method, req.Method) {
+		return false
+	}
+
+	u, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(u.Host, req.URL.Host) || strings.TrimSuffix(u.Path, "/") != strings.TrimSuffix(req.URL.Path, "/") {
+		return false
+	}
+
+	want, got := u.Query(), req.URL.Query()
+	if len(want) != len(got) {
+		return false
+	}
+	for key, values := range want {
+		if _, ok := got[key]; !ok {
+			return false
+		}
+		if logger.IsSensitiveKey(key) {
+			continue
+		}
+		if strings.Join(values, ",") != strings.Join(got[key], ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// equalBody compares the bodies as JSON when both are JSON, so that the order of the keys does not matter.
+func equalBody(recorded string, body []byte) bool {
+	var a, b any
+	if json.Unmarshal([]byte(recorded), &a) == nil && json.Unmarshal(body, &b) == nil {
+		x, _ := json.Marshal(a)
+		y, _ := json.Marshal(b)
+		return bytes.Equal(x, y)
+	}
+	return recorded == string(body)
+}
+
+// pipeListener is an in-memory listener whose connections are dialed by the replaying transport.
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "replay" }
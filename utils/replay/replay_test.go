@@ -0,0 +1,158 @@
+package replay
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flanksource/commons/har"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReplay(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Replay Suite")
+}
+
+func entry(method, url, body string, status int, response string) har.Entry {
+	e := har.Entry{
+		Request: har.Request{Method: method, URL: url},
+		Response: har.Response{
+			Status:  status,
+			Content: har.Content{MimeType: "application/json", Text: response},
+		},
+	}
+	if body != "" {
+		e.Request.PostData = &har.PostData{Text: body}
+	}
+	return e
+}
+
+var _ = Describe("Cassette", func() {
+	get := func(url string) (int, string) {
+		resp, err := http.Get(url)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, string(body)
+	}
+
+	It("serves recorded responses in the order they were recorded", func() {
+		cassette := NewCassette([]har.Entry{
+			entry("GET", "https://api.example.com/items?page=1", "", 429, `{"error":"rate limited"}`),
+			entry("GET", "https://api.example.com/items?page=1", "", 200, `{"items":[1]}`),
+			entry("GET", "https://api.example.com/items?page=2", "", 200, `{"items":[2]}`),
+		})
+		restore := cassette.Install()
+		defer restore()
+
+		status, body := get("https://api.example.com/items?page=1")
+		Expect(status).To(Equal(http.StatusTooManyRequests))
+		Expect(body).To(Equal(`{"error":"rate limited"}`))
+
+		status, body = get("https://api.example.com/items?page=1")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(Equal(`{"items":[1]}`))
+
+		_, body = get("https://api.example.com/items/?page=2")
+		Expect(body).To(Equal(`{"items":[2]}`))
+
+		// once all recordings are served, the last one is repeated
+		_, body = get("https://api.example.com/items?page=1")
+		Expect(body).To(Equal(`{"items":[1]}`))
+
+		Expect(cassette.Misses()).To(BeEmpty())
+	})
+
+	It("replays recorded exchanges with no network", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.WriteString(w, "page "+req.URL.Query().Get("page"))
+		}))
+
+		collector := har.NewCollector(RecordingConfig())
+		client := &http.Client{Transport: collector.Middleware()(http.DefaultTransport)}
+		for _, page := range []string{"1", "2"} {
+			resp, err := client.Get(server.URL + "/items?page=" + page)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Body.Close()).To(Succeed())
+		}
+		server.Close()
+
+		restore := NewCassette(collector.Entries()).Install()
+		defer restore()
+
+		status, body := get(server.URL + "/items?page=2")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("page 2"))
+	})
+
+	It("reports requests without a recorded response", func() {
+		cassette := NewCassette([]har.Entry{entry("GET", "http://api.example.com/items", "", 200, `[]`)})
+		restore := cassette.Install()
+		defer restore()
+
+		status, _ := get("http://api.example.com/other")
+		Expect(status).To(Equal(http.StatusNotImplemented))
+		Expect(cassette.Misses()).To(ConsistOf("GET http://api.example.com/other"))
+	})
+
+	It("serves clients with their own transport and reports requests that reached the network", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = io.WriteString(w, "live")
+		}))
+		defer server.Close()
+
+		cassette := NewCassette([]har.Entry{entry("GET", "https://api.example.com/items", "", 200, `[1]`)})
+		restore := cassette.Install()
+		defer restore()
+
+		collector := har.NewCollector(RecordingConfig())
+		replayed := &http.Client{Transport: collector.Middleware()(cassette)}
+		resp, err := replayed.Get("https://api.example.com/items")
+		Expect(err).NotTo(HaveOccurred())
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(string(body)).To(Equal(`[1]`))
+
+		live := &http.Client{Transport: collector.Middleware()(&http.Transport{})}
+		resp, err = live.Get(server.URL + "/items")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+
+		Expect(Unreplayed(collector.Entries())).To(ConsistOf("GET " + server.URL + "/items"))
+	})
+
+	It("ignores the values of sensitive query parameters and matches bodies as JSON", func() {
+		cassette := NewCassette([]har.Entry{
+			entry("POST", "https://api.example.com/graphql?access_token=****", `{"query":"a","variables":{}}`, 200, `"a"`),
+			entry("POST", "https://api.example.com/graphql?access_token=****", `{"variables":{},"query":"b"}`, 200, `"b"`),
+		})
+
+		req, err := http.NewRequest("POST", "https://api.example.com/graphql?access_token=secret", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		found, ok := cassette.Match(req, []byte(`{"query":"b","variables":{}}`))
+		Expect(ok).To(BeTrue())
+		Expect(found.Response.Content.Text).To(Equal(`"b"`))
+
+		found, ok = cassette.Match(req, []byte(`{"query":"a","variables":{}}`))
+		Expect(ok).To(BeTrue())
+		Expect(found.Response.Content.Text).To(Equal(`"a"`))
+	})
+
+	It("restores the network when uninstalled", func() {
+		transport := http.DefaultTransport.(*http.Transport)
+
+		restore := NewCassette(nil).Install()
+		Expect(transport.Proxy).To(BeNil())
+
+		restore()
+		Expect(transport.Proxy).NotTo(BeNil())
+		Expect(transport.DialTLSContext).To(BeNil())
+	})
+})